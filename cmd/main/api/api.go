@@ -1,13 +1,22 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/scheduler"
 	"web_blog/cmd/main/services"
 	"web_blog/docs"
 	"web_blog/internal/authentication"
+	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/mailer"
+	"web_blog/internal/policy"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -20,6 +29,9 @@ const (
 	Description string = "Blog API written in Golang for university module."
 	Version     string = "0.1"
 	BasePath    string = "/v1"
+
+	// Time given to in-flight requests when the server shuts down.
+	ShutdownTimeout time.Duration = 10 * time.Second
 )
 
 type Application struct {
@@ -27,7 +39,9 @@ type Application struct {
 	Middlewares   middlewares.Middleware
 	Services      services.Services
 	Storage       storage.Storage
-	Authenticator authentication.StatefulAuthenticator
+	Authenticator authentication.Authenticator
+	Scheduler     *scheduler.Scheduler
+	Mailer        *mailer.AsyncMailer
 	Logger        *zap.Logger
 }
 
@@ -52,12 +66,18 @@ func (app *Application) Mount() http.Handler {
 	Middlewares := app.Middlewares
 
 	StatefulAuthentication := Middlewares.StatefulAuthentication
-	Authorization := Middlewares.Authorization
+	OptionalAuthentication := Middlewares.OptionalAuthentication
+	Permission := Middlewares.Permission
+	Verified := Middlewares.Verified
 	PostContext := Middlewares.PostContext
+	CommentContext := Middlewares.CommentContext
+	TagContext := Middlewares.TagContext
+	PostPolicy := Middlewares.PostPolicy
+	CommentPolicy := Middlewares.CommentPolicy
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(Middlewares.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -67,53 +87,171 @@ func (app *Application) Mount() http.Handler {
 
 		// Post Services.
 		r.Group(func(r chi.Router) {
-			r.Get("/posts", Services.Post.FindAllPosts)
-			r.Get("/users/{id}/posts", Services.Post.FindAllPostsByUserID)
-			r.With(PostContext).
+			r.With(OptionalAuthentication).
+				Get("/posts", Services.Post.FindAllPosts)
+			r.With(OptionalAuthentication).
+				Get("/users/{id}/posts", Services.Post.FindAllPostsByUserID)
+			r.With(OptionalAuthentication, PostContext).
 				Get("/posts/{id}", Services.Post.FindPost)
+			r.With(OptionalAuthentication, PostContext).
+				Get("/posts/{id}/revisions", Services.Revision.FindAllPostRevisions)
+			r.With(OptionalAuthentication, PostContext).
+				Get("/posts/{id}/revisions/diff", Services.Revision.DiffPostRevisions)
+			r.With(OptionalAuthentication, PostContext).
+				Get("/posts/{id}/revisions/{revision}", Services.Revision.FindPostRevision)
 
 			// With Authentication.
 			r.Group(func(r chi.Router) {
 				r.Use(StatefulAuthentication)
 
-				r.With(Authorization("user")).
+				r.With(Permission(entity.PermissionPostsCreate), Verified).
 					Post("/posts", Services.Post.CreatePost)
-				r.With(Authorization("moderator"), PostContext).
+				r.With(PostContext, PostPolicy(policy.UpdatePost)).
 					Patch("/posts/{id}", Services.Post.UpdatePost)
-				r.With(Authorization("moderator"), PostContext).
+				r.With(PostContext, PostPolicy(policy.DeletePost)).
 					Delete("/posts/{id}", Services.Post.DeletePost)
+				r.With(PostContext, PostPolicy(policy.UpdatePost)).
+					Post("/posts/{id}/revisions/{revision}/restore", Services.Revision.RestorePostRevision)
 			})
 
 		})
 
 		// Comment Services.
 		r.Group(func(r chi.Router) {
-			r.Get("/posts/{id}/comments", Services.Comment.FindAllCommentsByPostID)
+			r.With(OptionalAuthentication, PostContext).
+				Get("/posts/{id}/comments", Services.Comment.FindAllCommentsByPostID)
+			r.With(OptionalAuthentication, CommentContext).
+				Get("/comments/{id}/replies", Services.Comment.FindAllReplies)
 
 			// With Authentication.
 			r.Group(func(r chi.Router) {
 				r.Use(StatefulAuthentication)
-				r.With(Authorization("user")).
+				r.With(Permission(entity.PermissionCommentsCreate), Verified, PostContext).
 					Post("/posts/{id}/comments", Services.Comment.CreateComment)
-				r.With(Authorization("moderator")).
+				r.With(Permission(entity.PermissionCommentsCreate), Verified, CommentContext).
+					Post("/comments/{id}/replies", Services.Comment.CreateReply)
+				r.With(Permission(entity.PermissionCommentsReadAny)).
 					Get("/posts/comments", Services.Comment.FindAllComments)
-				r.With(Authorization("moderator")).
+				r.With(CommentContext, CommentPolicy(policy.UpdateComment)).
+					Patch("/posts/comments/{id}", Services.Comment.UpdateComment)
+				r.With(Permission(entity.PermissionCommentsReadAny), CommentContext).
+					Get("/posts/comments/{id}/edits", Services.Comment.FindAllCommentEdits)
+				r.With(CommentContext, CommentPolicy(policy.DeleteComment)).
 					Delete("/posts/comments/{id}", Services.Comment.DeleteComment)
 			})
 		})
 
+		// Tag Services.
+		r.Group(func(r chi.Router) {
+			r.Get("/tags", Services.Tag.FindAllTags)
+			r.With(OptionalAuthentication, TagContext).
+				Get("/tags/{slug}/posts", Services.Tag.FindAllPostsByTag)
+
+			// With Authentication.
+			r.Group(func(r chi.Router) {
+				r.Use(StatefulAuthentication)
+				r.With(Permission(entity.PermissionTagsManage), TagContext).
+					Patch("/tags/{slug}", Services.Tag.RenameTag)
+				r.With(Permission(entity.PermissionTagsManage), TagContext).
+					Post("/tags/{slug}/merge", Services.Tag.MergeTag)
+			})
+		})
+
+		// Search Services.
+		r.Group(func(r chi.Router) {
+			r.Get("/search", Services.Search.Search)
+		})
+
 		// User Services.
 		r.Group(func(r chi.Router) {
-			r.Use(StatefulAuthentication, Authorization("admin"))
-			r.Get("/users", Services.User.FindAllUsers)
+			r.Use(StatefulAuthentication)
+			r.With(Permission(entity.PermissionUsersRead)).
+				Get("/users", Services.User.FindAllUsers)
+			r.With(Permission(entity.PermissionUsersRead)).
+				Get("/users/{id}", Services.User.FindUser)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Patch("/users/{id}/role", Services.User.UpdateUserRole)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Post("/users/{id}/ban", Services.User.BanUser)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Post("/users/{id}/unban", Services.User.UnbanUser)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Post("/users/{id}/unlock", Services.User.UnlockUser)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Delete("/users/{id}", Services.User.DeleteUser)
+		})
+
+		// Moderation Services.
+		r.Group(func(r chi.Router) {
+			r.Use(StatefulAuthentication, Permission(entity.PermissionContentModerate))
+			r.Get("/moderation/queue", Services.Moderation.FindModerationQueue)
+			r.Post("/moderation/posts/{id}/approve", Services.Moderation.ApprovePost)
+			r.Post("/moderation/posts/{id}/reject", Services.Moderation.RejectPost)
+			r.Post("/moderation/comments/{id}/approve", Services.Moderation.ApproveComment)
+			r.Post("/moderation/comments/{id}/reject", Services.Moderation.RejectComment)
+		})
+
+		// Trash Services.
+		r.Group(func(r chi.Router) {
+			r.Use(StatefulAuthentication)
+			r.With(Permission(entity.PermissionPostsDeleteAny)).
+				Get("/trash/posts", Services.Trash.FindAllDeletedPosts)
+			r.With(Permission(entity.PermissionPostsDeleteAny)).
+				Post("/trash/posts/{id}/restore", Services.Trash.RestorePost)
+			r.With(Permission(entity.PermissionCommentsDeleteAny)).
+				Get("/trash/comments", Services.Trash.FindAllDeletedComments)
+			r.With(Permission(entity.PermissionCommentsDeleteAny)).
+				Post("/trash/comments/{id}/restore", Services.Trash.RestoreComment)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Get("/trash/users", Services.Trash.FindAllDeletedUsers)
+			r.With(Permission(entity.PermissionUsersManage)).
+				Post("/trash/users/{id}/restore", Services.Trash.RestoreUser)
+		})
+
+		// Account Services.
+		r.Group(func(r chi.Router) {
+			r.Use(StatefulAuthentication)
+			r.Get("/me", Services.Account.FindAccount)
+			r.Patch("/me", Services.Account.UpdateAccount)
+			r.Post("/me/password", Services.Account.ChangePassword)
+			r.Post("/me/email", Services.Account.ChangeEmail)
+			r.Get("/me/sessions", Services.Account.FindAllSessions)
+			r.Delete("/me/sessions", Services.Account.DeleteAllSessions)
+			r.Delete("/me/sessions/{id}", Services.Account.DeleteSession)
+		})
+
+		// Two Factor Services, usable before two factor authentication is
+		// enabled when the role requires it.
+		r.Group(func(r chi.Router) {
+			r.Use(Middlewares.EnrollingAuthentication)
+			r.Post("/me/two-factor", Services.TwoFactor.EnableTwoFactor)
+			r.Post("/me/two-factor/confirm", Services.TwoFactor.ConfirmTwoFactor)
+			r.Delete("/me/two-factor", Services.TwoFactor.DisableTwoFactor)
+			r.Post("/me/two-factor/recovery-codes", Services.TwoFactor.RegenerateRecoveryCodes)
+		})
+
+		// Role Services.
+		r.Group(func(r chi.Router) {
+			r.Use(StatefulAuthentication, Permission(entity.PermissionRolesManage))
+			r.Get("/admin/permissions", Services.Role.FindAllPermissions)
+			r.Get("/admin/roles", Services.Role.FindAllRoles)
+			r.Post("/admin/roles", Services.Role.CreateRole)
+			r.Put("/admin/roles/{id}/permissions", Services.Role.SetRolePermissions)
+			r.Get("/admin/roles/two-factor", Services.Role.FindRoleTwoFactor)
+			r.Put("/admin/roles/two-factor", Services.Role.SetRoleTwoFactor)
 		})
 
 		// Authentication Services.
 		r.Group(func(r chi.Router) {
 			r.Post("/authentication/register", Services.Auth.RegisterUser)
 			r.Post("/authentication/verify", Services.Auth.VerifyUser)
+			r.Post("/authentication/verify/resend", Services.Auth.ResendVerification)
 			r.Post("/authentication/login", Services.Auth.LoginUser)
+			r.Post("/authentication/login/two-factor", Services.Auth.LoginTwoFactor)
 			r.Delete("/authentication/logout", Services.Auth.LogoutUser)
+			r.Post("/authentication/refresh", Services.Auth.RefreshToken)
+			r.Post("/authentication/password/forgot", Services.Auth.ForgotPassword)
+			r.Post("/authentication/password/reset", Services.Auth.ResetPassword)
 		})
 	})
 
@@ -135,6 +273,35 @@ func (app *Application) Serve() error {
 		IdleTimeout:  30 * time.Second,
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if app.Scheduler != nil {
+		app.Scheduler.Start(ctx)
+		defer app.Scheduler.Wait()
+	}
+
+	if app.Mailer != nil {
+		app.Mailer.Start(ctx)
+		defer app.Mailer.Wait()
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		app.Logger.Info("Server is shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
 	app.Logger.Info("Server has started", zap.String("address", app.Config.Address))
-	return srv.ListenAndServe()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		stop()
+		return err
+	}
+
+	return <-shutdown
 }
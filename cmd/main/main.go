@@ -3,13 +3,20 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 	"web_blog/cmd/main/api"
 	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/scheduler"
 	"web_blog/cmd/main/services"
 	"web_blog/internal/authentication"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/data/storage/memstorage"
 	"web_blog/internal/data/storage/pgxstorage"
 	"web_blog/internal/env"
+	"web_blog/internal/jwt"
+	"web_blog/internal/mailer"
+	"web_blog/internal/policy"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -41,48 +48,275 @@ func main() {
 		Url:         address,
 	}
 
-	// Database
-	Database := &pgxstorage.PgxDatabase{}
+	// Database and storage
+	var Database storage.Database
+	var Storage storage.Storage
+	var DatabaseConfig any
+
+	switch env.GetString("DB_DRIVER", "postgres") {
+	case "memory":
+		database := &memstorage.MemDatabase{}
+		Database, Storage = database, memstorage.NewStorage(database)
+	default:
+		database := &pgxstorage.PgxDatabase{}
+		Database, Storage = database, pgxstorage.NewStorage(database)
+	}
+
 	if err = Database.Open(context.Background(), nil); err != nil {
 		Logger.Fatal("database error", zap.Error(err))
 		return
 	}
 	defer Database.Close(context.Background())
 
+	// Roles are read on every authorized request.
+	Storage.Roles = storage.NewRoleCache(Storage.Roles, env.GetDuration("ROLE_CACHE_TTL", storage.RoleCacheTTL))
+
+	// Settings are read on authenticated requests too.
+	Storage.Settings = storage.NewSettingsCache(Storage.Settings, env.GetDuration("SETTINGS_CACHE_TTL", storage.SettingsCacheTTL))
+
+	if database, ok := Database.(*pgxstorage.PgxDatabase); ok {
+		DatabaseConfig = database.Config
+	}
+
 	// Authenticator
-	Authenticator := authentication.StatefulAuthenticator{}
+	var Authenticator authentication.Authenticator
+
+	switch env.GetString("AUTHENTICATOR", "session") {
+	case "jwt":
+		var keys *jwt.KeySet
+		if keys, err = jwt.ParseKeySet(env.GetString("JWT_KEYS", "")); err != nil {
+			Logger.Fatal("config error", zap.Error(err))
+		}
+
+		Authenticator = &authentication.JWTAuthenticator{
+			Keys:            keys,
+			Issuer:          env.GetString("JWT_ISSUER", url),
+			AccessDuration:  env.GetDuration("JWT_ACCESS_DURATION", time.Minute*15),
+			RefreshDuration: env.GetDuration("JWT_REFRESH_DURATION", time.Hour*24*30),
+		}
+	default:
+		Authenticator = &authentication.StatefulAuthenticator{
+			Duration: env.GetDuration("SESSION_DURATION", time.Hour*24*7),
+			Sliding:  env.GetBool("SESSION_SLIDING", false),
+		}
+	}
+
+	// Mailer
+	var Sender mailer.Mailer
+	from := env.GetString("MAIL_FROM", "no-reply@localhost")
+
+	switch env.GetString("MAIL_DRIVER", "stdout") {
+	case "smtp":
+		Sender = &mailer.SMTPMailer{
+			Host:     env.GetString("SMTP_HOST", "localhost"),
+			Port:     env.GetInt("SMTP_PORT", 587),
+			Username: env.GetString("SMTP_USERNAME", ""),
+			Password: env.GetString("SMTP_PASSWORD", ""),
+			From:     from,
+		}
+	case "file":
+		Sender = &mailer.FileMailer{Directory: env.GetString("MAIL_DIR", "mail"), From: from}
+	default:
+		Sender = &mailer.FileMailer{Writer: os.Stdout, From: from}
+	}
+
+	Mailer := mailer.NewAsyncMailer(Sender, Logger, env.GetInt("MAIL_QUEUE_SIZE", 100))
+	Mailer.Retries = env.GetInt("MAIL_RETRIES", Mailer.Retries)
+	Mailer.Backoff = env.GetDuration("MAIL_RETRY_BACKOFF", Mailer.Backoff)
+
+	// What unverified users are allowed to do
+	var UnverifiedPolicy policy.Unverified
+	if UnverifiedPolicy, err = policy.ParseUnverified(env.GetString("UNVERIFIED_POLICY", string(policy.UnverifiedRestrict))); err != nil {
+		Logger.Fatal("config error", zap.Error(err))
+	}
+
+	// When new posts and comments are reviewed by moderators
+	var ModerationPolicy policy.Moderation
+	if ModerationPolicy, err = policy.ParseModeration(env.GetString("MODERATION_MODE", string(policy.ModerationPost))); err != nil {
+		Logger.Fatal("config error", zap.Error(err))
+	}
+
+	// Proxies trusted to forward the client address, none by default
+	var TrustedProxies policy.TrustedProxies
+	if TrustedProxies, err = policy.ParseTrustedProxies(env.GetString("TRUSTED_PROXIES", "")); err != nil {
+		Logger.Fatal("config error", zap.Error(err))
+	}
 
-	// Storage
-	Storage := storage.Storage{
-		Database:      Database,
-		Users:         &pgxstorage.PgxUserRepository{Database: Database},
-		Posts:         &pgxstorage.PgxPostRepository{Database: Database},
-		Comments:      &pgxstorage.PgxCommentRepository{Database: Database},
-		Verifications: &pgxstorage.PgxVerificationRepository{Database: Database},
-		Sessions:      &pgxstorage.PgxSessionRepository{Database: Database},
-		Roles:         &pgxstorage.PgxRoleRepository{Database: Database},
+	// How failed logins are throttled, per account and per address
+	AccountThrottle := policy.LoginThrottle{
+		Free:            env.GetInt("LOGIN_FREE_ATTEMPTS", 3),
+		Backoff:         env.GetDuration("LOGIN_BACKOFF", time.Second),
+		MaxBackoff:      env.GetDuration("LOGIN_BACKOFF_MAX", time.Minute),
+		Lockout:         env.GetInt("LOGIN_LOCKOUT_ATTEMPTS", 10),
+		LockoutDuration: env.GetDuration("LOGIN_LOCKOUT_DURATION", time.Minute*15),
+		Window:          env.GetDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+	IPThrottle := policy.LoginThrottle{
+		Free:            env.GetInt("LOGIN_IP_FREE_ATTEMPTS", 10),
+		Backoff:         env.GetDuration("LOGIN_IP_BACKOFF", time.Second),
+		MaxBackoff:      env.GetDuration("LOGIN_IP_BACKOFF_MAX", time.Minute),
+		Lockout:         env.GetInt("LOGIN_IP_LOCKOUT_ATTEMPTS", 50),
+		LockoutDuration: env.GetDuration("LOGIN_IP_LOCKOUT_DURATION", time.Hour),
+		Window:          env.GetDuration("LOGIN_IP_FAILURE_WINDOW", time.Hour),
 	}
 
 	// Middlewares
 	Middlewares := middlewares.Middleware{
-		Storage:       &Storage,
-		Authenticator: &Authenticator,
+		Storage:          &Storage,
+		Authenticator:    Authenticator,
+		UnverifiedPolicy: UnverifiedPolicy,
+		ModerationPolicy: ModerationPolicy,
+		TrustedProxies:   TrustedProxies,
 	}
 
 	// Services
 	Services := services.Services{
-		Health:  &services.HealthService{HealthEnvelope: healthEnvelope},
-		Auth:    &services.AuthService{Storage: &Storage, Authenticator: &Authenticator},
+		Health: &services.HealthService{HealthEnvelope: healthEnvelope},
+		Auth: &services.AuthService{
+			Storage:                    &Storage,
+			Authenticator:              Authenticator,
+			Mailer:                     Mailer,
+			PasswordResetDelay:         env.GetDuration("PASSWORD_RESET_DELAY", time.Millisecond*500),
+			VerificationResendInterval: env.GetDuration("VERIFICATION_RESEND_INTERVAL", time.Minute),
+			UnverifiedPolicy:           UnverifiedPolicy,
+			LoginChallengeDuration:     env.GetDuration("LOGIN_CHALLENGE_DURATION", time.Minute*5),
+			AccountThrottle:            AccountThrottle,
+			IPThrottle:                 IPThrottle,
+		},
 		User:    &services.UserService{Storage: &Storage},
-		Post:    &services.PostService{Storage: &Storage},
-		Comment: &services.CommentService{Storage: &Storage},
+		Account: &services.AccountService{Storage: &Storage, Mailer: Mailer},
+		TwoFactor: &services.TwoFactorService{
+			Storage:         &Storage,
+			Issuer:          env.GetString("TWO_FACTOR_ISSUER", api.Title),
+			AccountThrottle: AccountThrottle,
+		},
+		Role: &services.RoleService{Storage: &Storage},
+		Post: &services.PostService{Storage: &Storage, Moderation: ModerationPolicy},
+		Comment: &services.CommentService{
+			Storage:    &Storage,
+			Moderation: ModerationPolicy,
+			MaxDepth:   env.GetInt("COMMENT_MAX_DEPTH", 5),
+			EditWindow: env.GetDuration("COMMENT_EDIT_WINDOW", time.Minute*15),
+		},
+		Revision:   &services.RevisionService{Storage: &Storage, Moderation: ModerationPolicy},
+		Tag:        &services.TagService{Storage: &Storage, Moderation: ModerationPolicy},
+		Search:     &services.SearchService{Storage: &Storage, Moderation: ModerationPolicy},
+		Trash:      &services.TrashService{Storage: &Storage},
+		Moderation: &services.ModerationService{Storage: &Storage, Mailer: Mailer},
+	}
+
+	// Scheduler
+	Scheduler := &scheduler.Scheduler{
+		Logger: Logger,
+		Jobs: []scheduler.Job{
+			{
+				Name:     "publish scheduled posts",
+				Interval: env.GetDuration("SCHEDULER_INTERVAL", time.Minute),
+				Run: func(ctx context.Context) error {
+					count, err := Storage.Posts.PublishDue(ctx, time.Now())
+					if count > 0 {
+						Logger.Info("scheduled posts published", zap.Int64("count", count))
+					}
+
+					return err
+				},
+			},
+			{
+				Name:     "delete expired verifications",
+				Interval: env.GetDuration("VERIFICATION_CLEANUP_INTERVAL", time.Hour),
+				Run: func(ctx context.Context) error {
+					// Expired verifications are kept for a while, so verifying
+					// with them reports that they expired.
+					before := time.Now().Add(-env.GetDuration("VERIFICATION_RETENTION", time.Hour*24*7))
+
+					count, err := Storage.Verifications.DeleteExpired(ctx, before)
+					if count > 0 {
+						Logger.Info("expired verifications deleted", zap.Int64("count", count))
+					}
+
+					return err
+				},
+			},
+			{
+				Name:     "delete expired sessions",
+				Interval: env.GetDuration("SESSION_CLEANUP_INTERVAL", time.Hour),
+				Run: func(ctx context.Context) error {
+					count, err := Storage.Sessions.DeleteExpired(ctx, time.Now())
+					if count > 0 {
+						Logger.Info("expired sessions deleted", zap.Int64("count", count))
+					}
+
+					return err
+				},
+			},
+			{
+				Name:     "delete expired login challenges",
+				Interval: env.GetDuration("LOGIN_CHALLENGE_CLEANUP_INTERVAL", time.Hour),
+				Run: func(ctx context.Context) error {
+					count, err := Storage.LoginChallenges.DeleteExpired(ctx, time.Now())
+					if count > 0 {
+						Logger.Info("expired login challenges deleted", zap.Int64("count", count))
+					}
+
+					return err
+				},
+			},
+			{
+				Name:     "delete stale login failures",
+				Interval: env.GetDuration("LOGIN_FAILURE_CLEANUP_INTERVAL", time.Hour),
+				Run: func(ctx context.Context) error {
+					// Failures are kept when either throttle never forgets them.
+					if AccountThrottle.Window <= 0 || IPThrottle.Window <= 0 {
+						return nil
+					}
+
+					window := max(AccountThrottle.Window, IPThrottle.Window)
+					count, err := Storage.LoginFailures.DeleteExpired(ctx, time.Now().Add(-window))
+					if count > 0 {
+						Logger.Info("stale login failures deleted", zap.Int64("count", count))
+					}
+
+					return err
+				},
+			},
+			{
+				Name:     "purge deleted rows",
+				Interval: env.GetDuration("TRASH_PURGE_INTERVAL", time.Hour),
+				Run: func(ctx context.Context) error {
+					// Comments go first, then posts and users, so each count
+					// only has the rows deleted on their own.
+					before := time.Now().Add(-env.GetDuration("TRASH_RETENTION", time.Hour*24*30))
+					repositories := []struct {
+						name       string
+						repository storage.ITrashRepository[int64]
+					}{
+						{"comments", Storage.Comments},
+						{"posts", Storage.Posts},
+						{"users", Storage.Users},
+					}
+
+					for _, trash := range repositories {
+						count, err := trash.repository.Purge(ctx, before)
+						if count > 0 {
+							Logger.Info("deleted rows purged", zap.String("table", trash.name), zap.Int64("count", count))
+						}
+
+						if err != nil {
+							return err
+						}
+					}
+
+					return nil
+				},
+			},
+		},
 	}
 
 	// Application config
 	Config := api.Config{
 		Address: address,
 		Url:     url,
-		Storage: Database.Config,
+		Storage: DatabaseConfig,
 		SwaggerConfig: api.SwaggerConfig{
 			DocsURL: fmt.Sprintf("http://%s%s/swagger/doc.json", address, api.BasePath),
 		},
@@ -96,7 +330,11 @@ func main() {
 		Storage:       Storage,
 		Logger:        Logger,
 		Authenticator: Authenticator,
+		Scheduler:     Scheduler,
+		Mailer:        Mailer,
 	}
 
-	Logger.Fatal(Application.Serve().Error())
+	if err = Application.Serve(); err != nil {
+		Logger.Fatal("server error", zap.Error(err))
+	}
 }
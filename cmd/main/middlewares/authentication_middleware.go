@@ -7,15 +7,30 @@ import (
 	"strings"
 	"web_blog/cmd/main/utils"
 	"web_blog/internal/data/entity"
+	"web_blog/internal/policy"
 )
 
 type userKey string
 
-const UserCtx userKey = "user"
+const (
+	UserCtx    userKey = "user"
+	SessionCtx userKey = "session"
+)
 
 func (middleware *Middleware) StatefulAuthentication(next http.Handler) http.Handler {
+	return middleware.requireAuthentication(next, false)
+}
+
+// Like StatefulAuthentication, but lets users whose role requires two factor
+// authentication through before they enabled it, so they can enable it.
+func (middleware *Middleware) EnrollingAuthentication(next http.Handler) http.Handler {
+	return middleware.requireAuthentication(next, true)
+}
+
+func (middleware *Middleware) requireAuthentication(next http.Handler, enrolling bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var user *entity.User
+		var session string
 		var err error
 
 		header := r.Header.Get("Authorization")
@@ -24,25 +39,100 @@ func (middleware *Middleware) StatefulAuthentication(next http.Handler) http.Han
 			return
 		}
 
-		values := strings.Split(header, " ")
-		if len(values) < 2 {
-			utils.UnauthorizedResponse(w, r, errors.New("authorization header is formated incorrectly"))
+		if user, session, err = middleware.authenticate(r, header, enrolling); err != nil {
+			authenticationErrorResponse(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserCtx, user)
+		ctx = context.WithValue(ctx, SessionCtx, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Authenticates the user when an authorization header is sent, and lets
+// anonymous requests through without a user in the context.
+func (middleware *Middleware) OptionalAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user *entity.User
+		var session string
+		var err error
+
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		ctx := r.Context()
-		token := values[1]
-		if user, err = middleware.Authenticator.Validate(ctx, middleware.Storage.Sessions, token); err != nil {
-			utils.UnauthorizedResponse(w, r, errors.New("unauthorized"))
+		if user, session, err = middleware.authenticate(r, header, false); err != nil {
+			authenticationErrorResponse(w, r, err)
 			return
 		}
 
-		ctx = context.WithValue(ctx, UserCtx, user)
+		ctx := context.WithValue(r.Context(), UserCtx, user)
+		ctx = context.WithValue(ctx, SessionCtx, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Returns the user of the token along with the id of its session. The user's
+// role is read through the role cache, with its permissions. Unverified users
+// are refused when the unverified policy blocks them, and users without two
+// factor authentication when the settings require it of their role, unless
+// they are enrolling.
+func (middleware *Middleware) authenticate(r *http.Request, header string, enrolling bool) (*entity.User, string, error) {
+	var user *entity.User
+	var role *entity.Role
+	var settings *entity.Settings
+	var session string
+	var err error
+
+	values := strings.Split(header, " ")
+	if len(values) < 2 {
+		return nil, "", errors.New("authorization header is formated incorrectly")
+	}
+
+	if user, session, err = middleware.Authenticator.Validate(r.Context(), middleware.Storage, values[1]); err != nil {
+		return nil, "", errors.New("unauthorized")
+	}
+
+	if role, err = middleware.Storage.Roles.Find(r.Context(), user.RoleID); err != nil {
+		return nil, "", errors.New("unauthorized")
+	}
+
+	if !middleware.UnverifiedPolicy.CanLogin(user) {
+		return nil, "", utils.ErrorUnverified
+	}
+
+	if !user.TwoFactor && !enrolling {
+		if settings, err = middleware.Storage.Settings.Find(r.Context()); err != nil {
+			return nil, "", errors.New("unauthorized")
+		}
+
+		if policy.NewTwoFactor(settings).Required(role) {
+			return nil, "", utils.ErrorTwoFactorRequired
+		}
+	}
+
+	user.Role = *role
+	return user, session, nil
+}
+
+func authenticationErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, utils.ErrorUnverified) || errors.Is(err, utils.ErrorTwoFactorRequired) {
+		utils.ForbiddenResponse(w, r, err)
+		return
+	}
+
+	utils.UnauthorizedResponse(w, r, err)
+}
+
 func FindUserFromContext(r *http.Request) *entity.User {
 	user, _ := r.Context().Value(UserCtx).(*entity.User)
 	return user
 }
+
+func FindSessionIDFromContext(r *http.Request) string {
+	session, _ := r.Context().Value(SessionCtx).(string)
+	return session
+}
@@ -1,27 +1,19 @@
 package middlewares
 
 import (
-	"errors"
 	"net/http"
 	"web_blog/cmd/main/utils"
-	"web_blog/internal/data/entity"
+	"web_blog/internal/policy"
 )
 
-func (middleware *Middleware) Authorization(role string) func(http.Handler) http.Handler {
+// Refuses users whose role lacks the permission.
+func (middleware *Middleware) Permission(permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var user *entity.User
-			var requiredRole *entity.Role
-			var err error
+			user := FindUserFromContext(r)
 
-			user = FindUserFromContext(r)
-			if requiredRole, err = middleware.Storage.Roles.FindByName(r.Context(), nil, role); err != nil {
-				utils.InternalServerErrorResponse(w, r, err)
-				return
-			}
-
-			if user.Role.Level < requiredRole.Level {
-				utils.ForbiddenResponse(w, r, errors.New("forbidden"))
+			if user == nil || !user.Role.Can(permission) {
+				utils.ForbiddenResponse(w, r, policy.ErrorForbidden)
 				return
 			}
 
@@ -29,3 +21,17 @@ func (middleware *Middleware) Authorization(role string) func(http.Handler) http
 		})
 	}
 }
+
+// Refuses users that the unverified policy does not allow to author content.
+func (middleware *Middleware) Verified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := FindUserFromContext(r)
+
+		if user == nil || !middleware.UnverifiedPolicy.CanAuthor(user) {
+			utils.ForbiddenResponse(w, r, utils.ErrorUnverified)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
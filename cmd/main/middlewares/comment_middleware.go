@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type commentKey string
+
+const CommentCtx commentKey = "comment"
+
+func (middleware *Middleware) CommentContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var id int
+		var comment *entity.Comment
+		var err error
+
+		if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
+			utils.InternalServerErrorResponse(w, r, err)
+			return
+		}
+
+		if comment, err = middleware.Storage.Comments.Find(ctx, int64(id)); err != nil {
+			utils.SwitchInternalServerErrorResponse(w, r, err)
+			return
+		}
+
+		// Comments waiting for approval under pre-moderation do not exist for
+		// anyone but their author.
+		if !middleware.ModerationPolicy.CanSee(FindUserFromContext(r), comment.UserID, comment.Verified) {
+			utils.NotFoundResponse(w, r, storage.ErrorNotFound)
+			return
+		}
+
+		ctx = context.WithValue(ctx, CommentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func FindCommentFromContext(r *http.Request) *entity.Comment {
+	comment, _ := r.Context().Value(CommentCtx).(*entity.Comment)
+	return comment
+}
@@ -3,9 +3,13 @@ package middlewares
 import (
 	"web_blog/internal/authentication"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
 )
 
 type Middleware struct {
-	Storage       *storage.Storage
-	Authenticator *authentication.StatefulAuthenticator
+	Storage          *storage.Storage
+	Authenticator    authentication.Authenticator
+	UnverifiedPolicy policy.Unverified
+	ModerationPolicy policy.Moderation
+	TrustedProxies   policy.TrustedProxies
 }
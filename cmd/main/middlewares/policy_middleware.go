@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/policy"
+)
+
+// Must run after the context middleware that loads the resource.
+func (middleware *Middleware) PostPolicy(policy policy.Policy[entity.Post]) func(http.Handler) http.Handler {
+	return authorize(policy, FindPostFromContext)
+}
+
+// Must run after CommentContext.
+func (middleware *Middleware) CommentPolicy(policy policy.Policy[entity.Comment]) func(http.Handler) http.Handler {
+	return authorize(policy, FindCommentFromContext)
+}
+
+func authorize[T any](policy policy.Policy[T], resource func(*http.Request) *T) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := policy.Authorize(FindUserFromContext(r), resource(r)); err != nil {
+				utils.ForbiddenResponse(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"web_blog/cmd/main/utils"
 	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
 )
 
 type postKey string
@@ -15,11 +16,6 @@ const PostCtx postKey = "post"
 
 func (middleware *Middleware) PostContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			next.ServeHTTP(w, r)
-			return
-		}
-
 		ctx := r.Context()
 		var id int
 		var post *entity.Post
@@ -30,11 +26,19 @@ func (middleware *Middleware) PostContext(next http.Handler) http.Handler {
 			return
 		}
 
-		if post, err = middleware.Storage.Posts.Find(ctx, nil, int64(id)); err != nil {
+		if post, err = middleware.Storage.Posts.Find(ctx, int64(id)); err != nil {
 			utils.SwitchInternalServerErrorResponse(w, r, err)
 			return
 		}
 
+		// Unpublished posts do not exist for anyone but their author, nor do
+		// posts waiting for approval under pre-moderation.
+		user := FindUserFromContext(r)
+		if !post.VisibleTo(user) || !middleware.ModerationPolicy.CanSee(user, post.UserID, post.Verified) {
+			utils.NotFoundResponse(w, r, storage.ErrorNotFound)
+			return
+		}
+
 		ctx = context.WithValue(ctx, PostCtx, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
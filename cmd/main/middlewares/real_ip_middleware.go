@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Replaces the remote address with the client's when the request was
+// forwarded by a trusted proxy. Forwarding headers from other peers are
+// ignored, since anyone can send them.
+func (middleware *Middleware) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+			var forwarded []string
+			for _, header := range r.Header.Values("X-Forwarded-For") {
+				forwarded = append(forwarded, strings.Split(header, ",")...)
+			}
+
+			if client := middleware.TrustedProxies.ClientIP(peer.Addr(), forwarded); client != peer.Addr().Unmap() {
+				r.RemoteAddr = client.String()
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
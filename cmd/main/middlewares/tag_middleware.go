@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type tagKey string
+
+const TagCtx tagKey = "tag"
+
+func (middleware *Middleware) TagContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var tag *entity.Tag
+		var err error
+
+		if tag, err = middleware.Storage.Tags.FindBySlug(ctx, chi.URLParam(r, "slug")); err != nil {
+			utils.SwitchInternalServerErrorResponse(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, TagCtx, tag)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func FindTagFromContext(r *http.Request) *entity.Tag {
+	tag, _ := r.Context().Value(TagCtx).(*entity.Tag)
+	return tag
+}
@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Background work run every interval until the scheduler is stopped.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(context.Context) error
+}
+
+type Scheduler struct {
+	Jobs   []Job
+	Logger *zap.Logger
+
+	wg sync.WaitGroup
+}
+
+// Starts a goroutine per job. Jobs stop once ctx is done, Wait blocks until
+// their last runs have returned.
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	for _, job := range scheduler.Jobs {
+		scheduler.wg.Add(1)
+		go func() {
+			defer scheduler.wg.Done()
+			scheduler.loop(ctx, job)
+		}()
+	}
+}
+
+func (scheduler *Scheduler) Wait() {
+	scheduler.wg.Wait()
+}
+
+func (scheduler *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		scheduler.run(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (scheduler *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			scheduler.Logger.Error("job panicked", zap.String("job", job.Name), zap.Any("panic", recovered))
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+		scheduler.Logger.Error("job failed", zap.String("job", job.Name), zap.Error(err))
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/mailer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Self-service endpoints of the authenticated user.
+type AccountService struct {
+	Storage *storage.Storage
+	Mailer  mailer.Mailer
+}
+
+// FindAccount godoc
+//
+//	@Summary		Get the current user
+//	@Description	Retrieve the account of the authenticated user
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	EnvelopeJson{data=entity.User}
+//	@Failure		401	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me [get]
+func (service *AccountService) FindAccount(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var err error
+
+	if user, err = findAccount(r, service.Storage); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+// Reads the authenticated user from storage. The user in the context may only
+// hold what the authenticator's token carries.
+func findAccount(r *http.Request, store *storage.Storage) (*entity.User, error) {
+	current := middlewares.FindUserFromContext(r)
+	var user *entity.User
+	var err error
+
+	if user, err = store.Users.Find(r.Context(), current.ID); err != nil {
+		return nil, err
+	}
+
+	user.Role = current.Role
+	return user, nil
+}
+
+type UpdateAccountPayload struct {
+	Username  *string `json:"username" validate:"omitempty,min=3,max=32"`
+	Bio       *string `json:"bio" validate:"omitempty,max=1024"`
+	AvatarURL *string `json:"avatar_url" validate:"omitempty,http_url,max=2048"`
+}
+
+// UpdateAccount godoc
+//
+//	@Summary		Update the current user
+//	@Description	Update the username, bio and avatar url of the authenticated user
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateAccountPayload	true	"Update payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.User}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me [patch]
+func (service *AccountService) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var payload UpdateAccountPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = findAccount(r, service.Storage); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if payload.Username != nil {
+		user.Username = *payload.Username
+	}
+
+	if payload.Bio != nil {
+		user.Bio = *payload.Bio
+	}
+
+	if payload.AvatarURL != nil {
+		user.AvatarURL = *payload.AvatarURL
+	}
+
+	if err = service.Storage.Users.Update(r.Context(), user); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=64"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=64,nefield=CurrentPassword"`
+}
+
+// ChangePassword godoc
+//
+//	@Summary		Change the password of the current user
+//	@Description	Change the password of the authenticated user, which logs them out of all other sessions
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	ChangePasswordPayload	true	"Password payload"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/password [post]
+func (service *AccountService) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	session := middlewares.FindSessionIDFromContext(r)
+	var user *entity.User
+	var payload ChangePasswordPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = findAccount(r, service.Storage); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = user.Password.Compare([]byte(payload.CurrentPassword)); err != nil {
+		utils.ForbiddenResponse(w, r, errors.New("current password is incorrect"))
+		return
+	}
+
+	if err = user.Password.Set(payload.NewPassword); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Users.Update(r.Context(), user); err != nil {
+			return err
+		}
+
+		if err := store.Sessions.DeleteAllByUserIDExcept(r.Context(), user.ID, session); err != nil {
+			return err
+		}
+
+		return sendMail(r, service.Mailer, mailer.TemplatePasswordChanged, user.Email, mailer.Data{Username: user.Username})
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ChangeEmailPayload struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,max=64"`
+}
+
+// ChangeEmail godoc
+//
+//	@Summary		Change the email of the current user
+//	@Description	Start verifying a new email for the authenticated user. The email is changed once it is verified
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ChangeEmailPayload	true	"Email payload"
+//	@Success		202		{object}	EnvelopeJson{data=entity.User}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/email [post]
+func (service *AccountService) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var payload ChangeEmailPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = findAccount(r, service.Storage); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = user.Password.Compare([]byte(payload.Password)); err != nil {
+		utils.ForbiddenResponse(w, r, errors.New("password is incorrect"))
+		return
+	}
+
+	if _, err = service.Storage.Users.FindByEmail(r.Context(), payload.Email); err == nil {
+		utils.ConflictResponse(w, r, storage.ErrorDuplicate)
+		return
+	} else if !errors.Is(err, storage.ErrorNotFound) {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	// Pending verifications are replaced, verifying the new email also
+	// verifies the account.
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Verifications.DeleteAllByUserID(r.Context(), user.ID); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+			return err
+		}
+
+		verification := &entity.Verification{UserID: user.ID, Email: &payload.Email}
+		if err := store.Verifications.Create(r.Context(), verification); err != nil {
+			return err
+		}
+
+		return sendMail(r, service.Mailer, mailer.TemplateVerification, payload.Email, mailer.Data{Username: user.Username, Token: verification.UUID.String()})
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusAccepted, user)
+}
+
+// FindAllSessions godoc
+//
+//	@Summary		Get the sessions of the current user
+//	@Description	Retrieve the sessions of the authenticated user that have not expired, most recently used first. The session of the request is marked as current
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	EnvelopeJson{data=[]entity.Session}
+//	@Failure		401	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/sessions [get]
+func (service *AccountService) FindAllSessions(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.FindUserFromContext(r)
+	current := middlewares.FindSessionIDFromContext(r)
+	var sessions []*entity.Session
+	var err error
+
+	if sessions, err = service.Storage.Sessions.FindAllByUserID(r.Context(), user.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == current
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, sessions)
+}
+
+// DeleteSession godoc
+//
+//	@Summary		Revoke a session of the current user
+//	@Description	Log the authenticated user out of one of their sessions, which can be the current one
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"Session ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/sessions/{id} [delete]
+func (service *AccountService) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.FindUserFromContext(r)
+	var session *entity.Session
+	var err error
+
+	if session, err = service.Storage.Sessions.Find(r.Context(), chi.URLParam(r, "id")); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	// Sessions of other users are not revealed.
+	if session.UserID != user.ID {
+		utils.NotFoundResponse(w, r, storage.ErrorNotFound)
+		return
+	}
+
+	if err = service.Storage.Sessions.Delete(r.Context(), session.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAllSessions godoc
+//
+//	@Summary		Log out everywhere
+//	@Description	Revoke all sessions of the authenticated user, including the current one
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/sessions [delete]
+func (service *AccountService) DeleteAllSessions(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.FindUserFromContext(r)
+	var err error
+
+	if err = service.Storage.Sessions.DeleteAllByUserID(r.Context(), user.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
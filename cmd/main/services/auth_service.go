@@ -1,19 +1,112 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 	"web_blog/cmd/main/utils"
 
 	"web_blog/internal/authentication"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/mailer"
+	"web_blog/internal/policy"
 
 	"github.com/google/uuid"
 )
 
 type AuthService struct {
 	Storage       *storage.Storage
-	Authenticator *authentication.StatefulAuthenticator
+	Authenticator authentication.Authenticator
+	Mailer        mailer.Mailer
+
+	// Minimum time taken by ForgotPassword, so its response time does not
+	// reveal whether the email exists.
+	PasswordResetDelay time.Duration
+
+	// Minimum time between two verifications sent to the same user.
+	VerificationResendInterval time.Duration
+
+	// Unverified users can not log in when it blocks them.
+	UnverifiedPolicy policy.Unverified
+
+	// Time users with two factor authentication have to send a code after
+	// their password, 5 minutes when zero.
+	LoginChallengeDuration time.Duration
+
+	// Failed logins are throttled per account and per address, so guessing
+	// is slowed down both for one account and across accounts.
+	AccountThrottle policy.LoginThrottle
+	IPThrottle      policy.LoginThrottle
+}
+
+var (
+	errorInvalidResetToken     = errors.New("reset token is invalid or expired")
+	errorVerificationUnknown   = &utils.CodedError{Code: "verification_unknown", Message: "verification not found"}
+	errorVerificationExpired   = &utils.CodedError{Code: "verification_expired", Message: "verification has expired"}
+	errorAlreadyVerified       = &utils.CodedError{Code: "already_verified", Message: "user is already verified"}
+	errorVerificationThrottled = &utils.CodedError{Code: "verification_throttled", Message: "verification was sent recently"}
+	errorInvalidChallenge      = errors.New("login challenge is invalid or expired")
+	errorInvalidCredentials    = &utils.CodedError{Code: "invalid_credentials", Message: "invalid credentials"}
+	errorLoginThrottled        = &utils.CodedError{Code: "login_throttled", Message: "too many failed logins"}
+	errorUserBanned            = errors.New("user is banned")
+)
+
+// Longer user agents are cut when stored with sessions.
+const userAgentLength = 256
+
+const loginChallengeDuration = time.Minute * 5
+
+// Compared against when the email is unknown, so the response time does not
+// reveal whether it exists.
+var dummyPassword = sync.OnceValue(func() entity.Password {
+	password := entity.Password{}
+	_ = password.Set("invalid credentials")
+	return password
+})
+
+// Accounts are keyed by email rather than id, so unknown emails are throttled
+// and locked out like existing ones.
+func accountLoginKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func ipLoginKey(r *http.Request) string {
+	return "ip:" + requestIP(r)
+}
+
+// RemoteAddr is the peer, or the client behind it when the RealIP middleware
+// trusts the peer as a proxy.
+func requestIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func truncate(text string, length int) string {
+	if runes := []rune(text); len(runes) > length {
+		return string(runes[:length])
+	}
+
+	return text
+}
+
+// Mail is queued as the last step of transactions, so they are rolled back
+// when it can not be.
+func sendMail(r *http.Request, sender mailer.Mailer, template string, to string, data mailer.Data) error {
+	message, err := mailer.Compose(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	return sender.Send(r.Context(), message)
 }
 
 type RegisterUserPayload struct {
@@ -25,7 +118,7 @@ type RegisterUserPayload struct {
 // RegisterUser godoc
 //
 //	@Summary		Register a new user
-//	@Description	Create a new user account with the provided details
+//	@Description	Create a new user account with the provided details and mail it a verification code
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
@@ -63,12 +156,18 @@ func (service *AuthService) RegisterUser(w http.ResponseWriter, r *http.Request)
 		Password: password,
 	}
 
-	if err = service.Storage.Users.CreateWithVerification(
-		r.Context(),
-		nil,
-		service.Storage.Verifications,
-		user,
-	); err != nil {
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Users.Create(r.Context(), user); err != nil {
+			return err
+		}
+
+		verification := &entity.Verification{UserID: user.ID}
+		if err := store.Verifications.Create(r.Context(), verification); err != nil {
+			return err
+		}
+
+		return sendMail(r, service.Mailer, mailer.TemplateVerification, user.Email, mailer.Data{Username: user.Username, Token: verification.UUID.String()})
+	}); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
@@ -83,13 +182,16 @@ type VerifyUserPayload struct {
 // VerifyUser godoc
 //
 //	@Summary		Verify a user account
-//	@Description	Verify a user using a UUID from an email or verification method
+//	@Description	Verify a user using a UUID from an email or verification method. Errors have the code verification_unknown, verification_expired or already_verified
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		VerifyUserPayload	true	"Verification details"
 //	@Success		200		{object}	EnvelopeJson{data=entity.User}
 //	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		410		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/authentication/verify [post]
 func (service *AuthService) VerifyUser(w http.ResponseWriter, r *http.Request) {
@@ -109,22 +211,151 @@ func (service *AuthService) VerifyUser(w http.ResponseWriter, r *http.Request) {
 
 	user = &entity.User{}
 
-	if err = service.Storage.Users.Verify(
-		r.Context(),
-		nil,
-		service.Storage.Verifications,
-		payload.UUID,
-		user,
-	); err != nil {
-		utils.SwitchInternalServerErrorResponse(w, r, err)
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		verification, err := store.Verifications.Find(r.Context(), payload.UUID)
+		if errors.Is(err, storage.ErrorNotFound) {
+			return errorVerificationUnknown
+		} else if err != nil {
+			return err
+		}
+
+		if verification.UsedAt != nil {
+			return errorAlreadyVerified
+		}
+
+		if !verification.ExpiredAt.After(time.Now()) {
+			return errorVerificationExpired
+		}
+
+		// The verification is pending, so the user was already verified by
+		// other means and it does not change their email.
+		if err := store.Users.Verify(r.Context(), payload.UUID, user); errors.Is(err, storage.ErrorNotFound) {
+			return errorAlreadyVerified
+		} else if err != nil {
+			return err
+		}
+
+		return store.Verifications.UseAllByUserID(r.Context(), user.ID)
+	}); err != nil {
+		switchVerificationErrorResponse(w, r, err)
 		return
 	}
 
 	utils.WriteJsonData(w, http.StatusOK, user)
 }
 
+type ResendVerificationPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResendVerification godoc
+//
+//	@Summary		Resend a verification
+//	@Description	Replace the pending verification of the user with the email and mail it again. Pending email changes are sent to the new email. Unknown emails are accepted without sending anything
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	ResendVerificationPayload	true	"Resend details"
+//	@Success		202		"Accepted"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		429		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/authentication/verify/resend [post]
+func (service *AuthService) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var latest *entity.Verification
+	var payload ResendVerificationPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = service.Storage.Users.FindByEmail(r.Context(), payload.Email); errors.Is(err, storage.ErrorNotFound) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	} else if err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if latest, err = service.Storage.Verifications.FindLatestByUserID(r.Context(), user.ID); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	// Verified users can only have email changes resent.
+	email := user.Email
+	if latest != nil && latest.UsedAt == nil && latest.Email != nil {
+		email = *latest.Email
+	} else if user.Verified {
+		utils.ConflictResponse(w, r, errorAlreadyVerified)
+		return
+	}
+
+	if latest != nil {
+		if wait := service.VerificationResendInterval - time.Since(latest.CreatedAt); wait > 0 {
+			utils.TooManyRequestsResponse(w, r, errorVerificationThrottled, wait)
+			return
+		}
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Verifications.DeleteAllByUserID(r.Context(), user.ID); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+			return err
+		}
+
+		verification := &entity.Verification{UserID: user.ID}
+		if email != user.Email {
+			verification.Email = &email
+		}
+
+		if err := store.Verifications.Create(r.Context(), verification); err != nil {
+			return err
+		}
+
+		return sendMail(r, service.Mailer, mailer.TemplateVerification, email, mailer.Data{Username: user.Username, Token: verification.UUID.String()})
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func switchVerificationErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errorVerificationUnknown):
+		utils.NotFoundResponse(w, r, err)
+	case errors.Is(err, errorVerificationExpired):
+		utils.GoneResponse(w, r, err)
+	case errors.Is(err, errorAlreadyVerified):
+		utils.ConflictResponse(w, r, err)
+	default:
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+	}
+}
+
+// The refresh token is only sent by authenticators whose tokens can be
+// refreshed.
 type TokenEnvelopeJson struct {
-	Token string `json:"token"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Sent instead of a token when the user has two factor authentication
+// enabled.
+type ChallengeEnvelopeJson struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type LoginUserPayload struct {
@@ -135,17 +366,20 @@ type LoginUserPayload struct {
 // LoginUser godoc
 //
 //	@Summary		User login
-//	@Description	Authenticate a user and return a session token
+//	@Description	Authenticate a user and return a session token, along with a refresh token when tokens are refreshable. Users with two factor authentication get a login challenge instead, see ChallengeEnvelopeJson, which is exchanged for the token with a code. Unknown emails and wrong passwords both fail with the code invalid_credentials, and repeated failures are throttled with the code login_throttled and a Retry-After header
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		LoginUserPayload	true	"Login details"
 //	@Success		202		{object}	EnvelopeJson{data=TokenEnvelopeJson}
 //	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		429		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/authentication/login [post]
 func (service *AuthService) LoginUser(w http.ResponseWriter, r *http.Request) {
-	var token string
+	var tokens *authentication.Tokens
 	var user *entity.User
 	var payload *LoginUserPayload
 	var err error
@@ -162,27 +396,314 @@ func (service *AuthService) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user, err = service.Storage.Users.FindByEmail(r.Context(), nil, payload.Email); err != nil {
+	accountKey, ipKey := accountLoginKey(payload.Email), ipLoginKey(r)
+
+	if wait, err := lockedOut(r, service.Storage, accountKey, ipKey); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	} else if wait > 0 {
+		utils.TooManyRequestsResponse(w, r, errorLoginThrottled, wait)
+		return
+	}
+
+	if user, err = service.Storage.Users.FindByEmail(r.Context(), payload.Email); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil {
+		password := dummyPassword()
+		_ = password.Compare([]byte(payload.Password))
+	} else {
+		err = user.Password.Compare([]byte(payload.Password))
+	}
+
+	if user == nil || err != nil {
+		var id *int64
+		if user != nil {
+			id = &user.ID
+		}
+
+		if err = failLogin(r, service.Storage, ipKey, service.IPThrottle, nil); err == nil {
+			err = failLogin(r, service.Storage, accountKey, service.AccountThrottle, id)
+		}
+
+		if err != nil {
+			utils.InternalServerErrorResponse(w, r, err)
+			return
+		}
+
+		utils.UnauthorizedResponse(w, r, errorInvalidCredentials)
+		return
+	}
+
+	if err = service.canLogin(r, user); err != nil {
+		switchLoginErrorResponse(w, r, err)
+		return
+	}
+
+	if user.TwoFactor {
+		service.challenge(w, r, user)
+		return
+	}
+
+	if tokens, err = service.login(r, user); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusAccepted, newTokenEnvelope(tokens))
+}
+
+// Refuses banned users, and unverified users when the unverified policy blocks
+// them. Checked again when a login challenge is exchanged, as the user may
+// have been banned in between.
+func (service *AuthService) canLogin(r *http.Request, user *entity.User) error {
+	ban, err := service.Storage.Bans.Find(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, storage.ErrorNotFound) {
+		return err
+	}
+
+	if ban != nil && ban.Active(time.Now()) {
+		return errorUserBanned
+	}
+
+	if !service.UnverifiedPolicy.CanLogin(user) {
+		return utils.ErrorUnverified
+	}
+
+	return nil
+}
+
+func switchLoginErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errorUserBanned) || errors.Is(err, utils.ErrorUnverified) {
+		utils.ForbiddenResponse(w, r, err)
+		return
+	}
+
+	utils.InternalServerErrorResponse(w, r, err)
+}
+
+// Failures of the address are kept, otherwise logging into one account would
+// reset guesses made against others.
+func (service *AuthService) login(r *http.Request, user *entity.User) (*authentication.Tokens, error) {
+	session := &entity.Session{
+		IP:        requestIP(r),
+		UserAgent: truncate(r.UserAgent(), userAgentLength),
+	}
+
+	if err := service.Storage.LoginFailures.Delete(r.Context(), accountLoginKey(user.Email)); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+		return nil, err
+	}
+
+	return service.Authenticator.Create(r.Context(), service.Storage, user, session)
+}
+
+// Returns how long logins with any of the keys are locked out for.
+func lockedOut(r *http.Request, store *storage.Storage, keys ...string) (time.Duration, error) {
+	var wait time.Duration
+	now := time.Now()
+
+	for _, key := range keys {
+		failure, err := store.LoginFailures.Find(r.Context(), key)
+		if errors.Is(err, storage.ErrorNotFound) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+
+		if failure.Locked(now) {
+			wait = max(wait, failure.LockedUntil.Sub(now))
+		}
+	}
+
+	return wait, nil
+}
+
+// Counts a failed login against the key and locks it out when the throttle
+// says so. Lockouts are audited with the user of the account, if it exists.
+func failLogin(r *http.Request, store *storage.Storage, key string, throttle policy.LoginThrottle, userID *int64) error {
+	var failure *entity.LoginFailure
+	var err error
+
+	now := time.Now()
+	since := time.Time{}
+	if throttle.Window > 0 {
+		since = now.Add(-throttle.Window)
+	}
+
+	if failure, err = store.LoginFailures.Increment(r.Context(), key, since); err != nil {
+		return err
+	}
+
+	until, lockout := throttle.LockedUntil(failure.Failures, now)
+	if until.IsZero() {
+		return nil
+	}
+
+	if err = store.LoginFailures.Lock(r.Context(), key, until); err != nil {
+		return err
+	}
+
+	if !lockout {
+		return nil
+	}
+
+	return store.AuditLog.Create(r.Context(), &entity.AuditEntry{
+		Action:  entity.AuditLoginLockout,
+		UserID:  userID,
+		IP:      requestIP(r),
+		Details: fmt.Sprintf("%s locked until %s after %d failed logins", key, until.UTC().Format(time.RFC3339), failure.Failures),
+	})
+}
+
+// Writes a login challenge for the user, which LoginTwoFactor exchanges for a
+// token along with a code.
+func (service *AuthService) challenge(w http.ResponseWriter, r *http.Request, user *entity.User) {
+	var challenge *entity.LoginChallenge
+	var token string
+	var err error
+
+	duration := service.LoginChallengeDuration
+	if duration <= 0 {
+		duration = loginChallengeDuration
+	}
+
+	if token, err = authentication.GenerateToken(); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	challenge = &entity.LoginChallenge{
+		ID:        authentication.HashToken(token),
+		UserID:    user.ID,
+		ExpiredAt: time.Now().Add(duration),
+	}
+
+	if err = service.Storage.LoginChallenges.Create(r.Context(), challenge); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusAccepted, ChallengeEnvelopeJson{Challenge: token, ExpiresAt: challenge.ExpiredAt})
+}
+
+type LoginTwoFactorPayload struct {
+	Challenge    string `json:"challenge" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"omitempty,max=64"`
+}
+
+// LoginTwoFactor godoc
+//
+//	@Summary		User login with two factor authentication
+//	@Description	Exchange a login challenge and a TOTP code or recovery code for a session token. Challenges are single use, a wrong code requires logging in again. Bans and login throttling are checked again, like when logging in
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		LoginTwoFactorPayload	true	"Two factor details"
+//	@Success		202		{object}	EnvelopeJson{data=TokenEnvelopeJson}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		429		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/authentication/login/two-factor [post]
+func (service *AuthService) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var challenge *entity.LoginChallenge
+	var user *entity.User
+	var tokens *authentication.Tokens
+	var payload LoginTwoFactorPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	id := authentication.HashToken(payload.Challenge)
+	if challenge, err = service.Storage.LoginChallenges.Find(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			utils.UnauthorizedResponse(w, r, errorInvalidChallenge)
+			return
+		}
+
 		utils.InternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	if err = user.Password.Compare([]byte(payload.Password)); err != nil {
+	// Deleting the challenge first lets only one request use it.
+	if err = service.Storage.LoginChallenges.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			utils.UnauthorizedResponse(w, r, errorInvalidChallenge)
+			return
+		}
+
 		utils.InternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	if token, err = service.Authenticator.Generate(); err != nil {
+	if challenge.Expired(time.Now()) {
+		utils.UnauthorizedResponse(w, r, errorInvalidChallenge)
+		return
+	}
+
+	if user, err = service.Storage.Users.Find(r.Context(), challenge.UserID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	accountKey, ipKey := accountLoginKey(user.Email), ipLoginKey(r)
+
+	if wait, err := lockedOut(r, service.Storage, accountKey, ipKey); err != nil {
 		utils.InternalServerErrorResponse(w, r, err)
 		return
+	} else if wait > 0 {
+		utils.TooManyRequestsResponse(w, r, errorLoginThrottled, wait)
+		return
 	}
 
-	if err = service.Authenticator.Create(r.Context(), service.Storage.Sessions, token, user.ID); err != nil {
+	if err = service.canLogin(r, user); err != nil {
+		switchLoginErrorResponse(w, r, err)
+		return
+	}
+
+	// Wrong codes count against the account like wrong passwords do.
+	if err = verifyTwoFactor(r, service.Storage, challenge.UserID, payload.Code, payload.RecoveryCode); err != nil {
+		if errors.Is(err, errorTwoFactorDisabled) {
+			err = errorTwoFactorInvalid
+		}
+
+		if errors.Is(err, errorTwoFactorInvalid) {
+			if err := failLogin(r, service.Storage, accountKey, service.AccountThrottle, &user.ID); err != nil {
+				utils.InternalServerErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		switchTwoFactorErrorResponse(w, r, err)
+		return
+	}
+
+	if tokens, err = service.login(r, user); err != nil {
 		utils.InternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	utils.WriteJsonData(w, http.StatusAccepted, TokenEnvelopeJson{Token: token})
+	utils.WriteJsonData(w, http.StatusAccepted, newTokenEnvelope(tokens))
+}
+
+func newTokenEnvelope(tokens *authentication.Tokens) TokenEnvelopeJson {
+	return TokenEnvelopeJson{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	}
 }
 
 type LogoutUserPayload struct {
@@ -192,7 +713,7 @@ type LogoutUserPayload struct {
 // LogoutUser godoc
 //
 //	@Summary		User logout
-//	@Description	Invalidate a session token for a user
+//	@Description	Invalidate the session of a token. The token is the refresh token when tokens are refreshable
 //	@Tags			authentication
 //	@Security		ApiKeyAuth
 //	@Accept			json
@@ -217,10 +738,211 @@ func (service *AuthService) LogoutUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = service.Authenticator.Invalidate(r.Context(), service.Storage.Sessions, payload.Token); err != nil {
+	if err = service.Authenticator.Invalidate(r.Context(), service.Storage, payload.Token); err != nil {
 		utils.NotFoundResponse(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RefreshToken godoc
+//
+//	@Summary		Refresh a token
+//	@Description	Exchange a refresh token for a new token and refresh token. Refresh tokens are single use, sending one again revokes its session. Only supported by authenticators whose tokens are refreshable
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh details"
+//	@Success		200		{object}	EnvelopeJson{data=TokenEnvelopeJson}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/authentication/refresh [post]
+func (service *AuthService) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var tokens *authentication.Tokens
+	var payload RefreshTokenPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if tokens, err = service.Authenticator.Refresh(r.Context(), service.Storage, payload.RefreshToken); err != nil {
+		switchRefreshErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, newTokenEnvelope(tokens))
+}
+
+func switchRefreshErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, authentication.ErrorRefreshUnsupported):
+		utils.BadRequestResponse(w, r, err)
+	case errors.Is(err, authentication.ErrorInvalidToken),
+		errors.Is(err, authentication.ErrorSessionExpired),
+		errors.Is(err, authentication.ErrorTokenReused):
+		utils.UnauthorizedResponse(w, r, err)
+	default:
+		utils.InternalServerErrorResponse(w, r, err)
+	}
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ForgotPassword godoc
+//
+//	@Summary		Request a password reset
+//	@Description	Mail a single use password reset token to the user with the email. The response is the same whether or not the email exists
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	ForgotPasswordPayload	true	"Forgot password details"
+//	@Success		202		"Accepted"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/authentication/password/forgot [post]
+func (service *AuthService) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	deadline := time.Now().Add(service.PasswordResetDelay)
+	var user *entity.User
+	var token string
+	var payload ForgotPasswordPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = service.Storage.Users.FindByEmail(r.Context(), payload.Email); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if user != nil {
+		if token, err = authentication.GenerateToken(); err != nil {
+			utils.InternalServerErrorResponse(w, r, err)
+			return
+		}
+
+		// Requesting a new token revokes the pending ones.
+		if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+			if err := store.PasswordResets.DeleteAllByUserID(r.Context(), user.ID); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+				return err
+			}
+
+			if err := store.PasswordResets.Create(r.Context(), &entity.PasswordReset{
+				ID:     authentication.HashToken(token),
+				UserID: user.ID,
+			}); err != nil {
+				return err
+			}
+
+			return sendMail(r, service.Mailer, mailer.TemplatePasswordReset, user.Email, mailer.Data{Username: user.Username, Token: token})
+		}); err != nil {
+			utils.InternalServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	time.Sleep(time.Until(deadline))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=64"`
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Reset a password
+//	@Description	Set a new password with a password reset token. The token can only be used once and all sessions of the user are revoked
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	ResetPasswordPayload	true	"Reset password details"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/authentication/password/reset [post]
+func (service *AuthService) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+	var password entity.Password
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	password = entity.Password{}
+	if err = password.Set(payload.Password); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		reset, err := store.PasswordResets.Find(r.Context(), authentication.HashToken(payload.Token))
+		if errors.Is(err, storage.ErrorNotFound) {
+			return errorInvalidResetToken
+		} else if err != nil {
+			return err
+		}
+
+		if time.Now().After(reset.ExpiredAt) {
+			return errorInvalidResetToken
+		}
+
+		if err := store.PasswordResets.DeleteAllByUserID(r.Context(), reset.UserID); err != nil {
+			return err
+		}
+
+		user, err := store.Users.Find(r.Context(), reset.UserID)
+		if err != nil {
+			return err
+		}
+
+		user.Password = password
+		if err := store.Users.Update(r.Context(), user); err != nil {
+			return err
+		}
+
+		if err := store.Sessions.DeleteAllByUserID(r.Context(), user.ID); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+			return err
+		}
+
+		return sendMail(r, service.Mailer, mailer.TemplatePasswordChanged, user.Email, mailer.Data{Username: user.Username})
+	}); errors.Is(err, errorInvalidResetToken) {
+		utils.BadRequestResponse(w, r, err)
+		return
+	} else if err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
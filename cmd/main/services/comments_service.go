@@ -3,20 +3,43 @@ package services
 import (
 	"net/http"
 	"strconv"
+	"time"
 	"web_blog/cmd/main/middlewares"
 	"web_blog/cmd/main/utils"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type CommentService struct {
-	Storage *storage.Storage
+	Storage    *storage.Storage
+	Moderation policy.Moderation
+
+	// Deepest depth replies can be nested at, 5 when zero. Comments on posts
+	// are at depth 0.
+	MaxDepth int
+
+	// Time authors have to edit their comments after creating them, 15
+	// minutes when zero. Roles allowed to edit any comment are not limited.
+	EditWindow time.Duration
 }
 
+var (
+	errorCommentTooDeep   = &utils.CodedError{Code: "comment_too_deep", Message: "comment is nested too deeply to reply to"}
+	errorEditWindowClosed = &utils.CodedError{Code: "edit_window_closed", Message: "comment can no longer be edited"}
+)
+
+const commentMaxDepth = 5
+
+const commentEditWindow = time.Minute * 15
+
+// Replies nested under every comment of trees when not asked for.
+const threadReplies = 3
+
 type CreateCommentPayload struct {
-	Content string `json:"content" service.Application.Validator:"required,max=512"`
+	Content string `json:"content" validate:"required,max=512"`
 }
 
 // CreateComment godoc
@@ -30,6 +53,7 @@ type CreateCommentPayload struct {
 //	@Param			payload	body		CreateCommentPayload	true	"Comment payload"
 //	@Success		201		{object}	EnvelopeJson{data=entity.Comment}
 //	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id}/comments [post]
@@ -60,7 +84,7 @@ func (service *CommentService) CreateComment(w http.ResponseWriter, r *http.Requ
 		Content: payload.Content,
 	}
 
-	if err = service.Storage.Comments.Create(r.Context(), nil, comment); err != nil {
+	if err = service.Storage.Comments.Create(r.Context(), comment); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
@@ -68,6 +92,171 @@ func (service *CommentService) CreateComment(w http.ResponseWriter, r *http.Requ
 	utils.WriteJsonData(w, http.StatusCreated, comment)
 }
 
+// CreateReply godoc
+//
+//	@Summary		Reply to a comment
+//	@Description	Add a reply to a comment, on the post of the comment. Replies nested deeper than allowed fail with the code comment_too_deep
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Comment ID"
+//	@Param			payload	body		CreateCommentPayload	true	"Comment payload"
+//	@Success		201		{object}	EnvelopeJson{data=entity.Comment}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/comments/{id}/replies [post]
+func (service *CommentService) CreateReply(w http.ResponseWriter, r *http.Request) {
+	var comment *entity.Comment
+	var payload CreateCommentPayload
+	var err error
+
+	parent := middlewares.FindCommentFromContext(r)
+
+	if err = service.checkPost(r, parent); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	maxDepth := service.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = commentMaxDepth
+	}
+
+	if parent.Depth >= maxDepth {
+		utils.BadRequestResponse(w, r, errorCommentTooDeep)
+		return
+	}
+
+	comment = &entity.Comment{
+		PostID:   parent.PostID,
+		ParentID: &parent.ID,
+		Depth:    parent.Depth + 1,
+		UserID:   middlewares.FindUserFromContext(r).ID,
+		Content:  payload.Content,
+	}
+
+	if err = service.Storage.Comments.Create(r.Context(), comment); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusCreated, comment)
+}
+
+// FindAllReplies godoc
+//
+//	@Summary		Get the replies of a comment
+//	@Description	Retrieve the direct replies of a comment, as a flat list or, with view=tree, as threads with up to replies replies nested under every reply
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Comment ID"
+//	@Param			view	query		string	false	"flat or tree"
+//	@Param			replies	query		int	false	"Replies nested under every comment of trees"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
+//	@Param			user_id	query		int	false	"User ID"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Comment}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/comments/{id}/replies [get]
+func (service *CommentService) FindAllReplies(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var thread storage.ThreadQuery
+	var page *storage.Page[entity.Comment]
+	var err error
+
+	comment := middlewares.FindCommentFromContext(r)
+
+	if err = service.checkPost(r, comment); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if filter, thread, err = parseThreadQuery(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	moderateFilter(r, service.Moderation, &filter, 0)
+
+	if thread.Tree() {
+		page, err = service.Storage.Comments.FindThreadsByParentID(r.Context(), filter, comment.ID, thread.Replies)
+	} else {
+		page, err = service.Storage.Comments.FindAllByParentID(r.Context(), filter, comment.ID)
+	}
+
+	if err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// Comments do not exist for anyone who can not see their post.
+func (service *CommentService) checkPost(r *http.Request, comment *entity.Comment) error {
+	user := middlewares.FindUserFromContext(r)
+	post, err := service.Storage.Posts.Find(r.Context(), comment.PostID)
+	if err != nil {
+		return err
+	}
+
+	if !post.VisibleTo(user) || !service.Moderation.CanSee(user, post.UserID, post.Verified) {
+		return storage.ErrorNotFound
+	}
+
+	return nil
+}
+
+func parseThreadQuery(r *http.Request) (storage.FilterQuery, storage.ThreadQuery, error) {
+	var err error
+
+	filter := storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.CommentListing,
+	}
+	thread := storage.ThreadQuery{Replies: threadReplies}
+
+	if err = filter.Parse(r); err != nil {
+		return filter, thread, err
+	}
+
+	if err = thread.Parse(r); err != nil {
+		return filter, thread, err
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		return filter, thread, err
+	}
+
+	if err = utils.ValidateStruct(thread); err != nil {
+		return filter, thread, err
+	}
+
+	return filter, thread, nil
+}
+
 // FindAllComments godoc
 //
 //	@Summary		Get all comments
@@ -77,18 +266,25 @@ func (service *CommentService) CreateComment(w http.ResponseWriter, r *http.Requ
 //	@Produce		json
 //	@Param			limit	query		int	false	"Limit"
 //	@Param			offset	query		int	false	"Offset"
-//	@Success		200		{array}		EnvelopeJson{data=[]entity.Comment}
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
+//	@Param			user_id	query		int	false	"User ID"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Comment}
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Security		ApiKeyAuth
 //	@Router			/posts/comments [get]
 func (service *CommentService) FindAllComments(w http.ResponseWriter, r *http.Request) {
 	var filter storage.FilterQuery
-	var comments []*entity.Comment
+	var page *storage.Page[entity.Comment]
 	var err error
 
 	filter = storage.FilterQuery{
-		Limit:  20,
-		Offset: 0,
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.CommentListing,
 	}
 
 	if err = filter.Parse(r); err != nil {
@@ -101,86 +297,212 @@ func (service *CommentService) FindAllComments(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if comments, err = service.Storage.Comments.FindAll(r.Context(), nil, filter); err != nil {
+	if page, err = service.Storage.Comments.FindAll(r.Context(), filter); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	utils.WriteJsonData(w, http.StatusOK, comments)
+	utils.WriteJsonPage(w, http.StatusOK, page)
 }
 
 // FindAllCommentsByPostID godoc
 //
 //	@Summary		Get all comments by post ID
-//	@Description	Retrieve all comments associated with a specific post
+//	@Description	Retrieve all comments associated with a specific post, as a flat list or, with view=tree, as threads of comments without a parent with up to replies replies nested under every comment. The remaining replies of a comment, counted by reply_count, are listed by its replies
 //	@Tags			comments
 //	@Accept			json
 //	@Produce		json
 //	@Param			id		path		int	true	"Post ID"
+//	@Param			view	query		string	false	"flat or tree"
+//	@Param			replies	query		int	false	"Replies nested under every comment of trees"
 //	@Param			limit	query		int	false	"Limit"
 //	@Param			offset	query		int	false	"Offset"
-//	@Success		200		{array}		EnvelopeJson{data=[]entity.Comment}
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
+//	@Param			user_id	query		int	false	"User ID"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Comment}
 //	@Failure		400		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/posts/{id}/comments [get]
 func (service *CommentService) FindAllCommentsByPostID(w http.ResponseWriter, r *http.Request) {
 	var filter storage.FilterQuery
-	var comments []*entity.Comment
+	var thread storage.ThreadQuery
+	var page *storage.Page[entity.Comment]
 	var id int
 	var err error
 
-	filter = storage.FilterQuery{
-		Limit:  20,
-		Offset: 0,
+	if filter, thread, err = parseThreadQuery(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
 	}
 
-	if err = filter.Parse(r); err != nil {
+	if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	moderateFilter(r, service.Moderation, &filter, 0)
+
+	if thread.Tree() {
+		page, err = service.Storage.Comments.FindThreadsByPostID(r.Context(), filter, int64(id), thread.Replies)
+	} else {
+		page, err = service.Storage.Comments.FindAllByPostID(r.Context(), filter, int64(id))
+	}
+
+	if err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=512"`
+}
+
+// UpdateComment godoc
+//
+//	@Summary		Update a comment
+//	@Description	Edit the content of a comment, keeping what it said before in its edits. Authors can only edit within the edit window, after which edits fail with the code edit_window_closed. Under pre-moderation, edited comments have to be approved again
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Comment ID"
+//	@Param			payload	body		UpdateCommentPayload	true	"Comment payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Comment}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/posts/comments/{id} [patch]
+func (service *CommentService) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	comment := middlewares.FindCommentFromContext(r)
+	user := middlewares.FindUserFromContext(r)
+	var payload UpdateCommentPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
 		utils.BadRequestResponse(w, r, err)
 		return
 	}
 
-	if err = utils.ValidateStruct(filter); err != nil {
+	if err = utils.ValidateStruct(payload); err != nil {
 		utils.BadRequestResponse(w, r, err)
 		return
 	}
 
-	if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
-		utils.InternalServerErrorResponse(w, r, err)
+	window := service.EditWindow
+	if window <= 0 {
+		window = commentEditWindow
+	}
+
+	if !user.Role.Can(entity.PermissionCommentsUpdateAny) && time.Since(comment.CreatedAt) > window {
+		utils.ForbiddenResponse(w, r, errorEditWindowClosed)
+		return
+	}
+
+	if payload.Content == comment.Content {
+		utils.WriteJsonData(w, http.StatusOK, comment)
 		return
 	}
 
-	if comments, err = service.Storage.Comments.FindAllByPostID(r.Context(), nil, filter, int64(id)); err != nil {
+	edit := &entity.CommentEdit{
+		CommentID: comment.ID,
+		EditorID:  &user.ID,
+		Content:   comment.Content,
+	}
+	comment.Content = payload.Content
+	if service.Moderation.ReviewsEdits() {
+		comment.Verified = false
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.CommentEdits.Create(r.Context(), edit); err != nil {
+			return err
+		}
+
+		return store.Comments.Update(r.Context(), comment)
+	}); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	utils.WriteJsonData(w, http.StatusOK, comments)
+	utils.WriteJsonData(w, http.StatusOK, comment)
+}
+
+// FindAllCommentEdits godoc
+//
+//	@Summary		Get edits of a comment
+//	@Description	Retrieve what a comment said before each of its edits, oldest first
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Comment ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.CommentEdit}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/posts/comments/{id}/edits [get]
+func (service *CommentService) FindAllCommentEdits(w http.ResponseWriter, r *http.Request) {
+	comment := middlewares.FindCommentFromContext(r)
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.CommentEdit]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.CommentEditListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.CommentEdits.FindAllByCommentID(r.Context(), filter, comment.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
 }
 
 // DeleteComment godoc
 //
 //	@Summary		Delete a comment
-//	@Description	Delete a specific comment by its ID
+//	@Description	Move a specific comment and its replies to the trash, from where they can be restored until purged
 //	@Tags			comments
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path	int	true	"Comment ID"
 //	@Success		204	"No Content"
 //	@Failure		400	{object}	ErrorEnvelopeJson
+//	@Failure		403	{object}	ErrorEnvelopeJson
 //	@Failure		404	{object}	ErrorEnvelopeJson
 //	@Failure		500	{object}	ErrorEnvelopeJson
 //	@Security		ApiKeyAuth
 //	@Router			/posts/comments/{id} [delete]
 func (service *CommentService) DeleteComment(w http.ResponseWriter, r *http.Request) {
-	var id int
+	comment := middlewares.FindCommentFromContext(r)
+	user := middlewares.FindUserFromContext(r)
 	var err error
 
-	if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
-		utils.InternalServerErrorResponse(w, r, err)
-		return
-	}
-
-	if err = service.Storage.Comments.Delete(r.Context(), nil, int64(id)); err != nil {
+	if err = service.Storage.Comments.SoftDelete(r.Context(), comment.ID, user.ID); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/mailer"
+	"web_blog/internal/policy"
+)
+
+type ModerationService struct {
+	Storage *storage.Storage
+	Mailer  mailer.Mailer
+}
+
+var errorAlreadyApproved = &utils.CodedError{Code: "already_approved", Message: "content is already approved"}
+
+// Approvals can leave a note for the author, rejections have to give a reason.
+type ApprovePayload struct {
+	Reason string `json:"reason" validate:"max=512"`
+}
+
+type RejectPayload struct {
+	Reason string `json:"reason" validate:"required,max=512"`
+}
+
+// FindModerationQueue godoc
+//
+//	@Summary		Get the moderation queue
+//	@Description	Retrieve the posts and comments waiting to be approved, oldest first. Drafts are left out until they are published or scheduled
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			type	query		string	false	"Types to list, comma separated: posts, comments"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.ModerationItem}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/queue [get]
+func (service *ModerationService) FindModerationQueue(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var queue storage.ModerationQuery
+	var page *storage.Page[entity.ModerationItem]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.ModerationListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = queue.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(queue); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Moderation.FindQueue(r.Context(), filter, queue); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// ApprovePost godoc
+//
+//	@Summary		Approve a post
+//	@Description	Verify a post, which makes it visible under pre-moderation, and notify its author
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Post ID"
+//	@Param			payload	body		ApprovePayload	true	"Optional note for the author"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Post}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/posts/{id}/approve [post]
+func (service *ModerationService) ApprovePost(w http.ResponseWriter, r *http.Request) {
+	var payload ApprovePayload
+	var post *entity.Post
+	var err error
+
+	if post, err = service.findPost(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if post.Verified {
+		utils.ConflictResponse(w, r, errorAlreadyApproved)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = service.decide(r, moderationDecision{
+		kind:     "post",
+		id:       post.ID,
+		author:   post.UserID,
+		title:    post.Title,
+		reason:   payload.Reason,
+		action:   entity.AuditPostApprove,
+		template: mailer.TemplateContentApproved,
+		apply: func(store *storage.Storage) error {
+			return store.Posts.Approve(r.Context(), post.ID)
+		},
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	post.Verified = true
+	utils.WriteJsonData(w, http.StatusOK, post)
+}
+
+// RejectPost godoc
+//
+//	@Summary		Reject a post
+//	@Description	Move a post and its comments to the trash and notify its author of the reason. Approved posts can be rejected too
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"Post ID"
+//	@Param			payload	body	RejectPayload	true	"Reason for the author"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/posts/{id}/reject [post]
+func (service *ModerationService) RejectPost(w http.ResponseWriter, r *http.Request) {
+	var payload RejectPayload
+	var post *entity.Post
+	var err error
+
+	if post, err = service.findPost(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	moderator := middlewares.FindUserFromContext(r)
+
+	if err = service.decide(r, moderationDecision{
+		kind:     "post",
+		id:       post.ID,
+		author:   post.UserID,
+		title:    post.Title,
+		reason:   payload.Reason,
+		action:   entity.AuditPostReject,
+		template: mailer.TemplateContentRejected,
+		apply: func(store *storage.Storage) error {
+			return store.Posts.SoftDelete(r.Context(), post.ID, moderator.ID)
+		},
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApproveComment godoc
+//
+//	@Summary		Approve a comment
+//	@Description	Verify a comment, which makes it visible under pre-moderation, and notify its author
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Comment ID"
+//	@Param			payload	body		ApprovePayload	true	"Optional note for the author"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Comment}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/comments/{id}/approve [post]
+func (service *ModerationService) ApproveComment(w http.ResponseWriter, r *http.Request) {
+	var payload ApprovePayload
+	var comment *entity.Comment
+	var post *entity.Post
+	var err error
+
+	if comment, post, err = service.findComment(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if comment.Verified {
+		utils.ConflictResponse(w, r, errorAlreadyApproved)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = service.decide(r, moderationDecision{
+		kind:     "comment",
+		id:       comment.ID,
+		author:   comment.UserID,
+		title:    post.Title,
+		reason:   payload.Reason,
+		action:   entity.AuditCommentApprove,
+		template: mailer.TemplateContentApproved,
+		apply: func(store *storage.Storage) error {
+			return store.Comments.Approve(r.Context(), comment.ID)
+		},
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	comment.Verified = true
+	utils.WriteJsonData(w, http.StatusOK, comment)
+}
+
+// RejectComment godoc
+//
+//	@Summary		Reject a comment
+//	@Description	Move a comment and its replies to the trash and notify its author of the reason. Approved comments can be rejected too
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"Comment ID"
+//	@Param			payload	body	RejectPayload	true	"Reason for the author"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/comments/{id}/reject [post]
+func (service *ModerationService) RejectComment(w http.ResponseWriter, r *http.Request) {
+	var payload RejectPayload
+	var comment *entity.Comment
+	var post *entity.Post
+	var err error
+
+	if comment, post, err = service.findComment(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	moderator := middlewares.FindUserFromContext(r)
+
+	if err = service.decide(r, moderationDecision{
+		kind:     "comment",
+		id:       comment.ID,
+		author:   comment.UserID,
+		title:    post.Title,
+		reason:   payload.Reason,
+		action:   entity.AuditCommentReject,
+		template: mailer.TemplateContentRejected,
+		apply: func(store *storage.Storage) error {
+			return store.Comments.SoftDelete(r.Context(), comment.ID, moderator.ID)
+		},
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// A moderation decision on a post or comment. The title is the title of the
+// post, which the comment is on for comments.
+type moderationDecision struct {
+	kind     string
+	id       int64
+	author   int64
+	title    string
+	reason   string
+	action   string
+	template string
+	apply    func(*storage.Storage) error
+}
+
+// Applies the decision, records it in the audit log and notifies the author,
+// all in one transaction.
+func (service *ModerationService) decide(r *http.Request, decision moderationDecision) error {
+	moderator := middlewares.FindUserFromContext(r)
+	author, err := service.Storage.Users.Find(r.Context(), decision.author)
+	if err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("%s %d", decision.kind, decision.id)
+	if decision.reason != "" {
+		details += ": " + decision.reason
+	}
+
+	return service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := decision.apply(store); err != nil {
+			return err
+		}
+
+		if err := store.AuditLog.Create(r.Context(), &entity.AuditEntry{
+			Action:  decision.action,
+			ActorID: &moderator.ID,
+			UserID:  &author.ID,
+			IP:      requestIP(r),
+			Details: details,
+		}); err != nil {
+			return err
+		}
+
+		return sendMail(r, service.Mailer, decision.template, author.Email, mailer.Data{
+			Username: author.Username,
+			Kind:     decision.kind,
+			Title:    decision.title,
+			Reason:   decision.reason,
+		})
+	})
+}
+
+// Moderators can moderate posts of any status, as the post context only finds
+// published ones for them.
+func (service *ModerationService) findPost(r *http.Request) (*entity.Post, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.Storage.Posts.Find(r.Context(), id)
+}
+
+// Finds the comment of the id path parameter with the post it is on.
+func (service *ModerationService) findComment(r *http.Request) (*entity.Comment, *entity.Post, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comment, err := service.Storage.Comments.Find(r.Context(), id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post, err := service.Storage.Posts.Find(r.Context(), comment.PostID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return comment, post, nil
+}
+
+// Under pre-moderation listings only hold approved content, unless the user
+// can see the unapproved content of the author, who is 0 for listings of
+// everyone's content.
+func moderateFilter(r *http.Request, moderation policy.Moderation, filter *storage.FilterQuery, author int64) {
+	if !moderation.CanSee(middlewares.FindUserFromContext(r), author, false) {
+		verified := true
+		filter.Verified = &verified
+	}
+}
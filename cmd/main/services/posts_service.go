@@ -1,23 +1,32 @@
 package services
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 	"web_blog/cmd/main/middlewares"
 	"web_blog/cmd/main/utils"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type PostService struct {
-	Storage *storage.Storage
+	Storage    *storage.Storage
+	Moderation policy.Moderation
 }
 
+// Posts are published right away unless created as drafts or scheduled, which
+// needs a publish_at time in the future.
 type CreatePostPayload struct {
-	Title   string `json:"title" validate:"required,max=128"`
-	Content string `json:"content" validate:"required,max=1024"`
+	Title     string     `json:"title" validate:"required,max=128"`
+	Content   string     `json:"content" validate:"required,max=1024"`
+	Tags      []string   `json:"tags" validate:"max=10,dive,required,max=32,slug"`
+	Status    string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 // CreatePost godoc
@@ -31,6 +40,7 @@ type CreatePostPayload struct {
 //	@Param			payload	body		CreatePostPayload	true	"Post payload"
 //	@Success		201		{object}	EnvelopeJson{data=entity.Post}
 //	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/posts [post]
 func (service *PostService) CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -50,10 +60,31 @@ func (service *PostService) CreatePost(w http.ResponseWriter, r *http.Request) {
 	post := &entity.Post{
 		Title:   payload.Title,
 		Content: payload.Content,
+		Tags:    payload.Tags,
+		Status:  entity.PostPublished,
 		UserID:  middlewares.FindUserFromContext(r).ID,
 	}
 
-	if err = service.Storage.Posts.Create(r.Context(), nil, post); err != nil {
+	if payload.Status != "" {
+		post.Status = payload.Status
+	}
+
+	if err = setPostStatus(post, post.Status, payload.PublishAt); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Posts.Create(r.Context(), post); err != nil {
+			return err
+		}
+
+		if err := store.Revisions.Create(r.Context(), newPostRevision(post, post.UserID)); err != nil {
+			return err
+		}
+
+		return store.Tags.SetPostTags(r.Context(), post)
+	}); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
@@ -70,18 +101,29 @@ func (service *PostService) CreatePost(w http.ResponseWriter, r *http.Request) {
 //	@Produce		json
 //	@Param			limit	query		int	false	"Limit"
 //	@Param			offset	query		int	false	"Offset"
-//	@Success		200		{array}		EnvelopeJson{data=entity.Post}
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
+//	@Param			user_id	query		int	false	"User ID"
+//	@Param			status	query		string	false	"Status, only authors can list unpublished posts"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Post}
+//	@Failure		403		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/posts [get]
 func (service *PostService) FindAllPosts(w http.ResponseWriter, r *http.Request) {
 	var filter storage.FilterQuery
-	var posts []*entity.Post
+	var page *storage.Page[entity.Post]
 	var err error
 	ctx := r.Context()
 
+	status := entity.PostPublished
 	filter = storage.FilterQuery{
-		Limit:  20,
-		Offset: 0,
+		Limit:   20,
+		Offset:  0,
+		Status:  &status,
+		Listing: storage.PostListing,
 	}
 
 	if err = filter.Parse(r); err != nil {
@@ -94,12 +136,19 @@ func (service *PostService) FindAllPosts(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if posts, err = service.Storage.Posts.FindAll(ctx, nil, filter); err != nil {
+	if err = authorizePostStatus(r, filter, 0); err != nil {
+		utils.ForbiddenResponse(w, r, err)
+		return
+	}
+
+	moderateFilter(r, service.Moderation, &filter, 0)
+
+	if page, err = service.Storage.Posts.FindAll(ctx, filter); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	utils.WriteJsonData(w, http.StatusOK, posts)
+	utils.WriteJsonPage(w, http.StatusOK, page)
 }
 
 // FindAllPostsByUserID godoc
@@ -112,20 +161,31 @@ func (service *PostService) FindAllPosts(w http.ResponseWriter, r *http.Request)
 //	@Param			id		path		int	true	"User ID"
 //	@Param			limit	query		int	false	"Limit"
 //	@Param			offset	query		int	false	"Offset"
-//	@Success		200		{array}		EnvelopeJson{data=entity.Post}
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
+//	@Param			user_id	query		int	false	"User ID"
+//	@Param			status	query		string	false	"Status, only authors can list unpublished posts"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Post}
 //	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/posts/user/{id} [get]
 func (service *PostService) FindAllPostsByUserID(w http.ResponseWriter, r *http.Request) {
 	var filter storage.FilterQuery
-	var posts []*entity.Post
+	var page *storage.Page[entity.Post]
 	var id int
 	var err error
 	ctx := r.Context()
 
+	status := entity.PostPublished
 	filter = storage.FilterQuery{
-		Limit:  20,
-		Offset: 0,
+		Limit:   20,
+		Offset:  0,
+		Status:  &status,
+		Listing: storage.PostListing,
 	}
 
 	if err = filter.Parse(r); err != nil {
@@ -143,12 +203,19 @@ func (service *PostService) FindAllPostsByUserID(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if posts, err = service.Storage.Posts.FindAllByUserID(ctx, nil, filter, int64(id)); err != nil {
+	if err = authorizePostStatus(r, filter, int64(id)); err != nil {
+		utils.ForbiddenResponse(w, r, err)
+		return
+	}
+
+	moderateFilter(r, service.Moderation, &filter, int64(id))
+
+	if page, err = service.Storage.Posts.FindAllByUserID(ctx, filter, int64(id)); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
 
-	utils.WriteJsonData(w, http.StatusOK, posts)
+	utils.WriteJsonPage(w, http.StatusOK, page)
 }
 
 // FindPost godoc
@@ -169,14 +236,17 @@ func (service *PostService) FindPost(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=128"`
-	Content *string `json:"content" validate:"omitempty,max=1024"`
+	Title     *string    `json:"title" validate:"omitempty,max=128"`
+	Content   *string    `json:"content" validate:"omitempty,max=1024"`
+	Tags      *[]string  `json:"tags" validate:"omitempty,max=10,dive,required,max=32,slug"`
+	Status    *string    `json:"status" validate:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Update a post
-//	@Description	Update the details of a specific post
+//	@Description	Update the details of a specific post. Under pre-moderation, a changed title or content has to be approved again
 //	@Tags			posts
 //	@Security		ApiKeyAuth
 //	@Accept			json
@@ -186,10 +256,12 @@ type UpdatePostPayload struct {
 //	@Success		200		{object}	EnvelopeJson{data=entity.Post}
 //	@Failure		400		{object}	ErrorEnvelopeJson
 //	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Router			/posts/{id} [patch]
 func (service *PostService) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	post := middlewares.FindPostFromContext(r)
+	user := middlewares.FindUserFromContext(r)
 	var payload UpdatePostPayload
 	var err error
 
@@ -203,6 +275,9 @@ func (service *PostService) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	edited := (payload.Title != nil && *payload.Title != post.Title) ||
+		(payload.Content != nil && *payload.Content != post.Content)
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
@@ -211,7 +286,40 @@ func (service *PostService) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		post.Content = *payload.Content
 	}
 
-	if err = service.Storage.Posts.Update(r.Context(), nil, post); err != nil {
+	if edited && service.Moderation.ReviewsEdits() {
+		post.Verified = false
+	}
+
+	if payload.Status != nil || payload.PublishAt != nil {
+		status := post.Status
+		if payload.Status != nil {
+			status = *payload.Status
+		}
+
+		if err = setPostStatus(post, status, payload.PublishAt); err != nil {
+			utils.BadRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Posts.Update(r.Context(), post); err != nil {
+			return err
+		}
+
+		if edited {
+			if err := store.Revisions.Create(r.Context(), newPostRevision(post, user.ID)); err != nil {
+				return err
+			}
+		}
+
+		if payload.Tags == nil {
+			return nil
+		}
+
+		post.Tags = *payload.Tags
+		return store.Tags.SetPostTags(r.Context(), post)
+	}); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
@@ -222,7 +330,7 @@ func (service *PostService) UpdatePost(w http.ResponseWriter, r *http.Request) {
 // DeletePost godoc
 //
 //	@Summary		Delete a post
-//	@Description	Delete a specific post by its ID
+//	@Description	Move a specific post and its comments to the trash, from where they can be restored until purged
 //	@Tags			posts
 //	@Security		ApiKeyAuth
 //	@Accept			json
@@ -230,23 +338,69 @@ func (service *PostService) UpdatePost(w http.ResponseWriter, r *http.Request) {
 //	@Param			id	path	int	true	"Post ID"
 //	@Success		204	"No Content"
 //	@Failure		400	{object}	ErrorEnvelopeJson
+//	@Failure		403	{object}	ErrorEnvelopeJson
 //	@Failure		404	{object}	ErrorEnvelopeJson
 //	@Failure		500	{object}	ErrorEnvelopeJson
 //	@Router			/posts/{id} [delete]
 func (service *PostService) DeletePost(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	var id int
+	post := middlewares.FindPostFromContext(r)
+	user := middlewares.FindUserFromContext(r)
 	var err error
 
-	if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
-		utils.InternalServerErrorResponse(w, r, err)
-		return
-	}
-
-	if err = service.Storage.Posts.Delete(ctx, nil, int64(id)); err != nil {
+	if err = service.Storage.Posts.SoftDelete(r.Context(), post.ID, user.ID); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Moves a post to a status. Scheduling needs a publish_at time in the future,
+// publishing sets it to now unless the post already was published, and drafts
+// have none.
+func setPostStatus(post *entity.Post, status string, publishAt *time.Time) error {
+	if publishAt != nil && status != entity.PostScheduled {
+		return errors.New("publish_at can only be set when scheduling a post")
+	}
+
+	switch status {
+	case entity.PostDraft:
+		post.PublishAt = nil
+	case entity.PostScheduled:
+		if publishAt == nil && post.Status == entity.PostScheduled {
+			publishAt = post.PublishAt
+		}
+
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return errors.New("scheduled posts need a publish_at time in the future")
+		}
+
+		post.PublishAt = publishAt
+	case entity.PostPublished:
+		if post.Status != entity.PostPublished && post.Status != entity.PostArchived {
+			post.PublishAt = nil
+		}
+	}
+
+	post.Status = status
+	return nil
+}
+
+// Only authors can list their unpublished posts, on their own post listing.
+func authorizePostStatus(r *http.Request, filter storage.FilterQuery, author int64) error {
+	user := middlewares.FindUserFromContext(r)
+	if *filter.Status == entity.PostPublished || (user != nil && user.ID == author) {
+		return nil
+	}
+
+	return errors.New("unpublished posts can only be listed by their author")
+}
+
+func newPostRevision(post *entity.Post, editor int64) *entity.PostRevision {
+	return &entity.PostRevision{
+		PostID:   post.ID,
+		EditorID: &editor,
+		Title:    post.Title,
+		Content:  post.Content,
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/diff"
+	"web_blog/internal/policy"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RevisionService struct {
+	Storage    *storage.Storage
+	Moderation policy.Moderation
+}
+
+type RevisionDiffEnvelope struct {
+	From    int64       `json:"from"`
+	To      int64       `json:"to"`
+	Title   []diff.Line `json:"title"`
+	Content []diff.Line `json:"content"`
+}
+
+// FindAllPostRevisions godoc
+//
+//	@Summary		Get revisions of a post
+//	@Description	Retrieve the revision history of a post, oldest first
+//	@Tags			revisions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.PostRevision}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/posts/{id}/revisions [get]
+func (service *RevisionService) FindAllPostRevisions(w http.ResponseWriter, r *http.Request) {
+	post := middlewares.FindPostFromContext(r)
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.PostRevision]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.RevisionListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Revisions.FindAllByPostID(r.Context(), filter, post.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// FindPostRevision godoc
+//
+//	@Summary		Get a revision of a post
+//	@Description	Retrieve a specific revision of a post
+//	@Tags			revisions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"Post ID"
+//	@Param			revision	path		int	true	"Revision ID"
+//	@Success		200			{object}	EnvelopeJson{data=entity.PostRevision}
+//	@Failure		404			{object}	ErrorEnvelopeJson
+//	@Failure		500			{object}	ErrorEnvelopeJson
+//	@Router			/posts/{id}/revisions/{revision} [get]
+func (service *RevisionService) FindPostRevision(w http.ResponseWriter, r *http.Request) {
+	post := middlewares.FindPostFromContext(r)
+	var revision *entity.PostRevision
+	var err error
+
+	if revision, err = service.findRevision(r.Context(), post, chi.URLParam(r, "revision")); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, revision)
+}
+
+// DiffPostRevisions godoc
+//
+//	@Summary		Diff two revisions of a post
+//	@Description	Line-level diff of the title and content of two revisions of a post
+//	@Tags			revisions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			from	query		int	true	"Revision ID to diff from"
+//	@Param			to		query		int	true	"Revision ID to diff to"
+//	@Success		200		{object}	EnvelopeJson{data=RevisionDiffEnvelope}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/posts/{id}/revisions/diff [get]
+func (service *RevisionService) DiffPostRevisions(w http.ResponseWriter, r *http.Request) {
+	post := middlewares.FindPostFromContext(r)
+	var from *entity.PostRevision
+	var to *entity.PostRevision
+	var err error
+
+	query := r.URL.Query()
+	if query.Get("from") == "" || query.Get("to") == "" {
+		utils.BadRequestResponse(w, r, errors.New("from and to revisions are required"))
+		return
+	}
+
+	if from, err = service.findRevision(r.Context(), post, query.Get("from")); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if to, err = service.findRevision(r.Context(), post, query.Get("to")); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, RevisionDiffEnvelope{
+		From:    from.ID,
+		To:      to.ID,
+		Title:   diff.Lines(from.Title, to.Title),
+		Content: diff.Lines(from.Content, to.Content),
+	})
+}
+
+// RestorePostRevision godoc
+//
+//	@Summary		Restore a revision of a post
+//	@Description	Restore the title and content of an older revision, which is stored as a new revision. Under pre-moderation, the restored post has to be approved again
+//	@Tags			revisions
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int	true	"Post ID"
+//	@Param			revision	path		int	true	"Revision ID"
+//	@Success		200			{object}	EnvelopeJson{data=entity.Post}
+//	@Failure		403			{object}	ErrorEnvelopeJson
+//	@Failure		404			{object}	ErrorEnvelopeJson
+//	@Failure		500			{object}	ErrorEnvelopeJson
+//	@Router			/posts/{id}/revisions/{revision}/restore [post]
+func (service *RevisionService) RestorePostRevision(w http.ResponseWriter, r *http.Request) {
+	post := middlewares.FindPostFromContext(r)
+	user := middlewares.FindUserFromContext(r)
+	var revision *entity.PostRevision
+	var err error
+
+	if revision, err = service.findRevision(r.Context(), post, chi.URLParam(r, "revision")); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if (revision.Title != post.Title || revision.Content != post.Content) && service.Moderation.ReviewsEdits() {
+		post.Verified = false
+	}
+
+	post.Title = revision.Title
+	post.Content = revision.Content
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Posts.Update(r.Context(), post); err != nil {
+			return err
+		}
+
+		return store.Revisions.Create(r.Context(), newPostRevision(post, user.ID))
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, post)
+}
+
+// Revisions of other posts are not found.
+func (service *RevisionService) findRevision(ctx context.Context, post *entity.Post, param string) (*entity.PostRevision, error) {
+	var revision *entity.PostRevision
+	var id int
+	var err error
+
+	if id, err = strconv.Atoi(param); err != nil {
+		return nil, storage.ErrorNotFound
+	}
+
+	if revision, err = service.Storage.Revisions.Find(ctx, int64(id)); err != nil {
+		return nil, err
+	}
+
+	if revision.PostID != post.ID {
+		return nil, storage.ErrorNotFound
+	}
+
+	return revision, nil
+}
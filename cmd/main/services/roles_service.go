@@ -0,0 +1,331 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RoleService struct {
+	Storage *storage.Storage
+}
+
+var errorUnknownPermission = errors.New("unknown permission")
+
+// FindAllPermissions godoc
+//
+//	@Summary		Get all permissions
+//	@Description	Retrieve a list of all permissions that can be assigned to roles
+//	@Tags			roles
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Permission}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/admin/permissions [get]
+func (service *RoleService) FindAllPermissions(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.Permission]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.PermissionListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Permissions.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// FindAllRoles godoc
+//
+//	@Summary		Get all roles
+//	@Description	Retrieve a list of all roles with their permissions
+//	@Tags			roles
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Role}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/admin/roles [get]
+func (service *RoleService) FindAllRoles(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.Role]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.RoleListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Roles.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+type CreateRolePayload struct {
+	Name        string   `json:"name" validate:"required,max=255"`
+	Description string   `json:"description" validate:"max=1024"`
+	Level       int      `json:"level" validate:"min=0"`
+	Permissions []string `json:"permissions" validate:"dive,required,max=255"`
+}
+
+// CreateRole godoc
+//
+//	@Summary		Create a role
+//	@Description	Create a new role with a set of permissions
+//	@Tags			roles
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateRolePayload	true	"Role payload"
+//	@Success		201		{object}	EnvelopeJson{data=entity.Role}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/admin/roles [post]
+func (service *RoleService) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var payload CreateRolePayload
+	var role *entity.Role
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	role = &entity.Role{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Level:       payload.Level,
+		Permissions: payload.Permissions,
+	}
+
+	// New roles are not cached yet, so they can be written within a
+	// transaction that bypasses the role cache.
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Roles.Create(r.Context(), role); err != nil {
+			return err
+		}
+
+		return setRolePermissions(r, store, role)
+	}); err != nil {
+		switchRoleErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusCreated, role)
+}
+
+type SetRolePermissionsPayload struct {
+	Permissions []string `json:"permissions" validate:"required,dive,required,max=255"`
+}
+
+// SetRolePermissions godoc
+//
+//	@Summary		Assign permissions to a role
+//	@Description	Replace the permissions of a role
+//	@Tags			roles
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Role ID"
+//	@Param			payload	body		SetRolePermissionsPayload	true	"Permissions payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Role}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/admin/roles/{id}/permissions [put]
+func (service *RoleService) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	var payload SetRolePermissionsPayload
+	var role *entity.Role
+	var id int
+	var err error
+
+	if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
+		utils.NotFoundResponse(w, r, storage.ErrorNotFound)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if role, err = service.Storage.Roles.Find(r.Context(), int64(id)); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	role.Permissions = payload.Permissions
+	if err = setRolePermissions(r, service.Storage, role); err != nil {
+		switchRoleErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, role)
+}
+
+// FindRoleTwoFactor godoc
+//
+//	@Summary		Get the two factor requirement of roles
+//	@Description	Retrieve the role level at or above which users have to enable two factor authentication before they can use their account, none when zero
+//	@Tags			roles
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Success		200	{object}	EnvelopeJson{data=entity.Settings}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Router			/admin/roles/two-factor [get]
+func (service *RoleService) FindRoleTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var settings *entity.Settings
+	var err error
+
+	if settings, err = service.Storage.Settings.Find(r.Context()); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, settings)
+}
+
+type SetRoleTwoFactorPayload struct {
+	Level *int `json:"level" validate:"required,min=0"`
+}
+
+// SetRoleTwoFactor godoc
+//
+//	@Summary		Require two factor authentication for roles
+//	@Description	Set the role level at or above which users have to enable two factor authentication before they can use their account, zero requires it of nobody. The change is audited
+//	@Tags			roles
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		SetRoleTwoFactorPayload	true	"Two factor payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Settings}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/admin/roles/two-factor [put]
+func (service *RoleService) SetRoleTwoFactor(w http.ResponseWriter, r *http.Request) {
+	admin := middlewares.FindUserFromContext(r)
+	var payload SetRoleTwoFactorPayload
+	var settings *entity.Settings
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if settings, err = service.Storage.Settings.Find(r.Context()); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	previous := settings.TwoFactorLevel
+	settings.TwoFactorLevel = *payload.Level
+
+	// Not written within a transaction, so the update goes through the
+	// settings cache and clears it.
+	if err = service.Storage.Settings.Update(r.Context(), settings); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.AuditLog.Create(r.Context(), &entity.AuditEntry{
+		Action:  entity.AuditSettingsUpdate,
+		ActorID: &admin.ID,
+		IP:      requestIP(r),
+		Details: fmt.Sprintf("two factor level changed from %d to %d", previous, settings.TwoFactorLevel),
+	}); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, settings)
+}
+
+// Unknown permissions are reported as bad requests rather than as a missing
+// role.
+func setRolePermissions(r *http.Request, store *storage.Storage, role *entity.Role) error {
+	if err := store.Roles.SetPermissions(r.Context(), role); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			return errorUnknownPermission
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func switchRoleErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errorUnknownPermission) {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	utils.SwitchInternalServerErrorResponse(w, r, err)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"net/http"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
+)
+
+type SearchService struct {
+	Storage    *storage.Storage
+	Moderation policy.Moderation
+}
+
+// Search godoc
+//
+//	@Summary		Search posts and comments
+//	@Description	Full-text search over posts and comments, ranked by relevance, with matches highlighted in snippets
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	true	"Search query"
+//	@Param			type	query		string	false	"Types to search, comma separated: posts, comments"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.SearchResult}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/search [get]
+func (service *SearchService) Search(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var search storage.SearchQuery
+	var page *storage.Page[entity.SearchResult]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.SearchListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = search.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(search); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	search.Approved = service.Moderation == policy.ModerationPre
+
+	if page, err = service.Storage.Search.Search(r.Context(), filter, search); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
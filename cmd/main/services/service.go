@@ -12,11 +12,32 @@ type IAuthenticationService interface {
 	RegisterUser(http.ResponseWriter, *http.Request)
 	VerifyUser(http.ResponseWriter, *http.Request)
 	LoginUser(http.ResponseWriter, *http.Request)
+	LoginTwoFactor(http.ResponseWriter, *http.Request)
 	LogoutUser(http.ResponseWriter, *http.Request)
+	RefreshToken(http.ResponseWriter, *http.Request)
+	ResendVerification(http.ResponseWriter, *http.Request)
+	ForgotPassword(http.ResponseWriter, *http.Request)
+	ResetPassword(http.ResponseWriter, *http.Request)
 }
 
 type IUserService interface {
 	FindAllUsers(http.ResponseWriter, *http.Request)
+	FindUser(http.ResponseWriter, *http.Request)
+	UpdateUserRole(http.ResponseWriter, *http.Request)
+	BanUser(http.ResponseWriter, *http.Request)
+	UnbanUser(http.ResponseWriter, *http.Request)
+	UnlockUser(http.ResponseWriter, *http.Request)
+	DeleteUser(http.ResponseWriter, *http.Request)
+}
+
+type IAccountService interface {
+	FindAccount(http.ResponseWriter, *http.Request)
+	UpdateAccount(http.ResponseWriter, *http.Request)
+	ChangePassword(http.ResponseWriter, *http.Request)
+	ChangeEmail(http.ResponseWriter, *http.Request)
+	FindAllSessions(http.ResponseWriter, *http.Request)
+	DeleteSession(http.ResponseWriter, *http.Request)
+	DeleteAllSessions(http.ResponseWriter, *http.Request)
 }
 
 type IPostService interface {
@@ -32,13 +53,76 @@ type ICommentService interface {
 	CreateComment(http.ResponseWriter, *http.Request)
 	FindAllComments(http.ResponseWriter, *http.Request)
 	FindAllCommentsByPostID(http.ResponseWriter, *http.Request)
+	CreateReply(http.ResponseWriter, *http.Request)
+	FindAllReplies(http.ResponseWriter, *http.Request)
+	UpdateComment(http.ResponseWriter, *http.Request)
+	FindAllCommentEdits(http.ResponseWriter, *http.Request)
 	DeleteComment(http.ResponseWriter, *http.Request)
 }
 
+type IRevisionService interface {
+	FindAllPostRevisions(http.ResponseWriter, *http.Request)
+	FindPostRevision(http.ResponseWriter, *http.Request)
+	DiffPostRevisions(http.ResponseWriter, *http.Request)
+	RestorePostRevision(http.ResponseWriter, *http.Request)
+}
+
+type ITagService interface {
+	FindAllTags(http.ResponseWriter, *http.Request)
+	FindAllPostsByTag(http.ResponseWriter, *http.Request)
+	RenameTag(http.ResponseWriter, *http.Request)
+	MergeTag(http.ResponseWriter, *http.Request)
+}
+
+type IRoleService interface {
+	FindAllPermissions(http.ResponseWriter, *http.Request)
+	FindAllRoles(http.ResponseWriter, *http.Request)
+	CreateRole(http.ResponseWriter, *http.Request)
+	SetRolePermissions(http.ResponseWriter, *http.Request)
+	FindRoleTwoFactor(http.ResponseWriter, *http.Request)
+	SetRoleTwoFactor(http.ResponseWriter, *http.Request)
+}
+
+type ITwoFactorService interface {
+	EnableTwoFactor(http.ResponseWriter, *http.Request)
+	ConfirmTwoFactor(http.ResponseWriter, *http.Request)
+	DisableTwoFactor(http.ResponseWriter, *http.Request)
+	RegenerateRecoveryCodes(http.ResponseWriter, *http.Request)
+}
+
+type ISearchService interface {
+	Search(http.ResponseWriter, *http.Request)
+}
+
+type ITrashService interface {
+	FindAllDeletedPosts(http.ResponseWriter, *http.Request)
+	FindAllDeletedComments(http.ResponseWriter, *http.Request)
+	FindAllDeletedUsers(http.ResponseWriter, *http.Request)
+	RestorePost(http.ResponseWriter, *http.Request)
+	RestoreComment(http.ResponseWriter, *http.Request)
+	RestoreUser(http.ResponseWriter, *http.Request)
+}
+
+type IModerationService interface {
+	FindModerationQueue(http.ResponseWriter, *http.Request)
+	ApprovePost(http.ResponseWriter, *http.Request)
+	RejectPost(http.ResponseWriter, *http.Request)
+	ApproveComment(http.ResponseWriter, *http.Request)
+	RejectComment(http.ResponseWriter, *http.Request)
+}
+
 type Services struct {
-	Health  IHealthService
-	Auth    IAuthenticationService
-	User    IUserService
-	Post    IPostService
-	Comment ICommentService
+	Health     IHealthService
+	Auth       IAuthenticationService
+	User       IUserService
+	Account    IAccountService
+	TwoFactor  ITwoFactorService
+	Role       IRoleService
+	Post       IPostService
+	Comment    ICommentService
+	Revision   IRevisionService
+	Tag        ITagService
+	Search     ISearchService
+	Trash      ITrashService
+	Moderation IModerationService
 }
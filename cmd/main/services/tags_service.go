@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
+)
+
+type TagService struct {
+	Storage    *storage.Storage
+	Moderation policy.Moderation
+}
+
+// FindAllTags godoc
+//
+//	@Summary		Get all tags
+//	@Description	Retrieve a list of all tags with the number of posts using them, most used first
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Tag}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/tags [get]
+func (service *TagService) FindAllTags(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.Tag]
+	var err error
+
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.TagListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Tags.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// FindAllPostsByTag godoc
+//
+//	@Summary		Get posts by tag
+//	@Description	Retrieve all posts tagged with a specific tag
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug	path		string	true	"Tag slug"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
+//	@Param			user_id	query		int		false	"User ID"
+//	@Param			status	query		string	false	"Status, only authors can list unpublished posts"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Post}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/tags/{slug}/posts [get]
+func (service *TagService) FindAllPostsByTag(w http.ResponseWriter, r *http.Request) {
+	tag := middlewares.FindTagFromContext(r)
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.Post]
+	var err error
+
+	status := entity.PostPublished
+	filter = storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Status:  &status,
+		Listing: storage.PostListing,
+	}
+
+	if err = filter.Parse(r); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(filter); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = authorizePostStatus(r, filter, 0); err != nil {
+		utils.ForbiddenResponse(w, r, err)
+		return
+	}
+
+	moderateFilter(r, service.Moderation, &filter, 0)
+
+	if page, err = service.Storage.Posts.FindAllByTagID(r.Context(), filter, tag.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+type RenameTagPayload struct {
+	Name string `json:"name" validate:"required,max=32,slug"`
+}
+
+// RenameTag godoc
+//
+//	@Summary		Rename a tag
+//	@Description	Rename a tag, which also changes its slug. Renaming onto an existing tag fails, merge them instead
+//	@Tags			tags
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug	path		string				true	"Tag slug"
+//	@Param			payload	body		RenameTagPayload	true	"Rename payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Tag}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/tags/{slug} [patch]
+func (service *TagService) RenameTag(w http.ResponseWriter, r *http.Request) {
+	tag := middlewares.FindTagFromContext(r)
+	var payload RenameTagPayload
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	tag.Name = payload.Name
+	if err = service.Storage.Tags.Update(r.Context(), tag); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, tag)
+}
+
+type MergeTagPayload struct {
+	Into string `json:"into" validate:"required"`
+}
+
+// MergeTag godoc
+//
+//	@Summary		Merge a tag into another
+//	@Description	Move all posts of a tag to another tag and delete it
+//	@Tags			tags
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug	path		string			true	"Tag slug"
+//	@Param			payload	body		MergeTagPayload	true	"Merge payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.Tag}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Router			/tags/{slug}/merge [post]
+func (service *TagService) MergeTag(w http.ResponseWriter, r *http.Request) {
+	tag := middlewares.FindTagFromContext(r)
+	var payload MergeTagPayload
+	var into *entity.Tag
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if into, err = service.Storage.Tags.FindBySlug(r.Context(), entity.Slugify(payload.Into)); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if into.ID == tag.ID {
+		utils.BadRequestResponse(w, r, errors.New("tag can not be merged into itself"))
+		return
+	}
+
+	if err = service.Storage.Tags.Merge(r.Context(), tag, into); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, into)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TrashService struct {
+	Storage *storage.Storage
+}
+
+// FindAllDeletedPosts godoc
+//
+//	@Summary		Get deleted posts
+//	@Description	Retrieve the posts in the trash, of any status
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			user_id	query		int		false	"User ID"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Post}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/trash/posts [get]
+func (service *TrashService) FindAllDeletedPosts(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.Post]
+	var err error
+
+	if filter, err = parseTrashFilter(r, storage.PostListing); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Posts.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// FindAllDeletedComments godoc
+//
+//	@Summary		Get deleted comments
+//	@Description	Retrieve the comments in the trash
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			user_id	query		int		false	"User ID"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.Comment}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/trash/comments [get]
+func (service *TrashService) FindAllDeletedComments(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.Comment]
+	var err error
+
+	if filter, err = parseTrashFilter(r, storage.CommentListing); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Comments.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// FindAllDeletedUsers godoc
+//
+//	@Summary		Get deleted users
+//	@Description	Retrieve the users in the trash
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Success		200		{object}	PageEnvelopeJson{data=[]entity.User}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/trash/users [get]
+func (service *TrashService) FindAllDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	var filter storage.FilterQuery
+	var page *storage.Page[entity.User]
+	var err error
+
+	if filter, err = parseTrashFilter(r, storage.UserListing); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if page, err = service.Storage.Users.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// RestorePost godoc
+//
+//	@Summary		Restore a post
+//	@Description	Move a post out of the trash, with the comments that were deleted along with it. Posts of deleted users can not be restored
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	EnvelopeJson{data=entity.Post}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/trash/posts/{id}/restore [post]
+func (service *TrashService) RestorePost(w http.ResponseWriter, r *http.Request) {
+	var post *entity.Post
+	var id int64
+	var err error
+
+	if id, err = parseID(r); err != nil {
+		utils.NotFoundResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.Posts.Restore(r.Context(), id); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if post, err = service.Storage.Posts.Find(r.Context(), id); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, post)
+}
+
+// RestoreComment godoc
+//
+//	@Summary		Restore a comment
+//	@Description	Move a comment out of the trash, with the replies that were deleted along with it. Comments on deleted posts, replies to deleted comments and comments of deleted users can not be restored
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Comment ID"
+//	@Success		200	{object}	EnvelopeJson{data=entity.Comment}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/trash/comments/{id}/restore [post]
+func (service *TrashService) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	var comment *entity.Comment
+	var id int64
+	var err error
+
+	if id, err = parseID(r); err != nil {
+		utils.NotFoundResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.Comments.Restore(r.Context(), id); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if comment, err = service.Storage.Comments.Find(r.Context(), id); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, comment)
+}
+
+// RestoreUser godoc
+//
+//	@Summary		Restore a user
+//	@Description	Move a user out of the trash, with the posts and comments that were deleted along with them
+//	@Tags			trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	EnvelopeJson{data=entity.User}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/trash/users/{id}/restore [post]
+func (service *TrashService) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var id int64
+	var err error
+
+	if id, err = parseID(r); err != nil {
+		utils.NotFoundResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.Users.Restore(r.Context(), id); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if user, err = service.Storage.Users.Find(r.Context(), id); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+// Parses the filter of a trash listing, which lists only deleted rows.
+func parseTrashFilter(r *http.Request, listing storage.Listing) (storage.FilterQuery, error) {
+	filter := storage.FilterQuery{
+		Limit:   20,
+		Offset:  0,
+		Listing: listing,
+	}
+
+	if err := filter.Parse(r); err != nil {
+		return filter, err
+	}
+
+	if err := utils.ValidateStruct(filter); err != nil {
+		return filter, err
+	}
+
+	filter.Deleted = true
+	return filter, nil
+}
+
+// Parses the id path parameter, which does not match any row when invalid.
+func parseID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return 0, storage.ErrorNotFound
+	}
+
+	return id, nil
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"web_blog/cmd/main/middlewares"
+	"web_blog/cmd/main/utils"
+	"web_blog/internal/authentication"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
+	"web_blog/internal/totp"
+)
+
+// Two factor authentication of the authenticated user, with TOTP codes and
+// recovery codes.
+type TwoFactorService struct {
+	Storage *storage.Storage
+
+	// Shown by authenticator apps next to the account.
+	Issuer string
+
+	// Wrong codes count against the account like failed logins, so codes can
+	// not be guessed with a stolen token either.
+	AccountThrottle policy.LoginThrottle
+}
+
+var (
+	errorTwoFactorInvalid  = &utils.CodedError{Code: "two_factor_invalid", Message: "two factor code is invalid"}
+	errorTwoFactorEnabled  = errors.New("two factor authentication is already enabled")
+	errorTwoFactorDisabled = errors.New("two factor authentication is not enabled")
+	errorTwoFactorPending  = errors.New("two factor authentication is not being enabled")
+)
+
+const (
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 5
+)
+
+type TwoFactorSetupJson struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesJson struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type EnableTwoFactorPayload struct {
+	Password string `json:"password" validate:"required,max=64"`
+}
+
+// EnableTwoFactor godoc
+//
+//	@Summary		Start enabling two factor authentication
+//	@Description	Generate a TOTP secret for the authenticated user, returned along with its otpauth URI. The secret is enabled once it is confirmed with a code, starting over replaces a pending secret
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		EnableTwoFactorPayload	true	"Enable payload"
+//	@Success		201		{object}	EnvelopeJson{data=TwoFactorSetupJson}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/two-factor [post]
+func (service *TwoFactorService) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var payload EnableTwoFactorPayload
+	var twoFactor *entity.TwoFactor
+	var secret string
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = findAccount(r, service.Storage); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = user.Password.Compare([]byte(payload.Password)); err != nil {
+		utils.ForbiddenResponse(w, r, errors.New("password is incorrect"))
+		return
+	}
+
+	if secret, err = totp.GenerateSecret(); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	twoFactor = &entity.TwoFactor{UserID: user.ID, Secret: secret}
+	if err = service.Storage.TwoFactors.Create(r.Context(), twoFactor); err != nil {
+		if errors.Is(err, storage.ErrorDuplicate) {
+			utils.ConflictResponse(w, r, errorTwoFactorEnabled)
+			return
+		}
+
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusCreated, TwoFactorSetupJson{
+		Secret: secret,
+		URI:    totp.URI(service.Issuer, user.Email, secret),
+	})
+}
+
+type ConfirmTwoFactorPayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// ConfirmTwoFactor godoc
+//
+//	@Summary		Enable two factor authentication
+//	@Description	Enable the pending TOTP secret of the authenticated user with a code, and return the recovery codes. They are only shown once. Wrong codes are throttled like failed logins
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ConfirmTwoFactorPayload	true	"Confirm payload"
+//	@Success		200		{object}	EnvelopeJson{data=RecoveryCodesJson}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		409		{object}	ErrorEnvelopeJson
+//	@Failure		429		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/two-factor/confirm [post]
+func (service *TwoFactorService) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.FindUserFromContext(r)
+	var payload ConfirmTwoFactorPayload
+	var twoFactor *entity.TwoFactor
+	var codes []string
+	var hashes []string
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if twoFactor, err = service.Storage.TwoFactors.Find(r.Context(), user.ID); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			utils.NotFoundResponse(w, r, errorTwoFactorPending)
+			return
+		}
+
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if twoFactor.Enabled() {
+		utils.ConflictResponse(w, r, errorTwoFactorEnabled)
+		return
+	}
+
+	if !service.allowed(w, r, user) {
+		return
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, payload.Code, time.Now())
+	if !ok {
+		if err = service.fail(r, user); err != nil {
+			utils.InternalServerErrorResponse(w, r, err)
+			return
+		}
+
+		utils.UnauthorizedResponse(w, r, errorTwoFactorInvalid)
+		return
+	}
+
+	if codes, hashes, err = generateRecoveryCodes(); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.TwoFactors.Enable(r.Context(), user.ID, step); err != nil {
+			return err
+		}
+
+		return store.RecoveryCodes.Replace(r.Context(), user.ID, hashes)
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, RecoveryCodesJson{RecoveryCodes: codes})
+}
+
+type DisableTwoFactorPayload struct {
+	Password     string `json:"password" validate:"required,max=64"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"omitempty,max=64"`
+}
+
+// DisableTwoFactor godoc
+//
+//	@Summary		Disable two factor authentication
+//	@Description	Disable two factor authentication of the authenticated user with their password and a code or recovery code. Users whose role requires it can not disable it. Wrong codes are throttled like failed logins
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	DisableTwoFactorPayload	true	"Disable payload"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		429		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/two-factor [delete]
+func (service *TwoFactorService) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var payload DisableTwoFactorPayload
+	var settings *entity.Settings
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if user, err = findAccount(r, service.Storage); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if settings, err = service.Storage.Settings.Find(r.Context()); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if policy.NewTwoFactor(settings).Required(&user.Role) {
+		utils.ForbiddenResponse(w, r, utils.ErrorTwoFactorRequired)
+		return
+	}
+
+	if !service.allowed(w, r, user) {
+		return
+	}
+
+	if err = user.Password.Compare([]byte(payload.Password)); err != nil {
+		utils.ForbiddenResponse(w, r, errors.New("password is incorrect"))
+		return
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := verifyTwoFactor(r, store, user.ID, payload.Code, payload.RecoveryCode); err != nil {
+			return err
+		}
+
+		if err := store.RecoveryCodes.DeleteAllByUserID(r.Context(), user.ID); err != nil {
+			return err
+		}
+
+		return store.TwoFactors.Delete(r.Context(), user.ID)
+	}); err != nil {
+		service.switchErrorResponse(w, r, user, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type RegenerateRecoveryCodesPayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// RegenerateRecoveryCodes godoc
+//
+//	@Summary		Regenerate recovery codes
+//	@Description	Replace the recovery codes of the authenticated user, confirmed with a code. They are only shown once. Wrong codes are throttled like failed logins
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RegenerateRecoveryCodesPayload	true	"Regenerate payload"
+//	@Success		200		{object}	EnvelopeJson{data=RecoveryCodesJson}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		401		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		429		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/me/two-factor/recovery-codes [post]
+func (service *TwoFactorService) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.FindUserFromContext(r)
+	var payload RegenerateRecoveryCodesPayload
+	var codes []string
+	var hashes []string
+	var err error
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if !service.allowed(w, r, user) {
+		return
+	}
+
+	if codes, hashes, err = generateRecoveryCodes(); err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := verifyTwoFactor(r, store, user.ID, payload.Code, ""); err != nil {
+			return err
+		}
+
+		return store.RecoveryCodes.Replace(r.Context(), user.ID, hashes)
+	}); err != nil {
+		service.switchErrorResponse(w, r, user, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, RecoveryCodesJson{RecoveryCodes: codes})
+}
+
+// Checks the TOTP code of the user, or the recovery code when no code is
+// given. Both are used up when they are valid.
+func verifyTwoFactor(r *http.Request, store *storage.Storage, id int64, code string, recoveryCode string) error {
+	var twoFactor *entity.TwoFactor
+	var err error
+
+	if twoFactor, err = store.TwoFactors.Find(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			return errorTwoFactorDisabled
+		}
+
+		return err
+	}
+
+	if !twoFactor.Enabled() {
+		return errorTwoFactorDisabled
+	}
+
+	if code == "" {
+		err = store.RecoveryCodes.Use(r.Context(), id, authentication.HashToken(normalizeRecoveryCode(recoveryCode)))
+	} else if step, ok := totp.Validate(twoFactor.Secret, code, time.Now()); ok {
+		err = store.TwoFactors.Use(r.Context(), id, step)
+	} else {
+		err = storage.ErrorNotFound
+	}
+
+	if errors.Is(err, storage.ErrorNotFound) {
+		return errorTwoFactorInvalid
+	}
+
+	return err
+}
+
+// Writes a throttled response when the user is locked out, like logins are.
+func (service *TwoFactorService) allowed(w http.ResponseWriter, r *http.Request, user *entity.User) bool {
+	wait, err := lockedOut(r, service.Storage, accountLoginKey(user.Email), ipLoginKey(r))
+	if err != nil {
+		utils.InternalServerErrorResponse(w, r, err)
+		return false
+	}
+
+	if wait > 0 {
+		utils.TooManyRequestsResponse(w, r, errorLoginThrottled, wait)
+		return false
+	}
+
+	return true
+}
+
+func (service *TwoFactorService) fail(r *http.Request, user *entity.User) error {
+	return failLogin(r, service.Storage, accountLoginKey(user.Email), service.AccountThrottle, &user.ID)
+}
+
+// Counts wrong codes before writing the error response.
+func (service *TwoFactorService) switchErrorResponse(w http.ResponseWriter, r *http.Request, user *entity.User, err error) {
+	if errors.Is(err, errorTwoFactorInvalid) {
+		if err := service.fail(r, user); err != nil {
+			utils.InternalServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	switchTwoFactorErrorResponse(w, r, err)
+}
+
+func switchTwoFactorErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errorTwoFactorInvalid):
+		utils.UnauthorizedResponse(w, r, err)
+	case errors.Is(err, errorTwoFactorDisabled):
+		utils.NotFoundResponse(w, r, err)
+	default:
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+	}
+}
+
+// Returns the recovery codes to hand out along with the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		bytes := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(bytes))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = authentication.HashToken(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// Recovery codes are accepted in any case and with or without the dash.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+	"web_blog/cmd/main/middlewares"
 	"web_blog/cmd/main/utils"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type UserService struct {
@@ -20,18 +26,24 @@ type UserService struct {
 //	@Produce		json
 //	@Param			limit	query		int	false	"Limit"
 //	@Param			offset	query		int	false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort fields, comma separated, prefixed with - for descending"
+//	@Param			since	query		string	false	"Created at or after, RFC 3339 or date"
+//	@Param			until	query		string	false	"Created before, RFC 3339 or date"
+//	@Param			verified	query		bool	false	"Verified"
 //	@Success		200		{object}	EnvelopeJson{data=[]entity.User}
 //	@Failure		500		{object}	ErrorEnvelopeJson
 //	@Security		ApiKeyAuth
 //	@Router			/users [get]
 func (service *UserService) FindAllUsers(w http.ResponseWriter, r *http.Request) {
 	var filter storage.FilterQuery
-	var users []*entity.User
+	var page *storage.Page[entity.User]
 	var err error
 
 	filter = storage.FilterQuery{
-		Limit:  20,
-		Offset: 0,
+		Limit:   20,
+		Offset:  0,
+		Listing: storage.UserListing,
 	}
 
 	if err = filter.Parse(r); err != nil {
@@ -44,9 +56,337 @@ func (service *UserService) FindAllUsers(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if users, err = service.Storage.Users.FindAll(r.Context(), nil, filter); err != nil {
+	if page, err = service.Storage.Users.FindAll(r.Context(), filter); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonPage(w, http.StatusOK, page)
+}
+
+// FindUser godoc
+//
+//	@Summary		Get a user
+//	@Description	Retrieve a specific user by their ID, with their ban if they have one
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	EnvelopeJson{data=entity.User}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id} [get]
+func (service *UserService) FindUser(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var err error
+
+	if user, err = service.findUser(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+type UpdateUserRolePayload struct {
+	Role string `json:"role" validate:"required,max=255"`
+}
+
+// UpdateUserRole godoc
+//
+//	@Summary		Change the role of a user
+//	@Description	Assign a role to a user by its name. Only roles below the level of the caller's own role can be assigned
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"User ID"
+//	@Param			payload	body		UpdateUserRolePayload	true	"Role payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.User}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/role [patch]
+func (service *UserService) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateUserRolePayload
+	var user *entity.User
+	var role *entity.Role
+	var err error
+
+	if user, err = service.findManagedUser(r); err != nil {
+		service.switchUserErrorResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if role, err = service.Storage.Roles.FindByName(r.Context(), payload.Role); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			utils.BadRequestResponse(w, r, errors.New("unknown role"))
+			return
+		}
+
+		utils.InternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if role.Level >= middlewares.FindUserFromContext(r).Role.Level {
+		utils.ForbiddenResponse(w, r, errorAssignRole)
+		return
+	}
+
+	user.RoleID = role.ID
+	if err = service.Storage.Users.Update(r.Context(), user); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+type BanUserPayload struct {
+	Reason    string     `json:"reason" validate:"required,max=1024"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// BanUser godoc
+//
+//	@Summary		Ban a user
+//	@Description	Ban a user until the ban expires, or permanently without an expiry. The user is logged out of all sessions
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"User ID"
+//	@Param			payload	body		BanUserPayload	true	"Ban payload"
+//	@Success		200		{object}	EnvelopeJson{data=entity.User}
+//	@Failure		400		{object}	ErrorEnvelopeJson
+//	@Failure		403		{object}	ErrorEnvelopeJson
+//	@Failure		404		{object}	ErrorEnvelopeJson
+//	@Failure		500		{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/ban [post]
+func (service *UserService) BanUser(w http.ResponseWriter, r *http.Request) {
+	admin := middlewares.FindUserFromContext(r)
+	var payload BanUserPayload
+	var user *entity.User
+	var err error
+
+	if user, err = service.findManagedUser(r); err != nil {
+		service.switchUserErrorResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ReadJson(w, r, &payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = utils.ValidateStruct(payload); err != nil {
+		utils.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		utils.BadRequestResponse(w, r, errors.New("expires_at must be in the future"))
+		return
+	}
+
+	user.Ban = &entity.Ban{
+		UserID:    user.ID,
+		BannedBy:  &admin.ID,
+		Reason:    payload.Reason,
+		ExpiresAt: payload.ExpiresAt,
+	}
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Bans.Create(r.Context(), user.Ban); err != nil {
+			return err
+		}
+
+		return store.Sessions.DeleteAllByUserID(r.Context(), user.ID)
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+// UnbanUser godoc
+//
+//	@Summary		Unban a user
+//	@Description	Lift the ban of a user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	EnvelopeJson{data=entity.User}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/unban [post]
+func (service *UserService) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var err error
+
+	if user, err = service.findUser(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err = service.Storage.Bans.Delete(r.Context(), user.ID); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	user.Ban = nil
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+// UnlockUser godoc
+//
+//	@Summary		Unlock a user
+//	@Description	Clear the failed logins of a user, lifting the lockout of their account. Failed logins of addresses are kept
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	EnvelopeJson{data=entity.User}
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/unlock [post]
+func (service *UserService) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var err error
+
+	if user, err = service.findUser(r); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	actor := middlewares.FindUserFromContext(r)
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.LoginFailures.Delete(r.Context(), accountLoginKey(user.Email)); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+			return err
+		}
+
+		return store.AuditLog.Create(r.Context(), &entity.AuditEntry{
+			Action:  entity.AuditLoginUnlock,
+			ActorID: &actor.ID,
+			UserID:  &user.ID,
+			IP:      requestIP(r),
+		})
+	}); err != nil {
+		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJsonData(w, http.StatusOK, user)
+}
+
+// DeleteUser godoc
+//
+//	@Summary		Delete a user
+//	@Description	Move a user with their posts and comments to the trash and sign them out. They can be restored until purged
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"User ID"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	ErrorEnvelopeJson
+//	@Failure		403	{object}	ErrorEnvelopeJson
+//	@Failure		404	{object}	ErrorEnvelopeJson
+//	@Failure		500	{object}	ErrorEnvelopeJson
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id} [delete]
+func (service *UserService) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	var user *entity.User
+	var err error
+
+	if user, err = service.findManagedUser(r); err != nil {
+		service.switchUserErrorResponse(w, r, err)
+		return
+	}
+
+	admin := middlewares.FindUserFromContext(r)
+
+	if err = service.Storage.WithinTx(r.Context(), func(store *storage.Storage) error {
+		if err := store.Users.SoftDelete(r.Context(), user.ID, admin.ID); err != nil {
+			return err
+		}
+
+		return store.Sessions.DeleteAllByUserID(r.Context(), user.ID)
+	}); err != nil {
 		utils.SwitchInternalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errorManageSelf = errors.New("users can not change their own role, ban or delete themselves")
+var errorAssignRole = errors.New("only roles below your own level can be assigned")
+
+// Finds the user of the id path parameter with their ban.
+func (service *UserService) findUser(r *http.Request) (*entity.User, error) {
+	var user *entity.User
+	var id int
+	var err error
+
+	if id, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
+		return nil, storage.ErrorNotFound
+	}
+
+	if user, err = service.Storage.Users.Find(r.Context(), int64(id)); err != nil {
+		return nil, err
+	}
+
+	if user.Ban, err = service.Storage.Bans.Find(r.Context(), user.ID); err != nil {
+		if !errors.Is(err, storage.ErrorNotFound) {
+			return nil, err
+		}
+
+		user.Ban = nil
+	}
+
+	return user, nil
+}
+
+// Like findUser, but users can not manage themselves, so admins can not lock
+// themselves out.
+func (service *UserService) findManagedUser(r *http.Request) (*entity.User, error) {
+	user, err := service.findUser(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ID == middlewares.FindUserFromContext(r).ID {
+		return nil, errorManageSelf
+	}
+
+	return user, nil
+}
+
+func (service *UserService) switchUserErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errorManageSelf) {
+		utils.BadRequestResponse(w, r, err)
+		return
 	}
 
-	utils.WriteJsonData(w, http.StatusOK, users)
+	utils.SwitchInternalServerErrorResponse(w, r, err)
 }
@@ -2,10 +2,13 @@ package utils
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 	"web_blog/internal/data/storage"
+	"web_blog/internal/policy"
 
-	"github.com/jackc/pgx"
 	"go.uber.org/zap"
 )
 
@@ -15,9 +18,36 @@ type ErrorService struct {
 
 var logger = zap.Must(zap.NewProduction())
 
+// Lets clients prompt users to verify their email.
+var ErrorUnverified = &CodedError{Code: "email_unverified", Message: "email is not verified"}
+
+// Lets clients prompt users to enable two factor authentication.
+var ErrorTwoFactorRequired = &CodedError{
+	Code:    "two_factor_required",
+	Message: "two factor authentication is required for the role of the user",
+}
+
+// Errors with a code that clients can match on instead of the message. The
+// code is written to the error envelope.
+type CodedError struct {
+	Code    string
+	Message string
+}
+
+func (err *CodedError) Error() string {
+	return err.Message
+}
+
 func writeResponse(w http.ResponseWriter, r *http.Request, status int, msg string, err error) {
-	logger.Warn(msg, zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
-	if err := WriteJsonError(w, r, status, err.Error()); err != nil {
+	var coded *CodedError
+	var code string
+
+	if errors.As(err, &coded) {
+		code = coded.Code
+	}
+
+	logger.Warn(msg, zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.String("code", code), zap.Error(err))
+	if err := WriteJsonError(w, r, status, err.Error(), code); err != nil {
 		logger.Error(err.Error())
 	}
 }
@@ -42,13 +72,33 @@ func UnauthorizedResponse(w http.ResponseWriter, r *http.Request, err error) {
 	writeResponse(w, r, http.StatusUnauthorized, "unauthorized error", err)
 }
 
+func ConflictResponse(w http.ResponseWriter, r *http.Request, err error) {
+	writeResponse(w, r, http.StatusConflict, "conflict error", err)
+}
+
+func GoneResponse(w http.ResponseWriter, r *http.Request, err error) {
+	writeResponse(w, r, http.StatusGone, "gone error", err)
+}
+
+// Sets Retry-After when retry is positive.
+func TooManyRequestsResponse(w http.ResponseWriter, r *http.Request, err error, retry time.Duration) {
+	if retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	}
+
+	writeResponse(w, r, http.StatusTooManyRequests, "too many requests error", err)
+}
+
 func SwitchInternalServerErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case errors.Is(err, storage.ErrorNotFound):
 		NotFoundResponse(w, r, err)
 		return
-	case errors.Is(err, pgx.ErrNoRows):
-		NotFoundResponse(w, r, storage.ErrorNotFound)
+	case errors.Is(err, storage.ErrorDuplicate):
+		ConflictResponse(w, r, err)
+		return
+	case errors.Is(err, policy.ErrorForbidden):
+		ForbiddenResponse(w, r, err)
 		return
 	default:
 		InternalServerErrorResponse(w, r, err)
@@ -4,24 +4,71 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New(validator.WithRequiredStructEnabled())
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterStructValidation(validateFilterQuery, storage.FilterQuery{})
+	validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return entity.Slugify(fl.Field().String()) != ""
+	})
+
+	return validate
+}
+
+// Checks sort and filter fields against the listing of the filtered entity,
+// and that the date range is not empty.
+func validateFilterQuery(sl validator.StructLevel) {
+	filter := sl.Current().Interface().(storage.FilterQuery)
+
+	for _, field := range filter.Sort {
+		if column, _ := storage.SortField(field); !slices.Contains(filter.Listing.Sortable, column) {
+			sl.ReportError(filter.Sort, "Sort", "sort", "sortable", field)
+		}
+	}
+
+	for _, name := range filter.Filters() {
+		if !slices.Contains(filter.Listing.Filterable, name) {
+			sl.ReportError(name, name, name, "filterable", "")
+		}
+	}
+
+	if filter.Cursor != nil && !filter.Listing.Seekable {
+		sl.ReportError(filter.Cursor, "Cursor", "cursor", "seekable", "")
+	}
+
+	if filter.Since != nil && filter.Until != nil && !filter.Until.After(*filter.Since) {
+		sl.ReportError(filter.Until, "Until", "until", "gtfield", "Since")
+	}
+}
 
 type EnvelopeJson struct {
 	Data any `json:"data"`
 }
 
+type PageEnvelopeJson struct {
+	Data       any    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
 type ErrorEnvelopeJson struct {
 	Error struct {
 		Method    string `json:"method"`
 		Path      string `json:"path"`
 		Timestamp int64  `json:"timestamp"`
 		Message   string `json:"message"`
+		Code      string `json:"code,omitempty"`
 	} `json:"error"`
 }
 
@@ -31,6 +78,34 @@ func getTagValidationError(tag string) string {
 		return "required field is empty"
 	case "max":
 		return "text is exceeding length"
+	case "min":
+		return "text is too short"
+	case "email":
+		return "value must be an email address"
+	case "http_url":
+		return "value must be an http or https url"
+	case "nefield":
+		return "value must differ from another field"
+	case "gte":
+		return "value is too small"
+	case "lte":
+		return "value is too large"
+	case "excluded_with":
+		return "field can not be combined with another field"
+	case "gt":
+		return "value is too small"
+	case "gtfield":
+		return "value must be after another field"
+	case "oneof":
+		return "value is not one of the allowed values"
+	case "seekable":
+		return "list can not be paged by cursor"
+	case "slug":
+		return "value must contain letters or digits"
+	case "sortable":
+		return "field can not be sorted by"
+	case "filterable":
+		return "field can not be filtered by"
 	default:
 		return ""
 	}
@@ -84,20 +159,24 @@ func WriteJsonData(w http.ResponseWriter, status int, payload any) error {
 	return WriteJson(w, status, response)
 }
 
-func WriteJsonError(w http.ResponseWriter, r *http.Request, status int, message string) error {
-	response := ErrorEnvelopeJson{
-		Error: struct {
-			Method    string `json:"method"`
-			Path      string `json:"path"`
-			Timestamp int64  `json:"timestamp"`
-			Message   string `json:"message"`
-		}{
-			Method:    r.Method,
-			Path:      r.URL.Path,
-			Timestamp: time.Now().Unix(),
-			Message:   message,
-		},
+func WriteJsonPage[T any](w http.ResponseWriter, status int, page *storage.Page[T]) error {
+	response := PageEnvelopeJson{
+		Data:       page.Items,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+		HasMore:    page.HasMore,
 	}
 
 	return WriteJson(w, status, response)
 }
+
+func WriteJsonError(w http.ResponseWriter, r *http.Request, status int, message string, code string) error {
+	response := ErrorEnvelopeJson{}
+	response.Error.Method = r.Method
+	response.Error.Path = r.URL.Path
+	response.Error.Timestamp = time.Now().Unix()
+	response.Error.Message = message
+	response.Error.Code = code
+
+	return WriteJson(w, status, response)
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx ON public.users (created_at, id);
+CREATE INDEX IF NOT EXISTS posts_created_at_id_idx ON public.posts (created_at, id);
+CREATE INDEX IF NOT EXISTS posts_user_id_created_at_id_idx ON public.posts (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS comments_created_at_id_idx ON public.comments (created_at, id);
+CREATE INDEX IF NOT EXISTS comments_post_id_created_at_id_idx ON public.comments (post_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.comments_post_id_created_at_id_idx;
+DROP INDEX IF EXISTS public.comments_created_at_id_idx;
+DROP INDEX IF EXISTS public.posts_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS public.posts_created_at_id_idx;
+DROP INDEX IF EXISTS public.users_created_at_id_idx;
+-- +goose StatementEnd
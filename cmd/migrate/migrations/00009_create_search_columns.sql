@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.posts ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', content), 'B')
+) STORED;
+ALTER TABLE public.comments ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    to_tsvector('english', content)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS posts_search_idx ON public.posts USING GIN (search);
+CREATE INDEX IF NOT EXISTS comments_search_idx ON public.comments USING GIN (search);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.comments_search_idx;
+DROP INDEX IF EXISTS public.posts_search_idx;
+
+ALTER TABLE public.comments DROP COLUMN IF EXISTS search;
+ALTER TABLE public.posts DROP COLUMN IF EXISTS search;
+-- +goose StatementEnd
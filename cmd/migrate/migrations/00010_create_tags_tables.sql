@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.tags (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    slug text UNIQUE NOT NULL,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS public.post_tags (
+    post_id bigint NOT NULL,
+    tag_id bigint NOT NULL,
+
+    PRIMARY KEY (post_id, tag_id),
+    CONSTRAINT post_fk FOREIGN KEY (post_id) REFERENCES public.posts (id) ON DELETE CASCADE,
+    CONSTRAINT tag_fk FOREIGN KEY (tag_id) REFERENCES public.tags (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_tags_tag_id_idx ON public.post_tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.post_tags;
+DROP TABLE IF EXISTS public.tags;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.posts
+    ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published'
+        CONSTRAINT status_check CHECK (status IN ('draft', 'scheduled', 'published', 'archived')),
+    ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone;
+
+-- Existing posts were live from the moment they were created.
+UPDATE public.posts SET publish_at = created_at;
+
+CREATE INDEX IF NOT EXISTS posts_status_publish_at_idx ON public.posts (status, publish_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.posts_status_publish_at_idx;
+
+ALTER TABLE public.posts
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.post_revisions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    editor_id bigint NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT post_fk FOREIGN KEY (post_id) REFERENCES public.posts (id) ON DELETE CASCADE,
+    CONSTRAINT editor_fk FOREIGN KEY (editor_id) REFERENCES public.users (id)
+);
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id_id_idx ON public.post_revisions (post_id, id);
+
+-- Existing posts start their history with their current text.
+INSERT INTO public.post_revisions (post_id, editor_id, title, content, created_at)
+SELECT id, user_id, title, content, updated_at FROM public.posts ORDER BY id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.post_revisions;
+-- +goose StatementEnd
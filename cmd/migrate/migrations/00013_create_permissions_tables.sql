@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.permissions (
+    id bigserial PRIMARY KEY,
+    name varchar(255) NOT NULL UNIQUE,
+    description text
+);
+
+CREATE TABLE IF NOT EXISTS public.role_permissions (
+    role_id bigint NOT NULL,
+    permission_id bigint NOT NULL,
+
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT role_fk FOREIGN KEY (role_id) REFERENCES public.roles (id) ON DELETE CASCADE,
+    CONSTRAINT permission_fk FOREIGN KEY (permission_id) REFERENCES public.permissions (id) ON DELETE CASCADE
+);
+
+INSERT INTO public.permissions (name, description)
+VALUES
+    ('posts:create', 'create posts'),
+    ('posts:update:any', 'update posts of other users'),
+    ('posts:delete:any', 'delete posts of other users'),
+    ('comments:create', 'create comments'),
+    ('comments:read:any', 'list the comments of all users'),
+    ('comments:delete:any', 'delete comments of other users'),
+    ('tags:manage', 'rename and merge tags'),
+    ('users:read', 'list users'),
+    ('roles:manage', 'create roles and assign permissions');
+
+INSERT INTO public.role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'user'
+    AND permissions.name IN ('posts:create', 'comments:create');
+
+INSERT INTO public.role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'moderator'
+    AND permissions.name NOT IN ('users:read', 'roles:manage');
+
+INSERT INTO public.role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'admin';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.role_permissions;
+DROP TABLE IF EXISTS public.permissions;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.user_bans (
+    user_id bigint PRIMARY KEY,
+    banned_by bigint,
+    reason text NOT NULL,
+    expires_at timestamp(0) with time zone,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE,
+    CONSTRAINT banned_by_fk FOREIGN KEY (banned_by) REFERENCES public.users (id) ON DELETE SET NULL
+);
+
+INSERT INTO public.permissions (name, description)
+VALUES ('users:manage', 'change roles of, ban and delete users');
+
+INSERT INTO public.role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name = 'users:manage';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM public.permissions WHERE name = 'users:manage';
+DROP TABLE IF EXISTS public.user_bans;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Deleting a user deletes their sessions, verifications, posts and comments,
+-- deleting a post deletes its comments. Revisions made by a deleted user
+-- are kept without an editor.
+ALTER TABLE public.sessions
+    DROP CONSTRAINT user_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.verifications
+    DROP CONSTRAINT user_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.posts
+    DROP CONSTRAINT user_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.comments
+    DROP CONSTRAINT user_fk,
+    DROP CONSTRAINT post_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT post_fk FOREIGN KEY (post_id) REFERENCES public.posts (id) ON DELETE CASCADE;
+
+ALTER TABLE public.post_revisions
+    ALTER COLUMN editor_id DROP NOT NULL,
+    DROP CONSTRAINT editor_fk,
+    ADD CONSTRAINT editor_fk FOREIGN KEY (editor_id) REFERENCES public.users (id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.post_revisions
+    DROP CONSTRAINT editor_fk,
+    ADD CONSTRAINT editor_fk FOREIGN KEY (editor_id) REFERENCES public.users (id);
+
+ALTER TABLE public.comments
+    DROP CONSTRAINT user_fk,
+    DROP CONSTRAINT post_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id),
+    ADD CONSTRAINT post_fk FOREIGN KEY (post_id) REFERENCES public.posts (id);
+
+ALTER TABLE public.posts
+    DROP CONSTRAINT user_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id);
+
+ALTER TABLE public.verifications
+    DROP CONSTRAINT user_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id);
+
+ALTER TABLE public.sessions
+    DROP CONSTRAINT user_fk,
+    ADD CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id);
+-- +goose StatementEnd
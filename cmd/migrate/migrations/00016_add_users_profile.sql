@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS bio text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS avatar_url text NOT NULL DEFAULT '';
+
+-- Verifications with an email change the user's email once verified.
+ALTER TABLE public.verifications
+    ADD COLUMN IF NOT EXISTS email citext;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.verifications
+    DROP COLUMN IF EXISTS email;
+
+ALTER TABLE public.users
+    DROP COLUMN IF EXISTS avatar_url,
+    DROP COLUMN IF EXISTS bio;
+-- +goose StatementEnd
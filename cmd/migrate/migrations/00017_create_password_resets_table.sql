@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.password_resets (
+    id text PRIMARY KEY,
+    user_id bigint NOT NULL,
+    expired_at timestamp(0) with time zone NOT NULL DEFAULT (now() + interval '1 hour'),
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON public.password_resets (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.password_resets;
+-- +goose StatementEnd
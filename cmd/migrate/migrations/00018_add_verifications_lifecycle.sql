@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.verifications
+    ADD COLUMN IF NOT EXISTS created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS used_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS verifications_user_id_created_at_idx ON public.verifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS verifications_expired_at_idx ON public.verifications (expired_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.verifications_expired_at_idx;
+DROP INDEX IF EXISTS public.verifications_user_id_created_at_idx;
+ALTER TABLE public.verifications DROP COLUMN IF EXISTS used_at, DROP COLUMN IF EXISTS created_at;
+-- +goose StatementEnd
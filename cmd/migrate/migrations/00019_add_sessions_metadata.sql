@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.sessions
+    ADD COLUMN IF NOT EXISTS ip text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS user_agent text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS last_seen_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS created_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON public.sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_expired_at_idx ON public.sessions (expired_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.sessions_expired_at_idx;
+DROP INDEX IF EXISTS public.sessions_user_id_idx;
+ALTER TABLE public.sessions
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip;
+-- +goose StatementEnd
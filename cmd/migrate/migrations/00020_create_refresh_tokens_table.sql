@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.refresh_tokens (
+    id text PRIMARY KEY,
+    session_id text NOT NULL,
+    user_id bigint NOT NULL,
+    expired_at timestamp(0) with time zone NOT NULL,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT session_fk FOREIGN KEY (session_id) REFERENCES public.sessions (id) ON DELETE CASCADE,
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON public.refresh_tokens (session_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.refresh_tokens;
+-- +goose StatementEnd
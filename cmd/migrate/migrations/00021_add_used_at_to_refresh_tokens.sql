@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.refresh_tokens ADD COLUMN IF NOT EXISTS used_at timestamp(0) with time zone;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.refresh_tokens DROP COLUMN IF EXISTS used_at;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.two_factors (
+    user_id bigint PRIMARY KEY,
+    secret text NOT NULL,
+    last_step bigint NOT NULL DEFAULT 0,
+    enabled_at timestamp(0) with time zone,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS public.recovery_codes (
+    id text PRIMARY KEY,
+    user_id bigint NOT NULL,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON public.recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS public.login_challenges (
+    id text PRIMARY KEY,
+    user_id bigint NOT NULL,
+    expired_at timestamp(0) with time zone NOT NULL,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS login_challenges_expired_at_idx ON public.login_challenges (expired_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.login_challenges;
+DROP TABLE IF EXISTS public.recovery_codes;
+DROP TABLE IF EXISTS public.two_factors;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.login_failures (
+    id text PRIMARY KEY,
+    failures int NOT NULL DEFAULT 1,
+    locked_until timestamp(0) with time zone,
+    last_failed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS login_failures_last_failed_at_idx ON public.login_failures (last_failed_at);
+
+CREATE TABLE IF NOT EXISTS public.audit_log (
+    id bigserial PRIMARY KEY,
+    action varchar(64) NOT NULL,
+    actor_id bigint,
+    user_id bigint,
+    ip text NOT NULL DEFAULT '',
+    details text NOT NULL DEFAULT '',
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT actor_fk FOREIGN KEY (actor_id) REFERENCES public.users (id) ON DELETE SET NULL,
+    CONSTRAINT user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON public.audit_log (user_id);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON public.audit_log (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.audit_log;
+DROP TABLE IF EXISTS public.login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Deleting a comment deletes its replies.
+ALTER TABLE public.comments
+    ADD COLUMN IF NOT EXISTS parent_id bigint,
+    ADD COLUMN IF NOT EXISTS depth int NOT NULL DEFAULT 0,
+    ADD CONSTRAINT parent_fk FOREIGN KEY (parent_id) REFERENCES public.comments (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS comments_parent_id_created_at_id_idx ON public.comments (parent_id, created_at, id);
+CREATE INDEX IF NOT EXISTS comments_post_id_roots_idx ON public.comments (post_id, created_at, id) WHERE parent_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.comments_post_id_roots_idx;
+DROP INDEX IF EXISTS public.comments_parent_id_created_at_id_idx;
+
+DELETE FROM public.comments WHERE parent_id IS NOT NULL;
+
+ALTER TABLE public.comments
+    DROP CONSTRAINT IF EXISTS parent_fk,
+    DROP COLUMN IF EXISTS depth,
+    DROP COLUMN IF EXISTS parent_id;
+-- +goose StatementEnd
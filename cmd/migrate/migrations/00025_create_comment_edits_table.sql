@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.comments ADD COLUMN IF NOT EXISTS edited_at timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS public.comment_edits (
+    id bigserial PRIMARY KEY,
+    comment_id bigint NOT NULL,
+    editor_id bigint,
+    content text NOT NULL,
+
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT comment_fk FOREIGN KEY (comment_id) REFERENCES public.comments (id) ON DELETE CASCADE,
+    CONSTRAINT editor_fk FOREIGN KEY (editor_id) REFERENCES public.users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS comment_edits_comment_id_id_idx ON public.comment_edits (comment_id, id);
+
+INSERT INTO public.permissions (name, description)
+VALUES ('comments:update:any', 'update comments of other users');
+
+INSERT INTO public.role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name IN ('moderator', 'admin') AND permissions.name = 'comments:update:any';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM public.permissions WHERE name = 'comments:update:any';
+
+DROP TABLE IF EXISTS public.comment_edits;
+
+ALTER TABLE public.comments DROP COLUMN IF EXISTS edited_at;
+-- +goose StatementEnd
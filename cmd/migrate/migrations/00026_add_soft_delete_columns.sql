@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Soft deleted rows keep who deleted them. Rows deleted along with another
+-- row share its deleted_at, so they can be restored with it. Unlike the other
+-- timestamps deleted_at keeps microseconds, so rows deleted separately within
+-- the same second are not restored together.
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone,
+    ADD COLUMN IF NOT EXISTS deleted_by bigint,
+    ADD CONSTRAINT deleted_by_fk FOREIGN KEY (deleted_by) REFERENCES public.users (id) ON DELETE SET NULL;
+
+ALTER TABLE public.posts
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone,
+    ADD COLUMN IF NOT EXISTS deleted_by bigint,
+    ADD CONSTRAINT deleted_by_fk FOREIGN KEY (deleted_by) REFERENCES public.users (id) ON DELETE SET NULL;
+
+ALTER TABLE public.comments
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone,
+    ADD COLUMN IF NOT EXISTS deleted_by bigint,
+    ADD CONSTRAINT deleted_by_fk FOREIGN KEY (deleted_by) REFERENCES public.users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON public.users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS posts_deleted_at_idx ON public.posts (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS comments_deleted_at_idx ON public.comments (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.comments_deleted_at_idx;
+DROP INDEX IF EXISTS public.posts_deleted_at_idx;
+DROP INDEX IF EXISTS public.users_deleted_at_idx;
+
+DELETE FROM public.users WHERE deleted_at IS NOT NULL;
+DELETE FROM public.posts WHERE deleted_at IS NOT NULL;
+DELETE FROM public.comments WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE public.comments
+    DROP CONSTRAINT IF EXISTS deleted_by_fk,
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE public.posts
+    DROP CONSTRAINT IF EXISTS deleted_by_fk,
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE public.users
+    DROP CONSTRAINT IF EXISTS deleted_by_fk,
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO public.permissions (name, description)
+VALUES ('content:moderate', 'approve and reject posts and comments');
+
+INSERT INTO public.role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name IN ('moderator', 'admin') AND permissions.name = 'content:moderate';
+
+-- The moderation queue lists unverified rows oldest first.
+CREATE INDEX IF NOT EXISTS posts_unverified_idx ON public.posts (created_at, id)
+    WHERE verified = false AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS comments_unverified_idx ON public.comments (created_at, id)
+    WHERE verified = false AND deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.comments_unverified_idx;
+DROP INDEX IF EXISTS public.posts_unverified_idx;
+
+DELETE FROM public.permissions WHERE name = 'content:moderate';
+-- +goose StatementEnd
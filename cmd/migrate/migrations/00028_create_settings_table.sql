@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A single row, the primary key can only be true.
+CREATE TABLE IF NOT EXISTS public.settings (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    two_factor_level int NOT NULL DEFAULT 0 CHECK (two_factor_level >= 0),
+
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO public.settings DEFAULT VALUES ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.settings;
+-- +goose StatementEnd
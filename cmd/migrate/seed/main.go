@@ -22,16 +22,10 @@ func main() {
 	}
 	defer database.Close(ctx)
 
-	store := &storage.Storage{
-		Database:      &database,
-		Users:         &pgxstorage.PgxUserRepository{Database: &database},
-		Posts:         &pgxstorage.PgxPostRepository{Database: &database},
-		Comments:      &pgxstorage.PgxCommentRepository{Database: &database},
-		Verifications: &pgxstorage.PgxVerificationRepository{Database: &database},
-	}
+	store := pgxstorage.NewStorage(&database)
 
 	logger.Info("seed has started")
-	if err := storage.Seed(store); err != nil {
+	if err := storage.Seed(&store); err != nil {
 		logger.Warn("seeding error occured", zap.Error(err))
 	}
 	logger.Info("seed has ended")
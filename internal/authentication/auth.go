@@ -1,8 +1,22 @@
 package authentication
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 const (
 	byteSize       int           = 20
 	expireDuration time.Duration = time.Hour * 24 * 7
+
+	// Sessions are written back at most this often when they are used.
+	touchInterval time.Duration = time.Minute
 )
+
+// Tokens are only stored as their hash, so a leaked table can not be used to
+// authenticate.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
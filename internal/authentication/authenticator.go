@@ -0,0 +1,58 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+var (
+	ErrorInvalidToken       = errors.New("token is invalid")
+	ErrorRefreshUnsupported = errors.New("tokens of this authenticator can not be refreshed")
+	ErrorTokenReused        = errors.New("refresh token was already used, its session has been revoked")
+)
+
+// Tokens handed out on login and refresh. The refresh token is only set by
+// authenticators that support refreshing.
+type Tokens struct {
+	Token        string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// Authenticators issue the tokens of a session and resolve them back to its
+// user. The session is stored either way, so it can be listed and revoked.
+type Authenticator interface {
+	// Stores the session of the user and returns its tokens. The session's id
+	// and expiry are set by the authenticator, the rest is left to the caller.
+	Create(ctx context.Context, store *storage.Storage, user *entity.User, session *entity.Session) (*Tokens, error)
+
+	// Returns the user of the token and the id of its session.
+	Validate(ctx context.Context, store *storage.Storage, token string) (*entity.User, string, error)
+
+	// Ends the session of the token given out on login. Which token that is
+	// depends on the authenticator.
+	Invalidate(ctx context.Context, store *storage.Storage, token string) error
+
+	// Returns new tokens for the refresh token, which may replace it.
+	Refresh(ctx context.Context, store *storage.Storage, token string) (*Tokens, error)
+}
+
+// Returns a random token, safe to hand out as a secret.
+func GenerateToken() (string, error) {
+	var encoding *base32.Encoding
+	var bytes []byte
+	var err error
+
+	encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+	bytes = make([]byte, byteSize)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(bytes), err
+}
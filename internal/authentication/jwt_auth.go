@@ -0,0 +1,254 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/jwt"
+)
+
+const (
+	accessDuration  time.Duration = time.Minute * 15
+	refreshDuration time.Duration = time.Hour * 24 * 30
+)
+
+// Claims of access tokens, enough to authorize a request without reading the
+// user.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+	RoleID    int64  `json:"rid"`
+	Username  string `json:"username"`
+	Verified  bool   `json:"verified"`
+	TwoFactor bool   `json:"tfa"`
+}
+
+// Authenticates with short lived signed access tokens, which are checked
+// without touching the database. Sessions are stored along with a refresh
+// token, which is exchanged for new access tokens. Revoking a session stops
+// it from being refreshed, its access tokens stay valid until they expire.
+type JWTAuthenticator struct {
+	Keys   *jwt.KeySet
+	Issuer string
+
+	// Lifetime of access tokens, 15 minutes when zero.
+	AccessDuration time.Duration
+
+	// Lifetime of sessions and their refresh tokens, 30 days when zero.
+	RefreshDuration time.Duration
+}
+
+func (authenticator *JWTAuthenticator) Create(
+	ctx context.Context,
+	store *storage.Storage,
+	user *entity.User,
+	session *entity.Session,
+) (*Tokens, error) {
+	var id string
+	var refresh string
+	var err error
+
+	if id, err = GenerateToken(); err != nil {
+		return nil, err
+	}
+
+	if refresh, err = GenerateToken(); err != nil {
+		return nil, err
+	}
+
+	session.ID = HashToken(id)
+	session.UserID = user.ID
+	session.ExpiredAt = time.Now().Add(authenticator.refreshDuration())
+
+	if err = store.WithinTx(ctx, func(store *storage.Storage) error {
+		if err := store.Sessions.Create(ctx, session); err != nil {
+			return err
+		}
+
+		return store.RefreshTokens.Create(ctx, &entity.RefreshToken{
+			ID:        HashToken(refresh),
+			SessionID: session.ID,
+			UserID:    user.ID,
+			ExpiredAt: session.ExpiredAt,
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return authenticator.sign(user, session.ID, refresh)
+}
+
+// Returns the user of the access token, built from its claims. Only the
+// fields needed for authorization are set.
+func (authenticator *JWTAuthenticator) Validate(
+	ctx context.Context,
+	store *storage.Storage,
+	token string,
+) (*entity.User, string, error) {
+	var claims AccessClaims
+	var id int64
+	var err error
+
+	if err = authenticator.Keys.Parse(token, &claims, time.Now()); err != nil {
+		return nil, "", err
+	}
+
+	if claims.Issuer != authenticator.Issuer || claims.SessionID == "" {
+		return nil, "", ErrorInvalidToken
+	}
+
+	if id, err = strconv.ParseInt(claims.Subject, 10, 64); err != nil {
+		return nil, "", ErrorInvalidToken
+	}
+
+	return &entity.User{
+		ID:        id,
+		RoleID:    claims.RoleID,
+		Username:  claims.Username,
+		Verified:  claims.Verified,
+		TwoFactor: claims.TwoFactor,
+	}, claims.SessionID, nil
+}
+
+// Ends the session of the refresh token, which also deletes the token.
+func (authenticator *JWTAuthenticator) Invalidate(
+	ctx context.Context,
+	store *storage.Storage,
+	token string,
+) error {
+	var refresh *entity.RefreshToken
+	var err error
+
+	if refresh, err = store.RefreshTokens.Find(ctx, HashToken(token)); err != nil {
+		return err
+	}
+
+	return store.Sessions.Delete(ctx, refresh.SessionID)
+}
+
+// Returns a new access token for the refresh token, along with the refresh
+// token replacing it. Tokens of revoked or expired sessions and of banned
+// users are refused. A token that was already replaced may have been stolen,
+// so sending it again revokes its session along with every token rotated
+// from it.
+func (authenticator *JWTAuthenticator) Refresh(
+	ctx context.Context,
+	store *storage.Storage,
+	token string,
+) (*Tokens, error) {
+	var refresh *entity.RefreshToken
+	var session *entity.Session
+	var user *entity.User
+	var next string
+	var err error
+
+	if refresh, err = store.RefreshTokens.Find(ctx, HashToken(token)); err != nil {
+		if errors.Is(err, storage.ErrorNotFound) {
+			return nil, ErrorInvalidToken
+		}
+
+		return nil, err
+	}
+
+	if next, err = GenerateToken(); err != nil {
+		return nil, err
+	}
+
+	if refresh.UsedAt == nil {
+		err = store.WithinTx(ctx, func(store *storage.Storage) error {
+			if session, user, err = store.Sessions.FindWithUser(ctx, refresh.SessionID); err != nil {
+				if errors.Is(err, storage.ErrorNotFound) {
+					return ErrorInvalidToken
+				}
+
+				return err
+			}
+
+			now := time.Now()
+			if refresh.Expired(now) || session.Expired(now) {
+				return ErrorSessionExpired
+			}
+
+			// Only one of concurrent refreshes with the same token can use it.
+			if err := store.RefreshTokens.Use(ctx, refresh.ID); err != nil {
+				if errors.Is(err, storage.ErrorNotFound) {
+					return ErrorTokenReused
+				}
+
+				return err
+			}
+
+			if err := store.RefreshTokens.Create(ctx, &entity.RefreshToken{
+				ID:        HashToken(next),
+				SessionID: session.ID,
+				UserID:    session.UserID,
+				ExpiredAt: refresh.ExpiredAt,
+			}); err != nil {
+				return err
+			}
+
+			session.LastSeenAt = now
+			return store.Sessions.Update(ctx, session)
+		})
+	} else {
+		err = ErrorTokenReused
+	}
+
+	// The session is revoked outside of the transaction, which is rolled back.
+	if errors.Is(err, ErrorTokenReused) {
+		if err := store.Sessions.Delete(ctx, refresh.SessionID); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+			return nil, err
+		}
+
+		return nil, ErrorTokenReused
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return authenticator.sign(user, session.ID, next)
+}
+
+func (authenticator *JWTAuthenticator) sign(user *entity.User, session string, refresh string) (*Tokens, error) {
+	now := time.Now()
+	expiresAt := now.Add(authenticator.accessDuration())
+
+	token, err := authenticator.Keys.Sign(AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    authenticator.Issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			ExpiresAt: expiresAt.Unix(),
+			IssuedAt:  now.Unix(),
+		},
+		SessionID: session,
+		RoleID:    user.RoleID,
+		Username:  user.Username,
+		Verified:  user.Verified,
+		TwoFactor: user.TwoFactor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{Token: token, RefreshToken: refresh, ExpiresAt: time.Unix(expiresAt.Unix(), 0)}, nil
+}
+
+func (authenticator *JWTAuthenticator) accessDuration() time.Duration {
+	if authenticator.AccessDuration > 0 {
+		return authenticator.AccessDuration
+	}
+
+	return accessDuration
+}
+
+func (authenticator *JWTAuthenticator) refreshDuration() time.Duration {
+	if authenticator.RefreshDuration > 0 {
+		return authenticator.RefreshDuration
+	}
+
+	return refreshDuration
+}
@@ -0,0 +1,161 @@
+package authentication
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+	"web_blog/internal/data/storage/memstorage"
+	"web_blog/internal/jwt"
+)
+
+func refreshSetup(t *testing.T, refreshDuration time.Duration) (*JWTAuthenticator, *storage.Storage, *entity.User) {
+	ctx := context.Background()
+
+	keys, err := jwt.ParseKeySet("test:HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("s", 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database := &memstorage.MemDatabase{}
+	if err := database.Open(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	store := memstorage.NewStorage(database)
+
+	user := &entity.User{RoleID: 1, Email: "ann@example.com", Username: "ann"}
+	if err := store.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := &JWTAuthenticator{Keys: keys, Issuer: "test", RefreshDuration: refreshDuration}
+	return authenticator, &store, user
+}
+
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	authenticator, store, user := refreshSetup(t, 0)
+
+	first, err := authenticator.Create(ctx, store, user, &entity.Session{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := authenticator.Refresh(ctx, store, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Token == "" || second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("got tokens %+v after %+v", second, first)
+	}
+
+	// The new access token belongs to the same session as the first one.
+	_, firstSession, err := authenticator.Validate(ctx, store, first.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validated, secondSession, err := authenticator.Validate(ctx, store, second.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if validated.ID != user.ID || secondSession != firstSession {
+		t.Errorf("got user %d in session %q, want %d in %q", validated.ID, secondSession, user.ID, firstSession)
+	}
+
+	// The rotated token can be refreshed in turn.
+	if _, err := authenticator.Refresh(ctx, store, second.RefreshToken); err != nil {
+		t.Errorf("got %v refreshing the rotated token", err)
+	}
+}
+
+// Sending a replaced token again revokes the session, so neither the thief nor
+// the user can keep refreshing it.
+func TestRefreshReuse(t *testing.T) {
+	ctx := context.Background()
+	authenticator, store, user := refreshSetup(t, 0)
+
+	first, err := authenticator.Create(ctx, store, user, &entity.Session{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := authenticator.Refresh(ctx, store, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := authenticator.Refresh(ctx, store, first.RefreshToken); !errors.Is(err, ErrorTokenReused) {
+		t.Fatalf("got %v reusing the old token, want %v", err, ErrorTokenReused)
+	}
+
+	_, session, err := authenticator.Validate(ctx, store, second.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Sessions.Find(ctx, session); !errors.Is(err, storage.ErrorNotFound) {
+		t.Errorf("got %v finding the revoked session", err)
+	}
+
+	for _, token := range []string{first.RefreshToken, second.RefreshToken} {
+		if _, err := authenticator.Refresh(ctx, store, token); !errors.Is(err, ErrorInvalidToken) {
+			t.Errorf("got %v refreshing a token of the revoked session, want %v", err, ErrorInvalidToken)
+		}
+	}
+}
+
+func TestRefreshRejected(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("expired session", func(t *testing.T) {
+		authenticator, store, user := refreshSetup(t, time.Nanosecond)
+
+		tokens, err := authenticator.Create(ctx, store, user, &entity.Session{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := authenticator.Refresh(ctx, store, tokens.RefreshToken); !errors.Is(err, ErrorSessionExpired) {
+			t.Errorf("got %v, want %v", err, ErrorSessionExpired)
+		}
+
+		// The token is not used up by the refused refresh.
+		refresh, err := store.RefreshTokens.Find(ctx, HashToken(tokens.RefreshToken))
+		if err != nil || refresh.UsedAt != nil {
+			t.Errorf("got %+v, %v", refresh, err)
+		}
+	})
+
+	t.Run("deleted session", func(t *testing.T) {
+		authenticator, store, user := refreshSetup(t, 0)
+
+		tokens, err := authenticator.Create(ctx, store, user, &entity.Session{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := authenticator.Invalidate(ctx, store, tokens.RefreshToken); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := authenticator.Refresh(ctx, store, tokens.RefreshToken); !errors.Is(err, ErrorInvalidToken) {
+			t.Errorf("got %v, want %v", err, ErrorInvalidToken)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		authenticator, store, _ := refreshSetup(t, 0)
+
+		if _, err := authenticator.Refresh(ctx, store, "unknown"); !errors.Is(err, ErrorInvalidToken) {
+			t.Errorf("got %v, want %v", err, ErrorInvalidToken)
+		}
+	})
+}
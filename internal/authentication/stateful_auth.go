@@ -2,94 +2,109 @@ package authentication
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/base32"
-	"encoding/hex"
+	"errors"
 	"time"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
 )
 
-type StatefulAuthenticator struct{}
+var ErrorSessionExpired = errors.New("session has expired")
 
-func (authenticator *StatefulAuthenticator) Generate() (string, error) {
-	var encoding *base32.Encoding
-	var bytes []byte
-	var err error
-
-	encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
-	bytes = make([]byte, byteSize)
-	if _, err = rand.Read(bytes); err != nil {
-		return "", err
-	}
+// Authenticates with opaque session tokens, looked up on every request.
+type StatefulAuthenticator struct {
+	// Lifetime of sessions, a week when zero.
+	Duration time.Duration
 
-	return encoding.EncodeToString(bytes), err
+	// Extends sessions by Duration whenever they are used, so only idle
+	// sessions expire.
+	Sliding bool
 }
 
 func (authenticator *StatefulAuthenticator) Create(
 	ctx context.Context,
-	repository storage.ISessionRepository,
-	token string,
-	id int64,
-) error {
-	var session *entity.Session
+	store *storage.Storage,
+	user *entity.User,
+	session *entity.Session,
+) (*Tokens, error) {
+	var token string
 	var err error
 
-	hash := sha256.Sum256([]byte(token))
-	token = hex.EncodeToString(hash[:])
-
-	session = &entity.Session{
-		ID:        token,
-		UserID:    id,
-		ExpiredAt: time.Now().Add(expireDuration),
+	if token, err = GenerateToken(); err != nil {
+		return nil, err
 	}
 
-	if err = repository.Create(ctx, nil, session); err != nil {
-		return err
+	session.ID = HashToken(token)
+	session.UserID = user.ID
+	session.ExpiredAt = time.Now().Add(authenticator.duration())
+
+	if err = store.Sessions.Create(ctx, session); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &Tokens{Token: token, ExpiresAt: session.ExpiredAt}, nil
 }
 
+// Returns the user of the token. Expired sessions are deleted and refused,
+// the others are marked as used.
 func (authenticator *StatefulAuthenticator) Validate(
 	ctx context.Context,
-	repository storage.ISessionRepository,
+	store *storage.Storage,
 	token string,
-) (*entity.User, error) {
+) (*entity.User, string, error) {
 	var user *entity.User
 	var session *entity.Session
 	var err error
 
-	hash := sha256.Sum256([]byte(token))
-	token = hex.EncodeToString(hash[:])
+	token = HashToken(token)
 
-	if session, user, err = repository.FindWithUser(ctx, nil, token); err != nil {
-		return nil, err
+	if session, user, err = store.Sessions.FindWithUser(ctx, token); err != nil {
+		return nil, "", err
 	}
 
-	if time.Now().Compare(session.ExpiredAt) > 0 {
-		if err = repository.Delete(ctx, nil, token); err != nil {
-			return nil, err
+	now := time.Now()
+	if session.Expired(now) {
+		if err = store.Sessions.Delete(ctx, token); err != nil && !errors.Is(err, storage.ErrorNotFound) {
+			return nil, "", err
+		}
+
+		return nil, "", ErrorSessionExpired
+	}
+
+	if now.Sub(session.LastSeenAt) >= touchInterval {
+		session.LastSeenAt = now
+		if authenticator.Sliding {
+			session.ExpiredAt = now.Add(authenticator.duration())
+		}
+
+		if err = store.Sessions.Update(ctx, session); err != nil {
+			return nil, "", err
 		}
 	}
 
-	return user, nil
+	return user, session.ID, nil
 }
 
 func (authenticator *StatefulAuthenticator) Invalidate(
 	ctx context.Context,
-	repository storage.ISessionRepository,
+	store *storage.Storage,
 	token string,
 ) error {
-	var err error
+	return store.Sessions.Delete(ctx, HashToken(token))
+}
 
-	hash := sha256.Sum256([]byte(token))
-	token = hex.EncodeToString(hash[:])
+// Session tokens live as long as their session, so there is nothing to refresh.
+func (authenticator *StatefulAuthenticator) Refresh(
+	ctx context.Context,
+	store *storage.Storage,
+	token string,
+) (*Tokens, error) {
+	return nil, ErrorRefreshUnsupported
+}
 
-	if err = repository.Delete(ctx, nil, token); err != nil {
-		return err
+func (authenticator *StatefulAuthenticator) duration() time.Duration {
+	if authenticator.Duration > 0 {
+		return authenticator.Duration
 	}
 
-	return nil
+	return expireDuration
 }
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	AuditLoginLockout   = "login.lockout"
+	AuditLoginUnlock    = "login.unlock"
+	AuditPostApprove    = "post.approve"
+	AuditPostReject     = "post.reject"
+	AuditCommentApprove = "comment.approve"
+	AuditCommentReject  = "comment.reject"
+	AuditSettingsUpdate = "settings.update"
+)
+
+// Audit entries record security relevant events and moderation decisions,
+// whose details hold the moderated row and the reason. They outlive the users
+// they mention, whose ids are cleared when they are deleted.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	ActorID   *int64    `json:"actor_id"`
+	UserID    *int64    `json:"user_id"`
+	IP        string    `json:"ip"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
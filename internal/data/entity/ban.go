@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Bans without an expiry are permanent. BannedBy is nil once the banning
+// user is deleted.
+type Ban struct {
+	UserID    int64      `json:"user_id"`
+	BannedBy  *int64     `json:"banned_by"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (ban *Ban) Active(now time.Time) bool {
+	return ban.ExpiresAt == nil || ban.ExpiresAt.After(now)
+}
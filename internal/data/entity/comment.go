@@ -2,12 +2,23 @@ package entity
 
 import "time"
 
+// Comments without a parent start a thread on their post. Replies are one
+// level deeper than the comment they reply to, on the same post.
 type Comment struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	PostID    int64     `json:"post_id"`
-	Content   string    `json:"content"`
-	Verified  bool      `json:"verified"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	PostID     int64      `json:"post_id"`
+	ParentID   *int64     `json:"parent_id"`
+	Depth      int        `json:"depth"`
+	Content    string     `json:"content"`
+	Verified   bool       `json:"verified"`
+	ReplyCount int64      `json:"reply_count"`
+	EditedAt   *time.Time `json:"edited_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy  *int64     `json:"deleted_by,omitempty"`
+
+	// Only loaded when comments are listed as threads.
+	Replies []*Comment `json:"replies,omitempty"`
 }
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// Immutable record of what a comment said before an edit, stored on every
+// edit. EditorID is nil once the editor is deleted.
+type CommentEdit struct {
+	ID        int64     `json:"id"`
+	CommentID int64     `json:"comment_id"`
+	EditorID  *int64    `json:"editor_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
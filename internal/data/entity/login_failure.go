@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Failed logins are counted per key, which names the account or the address
+// they were made from.
+type LoginFailure struct {
+	ID           string     `json:"id"`
+	Failures     int        `json:"failures"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+}
+
+func (failure *LoginFailure) Locked(now time.Time) bool {
+	return failure.LockedUntil != nil && failure.LockedUntil.After(now)
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// A post or comment waiting to be approved. Title is only set for posts.
+type ModerationItem struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	UserID    int64     `json:"user_id"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// Password resets are keyed by the hash of their token, the token itself is
+// only handed to the user.
+type PasswordReset struct {
+	ID        string    `json:"-"`
+	UserID    int64     `json:"user_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
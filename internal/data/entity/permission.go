@@ -0,0 +1,24 @@
+package entity
+
+// Permissions seeded by the permissions migration. Permissions ending in :any
+// apply to resources of other users, owners need no permission for their own.
+const (
+	PermissionPostsCreate       string = "posts:create"
+	PermissionPostsUpdateAny    string = "posts:update:any"
+	PermissionPostsDeleteAny    string = "posts:delete:any"
+	PermissionCommentsCreate    string = "comments:create"
+	PermissionCommentsReadAny   string = "comments:read:any"
+	PermissionCommentsUpdateAny string = "comments:update:any"
+	PermissionCommentsDeleteAny string = "comments:delete:any"
+	PermissionContentModerate   string = "content:moderate"
+	PermissionTagsManage        string = "tags:manage"
+	PermissionUsersRead         string = "users:read"
+	PermissionUsersManage       string = "users:manage"
+	PermissionRolesManage       string = "roles:manage"
+)
+
+type Permission struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
@@ -2,12 +2,31 @@ package entity
 
 import "time"
 
+// Post statuses. Only published posts are public, scheduled posts are
+// published by the scheduler once their publish_at time has passed.
+const (
+	PostDraft     string = "draft"
+	PostScheduled string = "scheduled"
+	PostPublished string = "published"
+	PostArchived  string = "archived"
+)
+
 type Post struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Verified  bool      `json:"verified"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	Verified  bool       `json:"verified"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int64     `json:"deleted_by,omitempty"`
+}
+
+// Published posts are visible to everyone, other posts only to their author.
+func (post *Post) VisibleTo(user *User) bool {
+	return post.Status == PostPublished || (user != nil && user.ID == post.UserID)
 }
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// Immutable snapshot of a post's title and content, stored on every edit.
+// EditorID is nil once the editor is deleted.
+type PostRevision struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	EditorID  *int64    `json:"editor_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
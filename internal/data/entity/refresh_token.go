@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Refresh tokens belong to a session and are deleted with it. Like sessions,
+// they are keyed by the hash of their token.
+//
+// Each refresh replaces the token with a new one of the same session, so a
+// session holds the family of tokens rotated from its first one. Used tokens
+// are kept to recognize them when they are sent again.
+type RefreshToken struct {
+	ID        string     `json:"-"`
+	SessionID string     `json:"session_id"`
+	UserID    int64      `json:"user_id"`
+	ExpiredAt time.Time  `json:"expired_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (token *RefreshToken) Expired(now time.Time) bool {
+	return !token.ExpiredAt.After(now)
+}
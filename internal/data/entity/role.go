@@ -1,8 +1,15 @@
 package entity
 
+import "slices"
+
 type Role struct {
-	ID          int64  `json:"id"`
-	Level       int    `json:"level"`
-	Name        string `json:"title"`
-	Description string `json:"description"`
+	ID          int64    `json:"id"`
+	Level       int      `json:"level"`
+	Name        string   `json:"title"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (role *Role) Can(permission string) bool {
+	return slices.Contains(role.Permissions, permission)
 }
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// A post or comment matching a search. Title is only set for posts, the
+// snippet highlights the matched terms with <b> tags.
+type SearchResult struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	UserID    int64     `json:"user_id"`
+	Title     string    `json:"title,omitempty"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
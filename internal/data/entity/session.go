@@ -4,8 +4,19 @@ import (
 	"time"
 )
 
+// Sessions are identified by the hash of their token, so the id can be shown
+// to the user without letting anyone authenticate with it.
 type Session struct {
-	ID        string    `json:"id"`
-	UserID    int64     `json:"user_id"`
-	ExpiredAt time.Time `json:"expired_at"`
+	ID         string    `json:"id"`
+	UserID     int64     `json:"user_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+	ExpiredAt  time.Time `json:"expired_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (session *Session) Expired(now time.Time) bool {
+	return !session.ExpiredAt.After(now)
 }
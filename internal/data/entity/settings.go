@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// Settings that admins change at runtime, stored in a single row.
+type Settings struct {
+	// Roles at or above this level have to enable two factor authentication
+	// before they can use their account, none when zero.
+	TwoFactorLevel int `json:"two_factor_level"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
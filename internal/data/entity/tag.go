@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Tag struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	PostCount int64  `json:"post_count"`
+}
+
+// Normalizes a tag name into its slug: lower case letters and digits, with
+// every other run of characters collapsed into a single dash.
+func Slugify(name string) string {
+	var sb strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// Users have at most one TOTP secret, keyed by their id. The secret is pending
+// until it is confirmed with a code, which enables it.
+type TwoFactor struct {
+	UserID int64  `json:"user_id"`
+	Secret string `json:"-"`
+
+	// Time step of the last accepted code, codes are not accepted twice.
+	LastStep int64 `json:"-"`
+
+	EnabledAt *time.Time `json:"enabled_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (twoFactor *TwoFactor) Enabled() bool {
+	return twoFactor.EnabledAt != nil
+}
+
+// Recovery codes are single use replacements for a TOTP code, keyed by their
+// hash.
+type RecoveryCode struct {
+	ID        string    `json:"-"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Login challenges are handed out instead of a token when the user has two
+// factor authentication enabled, and exchanged for one along with a code.
+// Like password resets, they are keyed by the hash of their token.
+type LoginChallenge struct {
+	ID        string    `json:"-"`
+	UserID    int64     `json:"user_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (challenge *LoginChallenge) Expired(now time.Time) bool {
+	return !challenge.ExpiredAt.After(now)
+}
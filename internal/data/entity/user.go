@@ -7,15 +7,21 @@ import (
 )
 
 type User struct {
-	ID        int64     `json:"id"`
-	RoleID    int64     `json:"role_id"`
-	Role      Role      `json:"-"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	Password  Password  `json:"-"`
-	Verified  bool      `json:"verified"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int64      `json:"id"`
+	RoleID    int64      `json:"role_id"`
+	Role      Role       `json:"-"`
+	Ban       *Ban       `json:"ban,omitempty"`
+	Email     string     `json:"email"`
+	Username  string     `json:"username"`
+	Password  Password   `json:"-"`
+	Bio       string     `json:"bio"`
+	AvatarURL string     `json:"avatar_url"`
+	Verified  bool       `json:"verified"`
+	TwoFactor bool       `json:"two_factor"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int64     `json:"deleted_by,omitempty"`
 }
 
 type Password struct {
@@ -6,8 +6,14 @@ import (
 	"github.com/google/uuid"
 )
 
+// Verifications with an email change the user's email to it once verified.
+// Used verifications are kept until they expire, so verifying twice can be
+// told apart from verifying with an unknown id.
 type Verification struct {
-	UUID      uuid.UUID `json:"id"`
-	UserID    int64     `json:"user_id"`
-	ExpiredAt time.Time `json:"expired_at"`
+	UUID      uuid.UUID  `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Email     *string    `json:"email,omitempty"`
+	ExpiredAt time.Time  `json:"expired_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -3,16 +3,79 @@ package storage
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// Fields a listing can be sorted and filtered by. Sort fields are column names,
+// filters are the query parameter names handled by FilterQuery.Parse. Only
+// seekable listings can be paged by cursor. Soft deleted rows of trashable
+// listings are left out, unless the trash is listed.
+type Listing struct {
+	Sortable   []string
+	Filterable []string
+	Seekable   bool
+	Trashable  bool
+}
+
+var (
+	UserListing = Listing{
+		Sortable:   []string{"id", "created_at", "updated_at", "username", "email"},
+		Filterable: []string{"since", "until", "verified"},
+		Seekable:   true,
+		Trashable:  true,
+	}
+	PostListing = Listing{
+		Sortable:   []string{"id", "created_at", "updated_at", "title"},
+		Filterable: []string{"since", "until", "verified", "user_id", "status"},
+		Seekable:   true,
+		Trashable:  true,
+	}
+	CommentListing = Listing{
+		Sortable:   []string{"id", "created_at", "updated_at"},
+		Filterable: []string{"since", "until", "verified", "user_id"},
+		Seekable:   true,
+		Trashable:  true,
+	}
+	// Tags are ordered by usage and paged by offset.
+	TagListing = Listing{}
+	// Revisions and comment edits are ordered oldest first and paged by offset.
+	RevisionListing    = Listing{}
+	CommentEditListing = Listing{}
+	// Roles and permissions are ordered by id and paged by offset.
+	RoleListing       = Listing{}
+	PermissionListing = Listing{}
+)
+
+// Lists are paged either by offset or, for seekable listings, by a cursor
+// taken from a previous page. The two can not be combined, and cursors
+// only follow the default newest first order, so they can not be sorted.
+//
+// Sort fields are ascending unless prefixed with "-". Since is inclusive and
+// Until exclusive, both compare against created_at.
 type FilterQuery struct {
-	Limit  int `json:"limit" validate:"gte=0,lte=20"`
-	Offset int `json:"offset" validate:"gte=0"`
+	Limit    int        `json:"limit" validate:"gte=0,lte=20"`
+	Offset   int        `json:"offset" validate:"gte=0,excluded_with=Cursor"`
+	Cursor   *Cursor    `json:"cursor" validate:"excluded_with=Sort"`
+	Sort     []string   `json:"sort"`
+	Since    *time.Time `json:"since"`
+	Until    *time.Time `json:"until"`
+	Verified *bool      `json:"verified"`
+	UserID   *int64     `json:"user_id" validate:"omitempty,gt=0"`
+	Status   *string    `json:"status" validate:"omitempty,oneof=draft scheduled published archived"`
+
+	// Whitelist the sort and filter fields are validated against.
+	Listing Listing `json:"-" validate:"-"`
+
+	// Lists only soft deleted rows of trashable listings. Set by services
+	// rather than parsed from the request.
+	Deleted bool `json:"-" validate:"-"`
 }
 
 func (filterQuery *FilterQuery) Parse(r *http.Request) error {
 	var limit int
 	var offset int
+	var cursor *Cursor
 	var err error
 	query := r.URL.Query()
 
@@ -32,5 +95,103 @@ func (filterQuery *FilterQuery) Parse(r *http.Request) error {
 		filterQuery.Offset = offset
 	}
 
+	if c := query.Get("cursor"); c != "" {
+		if cursor, err = DecodeCursor(c); err != nil {
+			return err
+		}
+
+		filterQuery.Cursor = cursor
+	}
+
+	if s := query.Get("sort"); s != "" {
+		for _, field := range strings.Split(s, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				filterQuery.Sort = append(filterQuery.Sort, field)
+			}
+		}
+	}
+
+	if s := query.Get("since"); s != "" {
+		if filterQuery.Since, err = parseTime(s); err != nil {
+			return err
+		}
+	}
+
+	if u := query.Get("until"); u != "" {
+		if filterQuery.Until, err = parseTime(u); err != nil {
+			return err
+		}
+	}
+
+	if v := query.Get("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+
+		filterQuery.Verified = &verified
+	}
+
+	if u := query.Get("user_id"); u != "" {
+		id, err := strconv.ParseInt(u, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		filterQuery.UserID = &id
+	}
+
+	if s := query.Get("status"); s != "" {
+		filterQuery.Status = &s
+	}
+
 	return nil
 }
+
+// Filters that were set, by their query parameter name.
+func (filterQuery *FilterQuery) Filters() []string {
+	var filters []string
+
+	if filterQuery.Since != nil {
+		filters = append(filters, "since")
+	}
+
+	if filterQuery.Until != nil {
+		filters = append(filters, "until")
+	}
+
+	if filterQuery.Verified != nil {
+		filters = append(filters, "verified")
+	}
+
+	if filterQuery.UserID != nil {
+		filters = append(filters, "user_id")
+	}
+
+	if filterQuery.Status != nil {
+		filters = append(filters, "status")
+	}
+
+	return filters
+}
+
+// Splits a sort field into its column and direction.
+func SortField(field string) (string, bool) {
+	if column, ok := strings.CutPrefix(field, "-"); ok {
+		return column, true
+	}
+
+	return field, false
+}
+
+// Accepts RFC 3339 timestamps or plain dates, which are read as UTC midnight.
+func parseTime(text string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, text); err != nil {
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,22 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type MemAuditLogRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemAuditLogRepository) Create(ctx context.Context, entry *entity.AuditEntry) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	entry.ID = database.nextID("audit_log")
+	entry.CreatedAt = now()
+	database.tables.auditLog[entry.ID] = *entry
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package memstorage
+
+import (
+	"context"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemBanRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemBanRepository) Create(ctx context.Context, ban *entity.Ban) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.users[ban.UserID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	ban.CreatedAt = now()
+	database.tables.bans[ban.UserID] = *ban
+
+	return nil
+}
+
+func (repository *MemBanRepository) Find(ctx context.Context, id int64) (*entity.Ban, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.bans, id)
+}
+
+func (repository *MemBanRepository) Delete(ctx context.Context, id int64) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return deleteOne(repository.Database.tables.bans, id)
+}
+
+// Must be called while holding the lock.
+func (database *MemDatabase) isBanned(id int64) bool {
+	ban, ok := database.tables.bans[id]
+	return ok && ban.Active(time.Now())
+}
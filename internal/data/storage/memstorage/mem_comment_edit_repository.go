@@ -0,0 +1,36 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemCommentEditRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemCommentEditRepository) Create(ctx context.Context, edit *entity.CommentEdit) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.comments[edit.CommentID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	edit.ID = database.nextID("comment_edits")
+	edit.CreatedAt = now()
+	database.tables.commentEdits[edit.ID] = *edit
+
+	return nil
+}
+
+func (repository *MemCommentEditRepository) FindAllByCommentID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.CommentEdit], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findPage(repository.Database.tables.commentEdits, filter, func(edit *entity.CommentEdit) bool {
+		return edit.CommentID == id
+	}, nil), nil
+}
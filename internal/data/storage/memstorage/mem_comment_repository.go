@@ -0,0 +1,339 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemCommentRepository struct {
+	Database *MemDatabase
+}
+
+var commentColumns = columns[entity.Comment]{
+	position: storage.CommentPosition,
+	compare: map[string]func(a, b *entity.Comment) int{
+		"updated_at": func(a, b *entity.Comment) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	},
+	verified:  func(comment *entity.Comment) bool { return comment.Verified },
+	userID:    func(comment *entity.Comment) int64 { return comment.UserID },
+	deletedAt: func(comment *entity.Comment) *time.Time { return comment.DeletedAt },
+}
+
+func (repository *MemCommentRepository) Create(ctx context.Context, comment *entity.Comment) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if comment.ParentID != nil {
+		if _, ok := database.tables.comments[*comment.ParentID]; !ok {
+			return storage.ErrorNotFound
+		}
+	}
+
+	comment.ID = database.nextID("comments")
+	comment.Verified = false
+	comment.ReplyCount = 0
+	comment.EditedAt = nil
+	comment.CreatedAt = now()
+	comment.UpdatedAt = comment.CreatedAt
+	database.tables.comments[comment.ID] = *comment
+
+	return nil
+}
+
+func (repository *MemCommentRepository) Find(ctx context.Context, id int64) (*entity.Comment, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	comment, err := findOne(repository.Database.tables.comments, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.DeletedAt != nil {
+		return nil, storage.ErrorNotFound
+	}
+
+	repository.Database.countReplies([]*entity.Comment{comment})
+	return comment, nil
+}
+
+func (repository *MemCommentRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Comment], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return repository.Database.findComments(filter, nil), nil
+}
+
+func (repository *MemCommentRepository) FindAllByUserID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Comment], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return repository.Database.findComments(filter, func(comment *entity.Comment) bool {
+		return comment.UserID == id
+	}), nil
+}
+
+func (repository *MemCommentRepository) FindAllByPostID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Comment], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return repository.Database.findComments(filter, func(comment *entity.Comment) bool {
+		return comment.PostID == id
+	}), nil
+}
+
+func (repository *MemCommentRepository) FindAllByParentID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Comment], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return repository.Database.findComments(filter, func(comment *entity.Comment) bool {
+		return comment.ParentID != nil && *comment.ParentID == id
+	}), nil
+}
+
+func (repository *MemCommentRepository) FindThreadsByPostID(ctx context.Context, filter storage.FilterQuery, id int64, replies int) (*storage.Page[entity.Comment], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	page := repository.Database.findComments(filter, func(comment *entity.Comment) bool {
+		return comment.PostID == id && comment.ParentID == nil
+	})
+	repository.Database.findReplies(page.Items, replies, filter.Verified)
+
+	return page, nil
+}
+
+func (repository *MemCommentRepository) FindThreadsByParentID(ctx context.Context, filter storage.FilterQuery, id int64, replies int) (*storage.Page[entity.Comment], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	page := repository.Database.findComments(filter, func(comment *entity.Comment) bool {
+		return comment.ParentID != nil && *comment.ParentID == id
+	})
+	repository.Database.findReplies(page.Items, replies, filter.Verified)
+
+	return page, nil
+}
+
+func (repository *MemCommentRepository) Update(ctx context.Context, comment *entity.Comment) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	stored, ok := database.tables.comments[comment.ID]
+	if !ok {
+		return storage.ErrorNotFound
+	}
+
+	stored.Content = comment.Content
+	stored.Verified = comment.Verified
+	stored.UpdatedAt = now()
+	stored.EditedAt = &stored.UpdatedAt
+	database.tables.comments[comment.ID] = stored
+
+	comment.EditedAt = stored.EditedAt
+	comment.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (repository *MemCommentRepository) Approve(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	comment, ok := database.tables.comments[id]
+	if !ok || comment.DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	comment.Verified = true
+	database.tables.comments[id] = comment
+
+	return nil
+}
+
+func (repository *MemCommentRepository) SoftDelete(ctx context.Context, id int64, by int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	deletedAt := deletedNow()
+	moved := database.moveComments(func(comment *entity.Comment) bool {
+		return comment.ID == id
+	}, nil, &deletedAt, &by)
+	if moved == 0 {
+		return storage.ErrorNotFound
+	}
+
+	return nil
+}
+
+// Replies can only be restored while their parent is not deleted.
+func (repository *MemCommentRepository) Restore(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	comment, ok := database.tables.comments[id]
+	if !ok || comment.DeletedAt == nil ||
+		database.tables.posts[comment.PostID].DeletedAt != nil ||
+		database.tables.users[comment.UserID].DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	if comment.ParentID != nil && database.tables.comments[*comment.ParentID].DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	database.moveComments(func(other *entity.Comment) bool {
+		return other.ID == id
+	}, comment.DeletedAt, nil, nil)
+
+	return nil
+}
+
+func (repository *MemCommentRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	count := len(database.tables.comments)
+	database.deleteComments(func(comment *entity.Comment) bool {
+		return comment.DeletedAt != nil && comment.DeletedAt.Before(before)
+	})
+
+	return int64(count - len(database.tables.comments)), nil
+}
+
+func (repository *MemCommentRepository) Delete(ctx context.Context, id int64) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	if _, ok := repository.Database.tables.comments[id]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	repository.Database.deleteComments(func(comment *entity.Comment) bool {
+		return comment.ID == id
+	})
+
+	return nil
+}
+
+// Must be called while holding the read lock.
+func (database *MemDatabase) findComments(filter storage.FilterQuery, where func(*entity.Comment) bool) *storage.Page[entity.Comment] {
+	page := findPage(database.tables.comments, filter, where, &commentColumns)
+	database.countReplies(page.Items)
+
+	return page
+}
+
+// Must be called while holding the read lock.
+func (database *MemDatabase) countReplies(comments []*entity.Comment) {
+	counts := map[int64]int64{}
+	for _, comment := range database.tables.comments {
+		if comment.ParentID != nil && comment.DeletedAt == nil {
+			counts[*comment.ParentID]++
+		}
+	}
+
+	for _, comment := range comments {
+		comment.ReplyCount = counts[comment.ID]
+	}
+}
+
+// Mirrors the recursive query of the pgx repository, walking down the threads
+// one depth at a time and taking the oldest replies of every comment, which
+// match verified unless it is nil.
+// Must be called while holding the read lock.
+func (database *MemDatabase) findReplies(roots []*entity.Comment, limit int, verified *bool) {
+	var replies []*entity.Comment
+	if limit <= 0 {
+		return
+	}
+
+	children := map[int64][]*entity.Comment{}
+	for _, key := range slices.Sorted(maps.Keys(database.tables.comments)) {
+		comment := database.tables.comments[key]
+		if verified != nil && comment.Verified != *verified {
+			continue
+		}
+
+		if comment.ParentID != nil && comment.DeletedAt == nil {
+			children[*comment.ParentID] = append(children[*comment.ParentID], &comment)
+		}
+	}
+
+	for _, list := range children {
+		slices.SortFunc(list, func(a, b *entity.Comment) int {
+			return storage.CommentPosition(a).Compare(storage.CommentPosition(b))
+		})
+	}
+
+	for level := roots; len(level) > 0; {
+		var next []*entity.Comment
+		for _, comment := range level {
+			list := children[comment.ID]
+			next = append(next, list[:min(len(list), limit)]...)
+		}
+
+		replies = append(replies, next...)
+		level = next
+	}
+
+	database.countReplies(replies)
+	storage.NestReplies(roots, replies)
+}
+
+// Mirrors moveComments of the pgx repositories, moving the matching comments
+// and their replies from the deleted_at from to the deleted_at to, and returns
+// how many were moved.
+// Must be called while holding the write lock.
+func (database *MemDatabase) moveComments(where func(*entity.Comment) bool, from *time.Time, to *time.Time, by *int64) int {
+	moved := map[int64]bool{}
+
+	for count := -1; count != len(moved); {
+		count = len(moved)
+		for id, comment := range database.tables.comments {
+			if moved[id] || !sameTime(comment.DeletedAt, from) {
+				continue
+			}
+
+			if where(&comment) || (comment.ParentID != nil && moved[*comment.ParentID]) {
+				comment.DeletedAt = to
+				comment.DeletedBy = by
+				database.tables.comments[id] = comment
+				moved[id] = true
+			}
+		}
+	}
+
+	return len(moved)
+}
+
+// Mirrors the cascading foreign key of replies on their parent comment.
+// Must be called while holding the write lock.
+func (database *MemDatabase) deleteComments(where func(*entity.Comment) bool) {
+	deleted := map[int64]bool{}
+
+	for count := -1; count != len(deleted); {
+		count = len(deleted)
+		maps.DeleteFunc(database.tables.comments, func(id int64, comment entity.Comment) bool {
+			if where(&comment) || (comment.ParentID != nil && deleted[*comment.ParentID]) {
+				deleted[id] = true
+				return true
+			}
+
+			return false
+		})
+	}
+
+	maps.DeleteFunc(database.tables.commentEdits, func(_ int64, edit entity.CommentEdit) bool {
+		return deleted[edit.CommentID]
+	})
+}
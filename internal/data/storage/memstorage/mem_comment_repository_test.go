@@ -0,0 +1,75 @@
+package memstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+func TestCommentUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	database := &MemDatabase{}
+	if err := database.Open(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStorage(database)
+
+	user := &entity.User{RoleID: 1, Email: "ann@example.com", Username: "ann"}
+	if err := store.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	post := &entity.Post{UserID: user.ID, Title: "title", Content: "content", Status: entity.PostPublished}
+	if err := store.Posts.Create(ctx, post); err != nil {
+		t.Fatal(err)
+	}
+
+	comment := &entity.Comment{UserID: user.ID, PostID: post.ID, Content: "before"}
+	if err := store.Comments.Create(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	// Backdated, so the update moves updated_at.
+	created := comment.CreatedAt.Add(-time.Hour)
+	stored := database.tables.comments[comment.ID]
+	stored.CreatedAt, stored.UpdatedAt = created, created
+	database.tables.comments[comment.ID] = stored
+
+	if err := store.WithinTx(ctx, func(store *storage.Storage) error {
+		edit := &entity.CommentEdit{CommentID: comment.ID, EditorID: &user.ID, Content: comment.Content}
+		if err := store.CommentEdits.Create(ctx, edit); err != nil {
+			return err
+		}
+
+		comment.Content = "after"
+		return store.Comments.Update(ctx, comment)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if comment.EditedAt == nil || !comment.EditedAt.Equal(comment.UpdatedAt) || !comment.UpdatedAt.After(created) {
+		t.Errorf("got edited at %v, updated at %v", comment.EditedAt, comment.UpdatedAt)
+	}
+
+	found, err := store.Comments.Find(ctx, comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.Content != "after" || found.EditedAt == nil || !found.UpdatedAt.Equal(comment.UpdatedAt) || !found.CreatedAt.Equal(created) {
+		t.Errorf("got stored %+v", found)
+	}
+
+	page, err := store.CommentEdits.FindAllByCommentID(ctx, storage.FilterQuery{Limit: 20, Listing: storage.CommentEditListing}, comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Items) != 1 || page.Items[0].Content != "before" || *page.Items[0].EditorID != user.ID {
+		t.Errorf("got edits %+v", page.Items)
+	}
+}
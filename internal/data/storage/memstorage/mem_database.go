@@ -0,0 +1,229 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+
+	"github.com/google/uuid"
+)
+
+// In-memory database, used for tests and local development.
+type MemDatabase struct {
+	mutex  sync.RWMutex
+	tables tables
+}
+
+type tables struct {
+	sequences      map[string]int64
+	users          map[int64]entity.User
+	posts          map[int64]entity.Post
+	comments       map[int64]entity.Comment
+	commentEdits   map[int64]entity.CommentEdit
+	verifications  map[uuid.UUID]entity.Verification
+	sessions       map[string]entity.Session
+	refreshTokens  map[string]entity.RefreshToken
+	bans           map[int64]entity.Ban
+	roles          map[int64]entity.Role
+	permissions    map[int64]entity.Permission
+	rolePerms      map[rolePermission]struct{}
+	tags           map[int64]entity.Tag
+	postTags       map[postTag]struct{}
+	revisions      map[int64]entity.PostRevision
+	passwordResets map[string]entity.PasswordReset
+	twoFactors     map[int64]entity.TwoFactor
+	recoveryCodes  map[string]entity.RecoveryCode
+	challenges     map[string]entity.LoginChallenge
+	loginFailures  map[string]entity.LoginFailure
+	auditLog       map[int64]entity.AuditEntry
+	settings       entity.Settings
+}
+
+type postTag struct {
+	postID int64
+	tagID  int64
+}
+
+type rolePermission struct {
+	roleID       int64
+	permissionID int64
+}
+
+func (database *MemDatabase) Open(ctx context.Context, config any) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.tables = tables{
+		sequences:      map[string]int64{},
+		users:          map[int64]entity.User{},
+		posts:          map[int64]entity.Post{},
+		comments:       map[int64]entity.Comment{},
+		commentEdits:   map[int64]entity.CommentEdit{},
+		verifications:  map[uuid.UUID]entity.Verification{},
+		sessions:       map[string]entity.Session{},
+		refreshTokens:  map[string]entity.RefreshToken{},
+		bans:           map[int64]entity.Ban{},
+		roles:          map[int64]entity.Role{},
+		permissions:    map[int64]entity.Permission{},
+		rolePerms:      map[rolePermission]struct{}{},
+		tags:           map[int64]entity.Tag{},
+		postTags:       map[postTag]struct{}{},
+		revisions:      map[int64]entity.PostRevision{},
+		passwordResets: map[string]entity.PasswordReset{},
+		twoFactors:     map[int64]entity.TwoFactor{},
+		recoveryCodes:  map[string]entity.RecoveryCode{},
+		challenges:     map[string]entity.LoginChallenge{},
+		loginFailures:  map[string]entity.LoginFailure{},
+		auditLog:       map[int64]entity.AuditEntry{},
+		settings:       entity.Settings{UpdatedAt: now()},
+	}
+
+	// Same roles and permissions as seeded by the migrations.
+	for _, role := range []entity.Role{
+		{Level: 1, Name: "user", Description: "user can - create posts, comments"},
+		{Level: 2, Name: "moderator", Description: "moderator can - create/update/delete posts, comments"},
+		{Level: 3, Name: "admin", Description: "admin"},
+	} {
+		role.ID = database.nextID("roles")
+		database.tables.roles[role.ID] = role
+	}
+
+	for _, permission := range []entity.Permission{
+		{Name: entity.PermissionPostsCreate, Description: "create posts"},
+		{Name: entity.PermissionPostsUpdateAny, Description: "update posts of other users"},
+		{Name: entity.PermissionPostsDeleteAny, Description: "delete posts of other users"},
+		{Name: entity.PermissionCommentsCreate, Description: "create comments"},
+		{Name: entity.PermissionCommentsReadAny, Description: "list the comments of all users"},
+		{Name: entity.PermissionCommentsUpdateAny, Description: "update comments of other users"},
+		{Name: entity.PermissionCommentsDeleteAny, Description: "delete comments of other users"},
+		{Name: entity.PermissionContentModerate, Description: "approve and reject posts and comments"},
+		{Name: entity.PermissionTagsManage, Description: "rename and merge tags"},
+		{Name: entity.PermissionUsersRead, Description: "list users"},
+		{Name: entity.PermissionRolesManage, Description: "create roles and assign permissions"},
+		{Name: entity.PermissionUsersManage, Description: "change roles of, ban and delete users"},
+	} {
+		permission.ID = database.nextID("permissions")
+		database.tables.permissions[permission.ID] = permission
+	}
+
+	for _, permission := range database.tables.permissions {
+		grant := map[string]bool{
+			"user": permission.Name == entity.PermissionPostsCreate ||
+				permission.Name == entity.PermissionCommentsCreate,
+			"moderator": permission.Name != entity.PermissionUsersRead &&
+				permission.Name != entity.PermissionUsersManage &&
+				permission.Name != entity.PermissionRolesManage,
+			"admin": true,
+		}
+
+		for _, role := range database.tables.roles {
+			if grant[role.Name] {
+				database.tables.rolePerms[rolePermission{roleID: role.ID, permissionID: permission.ID}] = struct{}{}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (database *MemDatabase) Close(ctx context.Context) error {
+	return nil
+}
+
+// Transactions hold the write lock while they run, so other callers wait for
+// them instead of seeing or overwriting their writes. Their callbacks work on
+// a view of the tables with its own lock, and are rolled back by restoring a
+// snapshot when they fail or panic.
+func (database *MemDatabase) WithinTx(ctx context.Context, fn func(*storage.Storage) error) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	snapshot := database.snapshot()
+	committed := false
+	defer func() {
+		if !committed {
+			database.tables = snapshot
+		}
+	}()
+
+	view := &MemDatabase{tables: database.tables}
+	store := NewStorage(view)
+	store.Database = memTransaction{view}
+
+	if err := fn(&store); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// Database handed to transactional callbacks, nested calls join the running
+// transaction instead of waiting for it to finish.
+type memTransaction struct {
+	*MemDatabase
+}
+
+func (transaction memTransaction) WithinTx(ctx context.Context, fn func(*storage.Storage) error) error {
+	store := NewStorage(transaction.MemDatabase)
+	store.Database = transaction
+	return fn(&store)
+}
+
+// Rows are stored by value, so copying the maps is enough.
+// Must be called while holding the lock.
+func (database *MemDatabase) snapshot() tables {
+	return tables{
+		sequences:      maps.Clone(database.tables.sequences),
+		users:          maps.Clone(database.tables.users),
+		posts:          maps.Clone(database.tables.posts),
+		comments:       maps.Clone(database.tables.comments),
+		commentEdits:   maps.Clone(database.tables.commentEdits),
+		verifications:  maps.Clone(database.tables.verifications),
+		sessions:       maps.Clone(database.tables.sessions),
+		refreshTokens:  maps.Clone(database.tables.refreshTokens),
+		bans:           maps.Clone(database.tables.bans),
+		roles:          maps.Clone(database.tables.roles),
+		permissions:    maps.Clone(database.tables.permissions),
+		rolePerms:      maps.Clone(database.tables.rolePerms),
+		tags:           maps.Clone(database.tables.tags),
+		postTags:       maps.Clone(database.tables.postTags),
+		revisions:      maps.Clone(database.tables.revisions),
+		passwordResets: maps.Clone(database.tables.passwordResets),
+		twoFactors:     maps.Clone(database.tables.twoFactors),
+		recoveryCodes:  maps.Clone(database.tables.recoveryCodes),
+		challenges:     maps.Clone(database.tables.challenges),
+		loginFailures:  maps.Clone(database.tables.loginFailures),
+		auditLog:       maps.Clone(database.tables.auditLog),
+		settings:       database.tables.settings,
+	}
+}
+
+func NewStorage(database *MemDatabase) storage.Storage {
+	return storage.Storage{
+		Database:        database,
+		Users:           &MemUserRepository{Database: database},
+		Posts:           &MemPostRepository{Database: database},
+		Comments:        &MemCommentRepository{Database: database},
+		CommentEdits:    &MemCommentEditRepository{Database: database},
+		Verifications:   &MemVerificationRepository{Database: database},
+		Sessions:        &MemSessionRepository{Database: database},
+		RefreshTokens:   &MemRefreshTokenRepository{Database: database},
+		Bans:            &MemBanRepository{Database: database},
+		Roles:           &MemRoleRepository{Database: database},
+		Permissions:     &MemPermissionRepository{Database: database},
+		Search:          &MemSearchRepository{Database: database},
+		Moderation:      &MemModerationRepository{Database: database},
+		Tags:            &MemTagRepository{Database: database},
+		Revisions:       &MemPostRevisionRepository{Database: database},
+		PasswordResets:  &MemPasswordResetRepository{Database: database},
+		TwoFactors:      &MemTwoFactorRepository{Database: database},
+		RecoveryCodes:   &MemRecoveryCodeRepository{Database: database},
+		LoginChallenges: &MemLoginChallengeRepository{Database: database},
+		LoginFailures:   &MemLoginFailureRepository{Database: database},
+		AuditLog:        &MemAuditLogRepository{Database: database},
+		Settings:        &MemSettingsRepository{Database: database},
+	}
+}
@@ -0,0 +1,155 @@
+package memstorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+func TestWithinTx(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("failure")
+
+	tests := []struct {
+		name  string
+		err   error
+		panic bool
+		kept  bool
+	}{
+		{name: "commit", err: nil, kept: true},
+		{name: "rollback", err: failure, kept: false},
+		{name: "panic", panic: true, kept: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database := &MemDatabase{}
+			if err := database.Open(ctx, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			store := NewStorage(database)
+			post := entity.Post{Title: "title"}
+
+			err := func() (err error) {
+				defer func() {
+					if recovered := recover(); (recovered != nil) != test.panic {
+						t.Fatalf("got panic %v", recovered)
+					}
+				}()
+
+				return store.Database.WithinTx(ctx, func(tx *storage.Storage) error {
+					if err := tx.Posts.Create(ctx, &post); err != nil {
+						return err
+					}
+
+					// Nested transactions join the running one.
+					return tx.Database.WithinTx(ctx, func(tx *storage.Storage) error {
+						if test.panic {
+							panic(failure)
+						}
+
+						return test.err
+					})
+				})
+			}()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			_, err = store.Posts.Find(ctx, post.ID)
+			if kept := err == nil; kept != test.kept {
+				t.Errorf("got post kept %v, want %v (error %v)", kept, test.kept, err)
+			}
+
+			// The sequence is rolled back along with the rows.
+			var next entity.Post
+			if err := store.Posts.Create(ctx, &next); err != nil {
+				t.Fatal(err)
+			}
+
+			want := post.ID
+			if test.kept {
+				want++
+			}
+
+			if next.ID != want {
+				t.Errorf("got next id %d, want %d", next.ID, want)
+			}
+		})
+	}
+}
+
+// Writes made outside of a transaction wait for it, so they neither see its
+// writes nor get lost when it is rolled back.
+func TestWithinTxConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("failure")
+
+	database := &MemDatabase{}
+	if err := database.Open(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStorage(database)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	transaction := make(chan error)
+
+	go func() {
+		transaction <- store.Database.WithinTx(ctx, func(tx *storage.Storage) error {
+			if err := tx.Posts.Create(ctx, &entity.Post{Title: "uncommitted"}); err != nil {
+				return err
+			}
+
+			close(started)
+			<-release
+			return failure
+		})
+	}()
+
+	<-started
+
+	type result struct {
+		posts int
+		err   error
+	}
+	outside := make(chan result)
+
+	go func() {
+		page, err := store.Posts.FindAll(ctx, storage.FilterQuery{Limit: 20, Listing: storage.PostListing})
+		if err != nil {
+			outside <- result{err: err}
+			return
+		}
+
+		outside <- result{posts: len(page.Items), err: store.Sessions.Create(ctx, &entity.Session{ID: "session", UserID: 1})}
+	}()
+
+	select {
+	case <-outside:
+		t.Fatal("got outside access while the transaction runs")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	if err := <-transaction; !errors.Is(err, failure) {
+		t.Fatalf("got error %v, want %v", err, failure)
+	}
+
+	got := <-outside
+	if got.err != nil {
+		t.Fatal(got.err)
+	}
+
+	if got.posts != 0 {
+		t.Errorf("got %d posts, want the uncommitted post hidden", got.posts)
+	}
+
+	if _, err := store.Sessions.Find(ctx, "session"); err != nil {
+		t.Errorf("got session lost: %v", err)
+	}
+}
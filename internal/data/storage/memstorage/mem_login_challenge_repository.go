@@ -0,0 +1,63 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemLoginChallengeRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemLoginChallengeRepository) Create(ctx context.Context, challenge *entity.LoginChallenge) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.users[challenge.UserID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	if _, ok := database.tables.challenges[challenge.ID]; ok {
+		return storage.ErrorDuplicate
+	}
+
+	challenge.CreatedAt = now()
+	database.tables.challenges[challenge.ID] = *challenge
+
+	return nil
+}
+
+func (repository *MemLoginChallengeRepository) Find(ctx context.Context, id string) (*entity.LoginChallenge, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.challenges, id)
+}
+
+func (repository *MemLoginChallengeRepository) Delete(ctx context.Context, id string) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return deleteOne(repository.Database.tables.challenges, id)
+}
+
+func (repository *MemLoginChallengeRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	maps.DeleteFunc(repository.Database.tables.challenges, func(_ string, challenge entity.LoginChallenge) bool {
+		if challenge.Expired(now) {
+			count++
+			return true
+		}
+
+		return false
+	})
+
+	return count, nil
+}
@@ -0,0 +1,81 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+type MemLoginFailureRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemLoginFailureRepository) Find(ctx context.Context, id string) (*entity.LoginFailure, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.loginFailures, id)
+}
+
+func (repository *MemLoginFailureRepository) Increment(ctx context.Context, id string, since time.Time) (*entity.LoginFailure, error) {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	failure, ok := database.tables.loginFailures[id]
+	if !ok {
+		failure = entity.LoginFailure{ID: id}
+	}
+
+	if failure.LastFailedAt.Before(since) {
+		failure.Failures = 0
+	}
+
+	failure.Failures++
+	failure.LastFailedAt = now()
+	database.tables.loginFailures[id] = failure
+
+	return &failure, nil
+}
+
+func (repository *MemLoginFailureRepository) Lock(ctx context.Context, id string, until time.Time) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	failure, err := findOne(database.tables.loginFailures, id)
+	if err != nil {
+		return err
+	}
+
+	failure.LockedUntil = &until
+	database.tables.loginFailures[id] = *failure
+
+	return nil
+}
+
+func (repository *MemLoginFailureRepository) Delete(ctx context.Context, id string) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return deleteOne(repository.Database.tables.loginFailures, id)
+}
+
+func (repository *MemLoginFailureRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	current := now()
+	maps.DeleteFunc(repository.Database.tables.loginFailures, func(_ string, failure entity.LoginFailure) bool {
+		if failure.LastFailedAt.Before(before) && !failure.Locked(current) {
+			count++
+			return true
+		}
+
+		return false
+	})
+
+	return count, nil
+}
@@ -0,0 +1,70 @@
+package memstorage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemModerationRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemModerationRepository) FindQueue(ctx context.Context, filter storage.FilterQuery, queue storage.ModerationQuery) (*storage.Page[entity.ModerationItem], error) {
+	var list []*entity.ModerationItem
+	database := repository.Database
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+
+	if queue.Includes(storage.ModerationPosts) {
+		for _, post := range database.tables.posts {
+			if post.Verified || post.DeletedAt != nil || post.Status == entity.PostDraft {
+				continue
+			}
+
+			list = append(list, &entity.ModerationItem{
+				Type:      "post",
+				ID:        post.ID,
+				PostID:    post.ID,
+				UserID:    post.UserID,
+				Title:     post.Title,
+				Content:   post.Content,
+				CreatedAt: post.CreatedAt,
+			})
+		}
+	}
+
+	if queue.Includes(storage.ModerationComments) {
+		for _, comment := range database.tables.comments {
+			if comment.Verified || comment.DeletedAt != nil || database.tables.posts[comment.PostID].Status == entity.PostDraft {
+				continue
+			}
+
+			list = append(list, &entity.ModerationItem{
+				Type:      "comment",
+				ID:        comment.ID,
+				PostID:    comment.PostID,
+				UserID:    comment.UserID,
+				Content:   comment.Content,
+				CreatedAt: comment.CreatedAt,
+			})
+		}
+	}
+
+	slices.SortFunc(list, func(a, b *entity.ModerationItem) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		if c := strings.Compare(b.Type, a.Type); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return storage.NewPage(paginate(list, filter), filter, nil), nil
+}
@@ -0,0 +1,91 @@
+package memstorage
+
+import (
+	"context"
+	"testing"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+// Approved content that is edited under pre-moderation is stored unverified,
+// and has to show up in the queue again.
+func TestEditAfterApproval(t *testing.T) {
+	ctx := context.Background()
+
+	database := &MemDatabase{}
+	if err := database.Open(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStorage(database)
+
+	user := &entity.User{RoleID: 1, Email: "ann@example.com", Username: "ann"}
+	if err := store.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	post := &entity.Post{UserID: user.ID, Title: "title", Content: "content", Status: entity.PostPublished}
+	if err := store.Posts.Create(ctx, post); err != nil {
+		t.Fatal(err)
+	}
+
+	comment := &entity.Comment{UserID: user.ID, PostID: post.ID, Content: "comment"}
+	if err := store.Comments.Create(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Posts.Approve(ctx, post.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Comments.Approve(ctx, comment.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	queue := storage.ModerationQuery{Types: []string{storage.ModerationPosts, storage.ModerationComments}}
+	filter := storage.FilterQuery{Limit: 20, Listing: storage.ModerationListing}
+
+	page, err := store.Moderation.FindQueue(ctx, filter, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Items) != 0 {
+		t.Fatalf("got %d queued items after approval", len(page.Items))
+	}
+
+	post, err = store.Posts.Find(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post.Content, post.Verified = "edited", false
+	if err := store.Posts.Update(ctx, post); err != nil {
+		t.Fatal(err)
+	}
+
+	comment, err = store.Comments.Find(ctx, comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment.Content, comment.Verified = "edited", false
+	if err := store.Comments.Update(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err = store.Moderation.FindQueue(ctx, filter, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Items) != 2 {
+		t.Fatalf("got %d queued items after the edit", len(page.Items))
+	}
+
+	for _, item := range page.Items {
+		if item.Content != "edited" {
+			t.Errorf("got queued %+v", item)
+		}
+	}
+}
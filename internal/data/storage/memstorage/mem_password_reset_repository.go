@@ -0,0 +1,69 @@
+package memstorage
+
+import (
+	"context"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+// Mirrors the expired_at default of the password_resets table.
+const passwordResetDuration = time.Hour
+
+type MemPasswordResetRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemPasswordResetRepository) Create(ctx context.Context, reset *entity.PasswordReset) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.users[reset.UserID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	if _, ok := database.tables.passwordResets[reset.ID]; ok {
+		return storage.ErrorDuplicate
+	}
+
+	reset.CreatedAt = now()
+	reset.ExpiredAt = reset.CreatedAt.Add(passwordResetDuration)
+	database.tables.passwordResets[reset.ID] = *reset
+
+	return nil
+}
+
+func (repository *MemPasswordResetRepository) Find(ctx context.Context, id string) (*entity.PasswordReset, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.passwordResets, id)
+}
+
+func (repository *MemPasswordResetRepository) Delete(ctx context.Context, id string) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return deleteOne(repository.Database.tables.passwordResets, id)
+}
+
+func (repository *MemPasswordResetRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	deleted := 0
+	for key, reset := range database.tables.passwordResets {
+		if reset.UserID == id {
+			delete(database.tables.passwordResets, key)
+			deleted++
+		}
+	}
+
+	if deleted == 0 {
+		return storage.ErrorNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemPermissionRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemPermissionRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Permission], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findPage(repository.Database.tables.permissions, filter, nil, nil), nil
+}
+
+// Must be called while holding the lock.
+func (database *MemDatabase) findPermissionByName(name string) (*entity.Permission, error) {
+	for _, permission := range database.tables.permissions {
+		if permission.Name == name {
+			return &permission, nil
+		}
+	}
+
+	return nil, storage.ErrorNotFound
+}
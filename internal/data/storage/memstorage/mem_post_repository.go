@@ -0,0 +1,250 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemPostRepository struct {
+	Database *MemDatabase
+}
+
+var postColumns = columns[entity.Post]{
+	position: storage.PostPosition,
+	compare: map[string]func(a, b *entity.Post) int{
+		"updated_at": func(a, b *entity.Post) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+		"title":      func(a, b *entity.Post) int { return strings.Compare(a.Title, b.Title) },
+	},
+	verified:  func(post *entity.Post) bool { return post.Verified },
+	userID:    func(post *entity.Post) int64 { return post.UserID },
+	status:    func(post *entity.Post) string { return post.Status },
+	deletedAt: func(post *entity.Post) *time.Time { return post.DeletedAt },
+}
+
+func (repository *MemPostRepository) Create(ctx context.Context, post *entity.Post) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	post.ID = database.nextID("posts")
+	post.Verified = false
+	post.CreatedAt = now()
+	post.UpdatedAt = post.CreatedAt
+	if post.PublishAt == nil && post.Status == entity.PostPublished {
+		post.PublishAt = &post.CreatedAt
+	}
+	database.tables.posts[post.ID] = *post
+
+	return nil
+}
+
+func (repository *MemPostRepository) Find(ctx context.Context, id int64) (*entity.Post, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	post, err := findOne(repository.Database.tables.posts, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.DeletedAt != nil {
+		return nil, storage.ErrorNotFound
+	}
+
+	post.Tags = repository.Database.postTagSlugs(post.ID)
+	return post, nil
+}
+
+func (repository *MemPostRepository) FindAllByUserID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Post], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	page := findPage(repository.Database.tables.posts, filter, func(post *entity.Post) bool {
+		return post.UserID == id
+	}, &postColumns)
+
+	return repository.withTags(page), nil
+}
+
+func (repository *MemPostRepository) FindAllByTagID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Post], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	page := findPage(repository.Database.tables.posts, filter, func(post *entity.Post) bool {
+		_, ok := repository.Database.tables.postTags[postTag{postID: post.ID, tagID: id}]
+		return ok
+	}, &postColumns)
+
+	return repository.withTags(page), nil
+}
+
+func (repository *MemPostRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Post], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	page := findPage(repository.Database.tables.posts, filter, nil, &postColumns)
+	return repository.withTags(page), nil
+}
+
+// Must be called while holding the lock.
+func (repository *MemPostRepository) withTags(page *storage.Page[entity.Post]) *storage.Page[entity.Post] {
+	for _, post := range page.Items {
+		post.Tags = repository.Database.postTagSlugs(post.ID)
+	}
+
+	return page
+}
+
+func (repository *MemPostRepository) Update(ctx context.Context, post *entity.Post) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	stored, ok := database.tables.posts[post.ID]
+	if !ok {
+		return storage.ErrorNotFound
+	}
+
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.Status = post.Status
+	stored.PublishAt = post.PublishAt
+	stored.Verified = post.Verified
+	stored.UpdatedAt = now()
+	if stored.PublishAt == nil && stored.Status == entity.PostPublished {
+		stored.PublishAt = &stored.UpdatedAt
+	}
+	database.tables.posts[post.ID] = stored
+
+	post.PublishAt = stored.PublishAt
+	post.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// Publishes scheduled posts whose publish_at time is not after now.
+func (repository *MemPostRepository) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	for id, post := range database.tables.posts {
+		if post.Status != entity.PostScheduled || post.PublishAt.After(now) || post.DeletedAt != nil {
+			continue
+		}
+
+		post.Status = entity.PostPublished
+		post.UpdatedAt = now.Truncate(time.Second)
+		database.tables.posts[id] = post
+		count++
+	}
+
+	return count, nil
+}
+
+func (repository *MemPostRepository) Approve(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	post, ok := database.tables.posts[id]
+	if !ok || post.DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	post.Verified = true
+	database.tables.posts[id] = post
+
+	return nil
+}
+
+func (repository *MemPostRepository) SoftDelete(ctx context.Context, id int64, by int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	post, ok := database.tables.posts[id]
+	if !ok || post.DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	deletedAt := deletedNow()
+	post.DeletedAt = &deletedAt
+	post.DeletedBy = &by
+	database.tables.posts[id] = post
+
+	database.moveComments(func(comment *entity.Comment) bool {
+		return comment.PostID == id
+	}, nil, post.DeletedAt, &by)
+
+	return nil
+}
+
+func (repository *MemPostRepository) Restore(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	post, ok := database.tables.posts[id]
+	if !ok || post.DeletedAt == nil || database.tables.users[post.UserID].DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	deletedAt := post.DeletedAt
+	post.DeletedAt = nil
+	post.DeletedBy = nil
+	database.tables.posts[id] = post
+
+	database.moveComments(func(comment *entity.Comment) bool {
+		return comment.PostID == id
+	}, deletedAt, nil, nil)
+
+	return nil
+}
+
+func (repository *MemPostRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	for id, post := range database.tables.posts {
+		if post.DeletedAt != nil && post.DeletedAt.Before(before) {
+			database.deletePost(id)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (repository *MemPostRepository) Delete(ctx context.Context, id int64) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return repository.Database.deletePost(id)
+}
+
+// Mirrors the cascading foreign keys of the posts table.
+// Must be called while holding the write lock.
+func (database *MemDatabase) deletePost(id int64) error {
+	if err := deleteOne(database.tables.posts, id); err != nil {
+		return err
+	}
+
+	database.deleteComments(func(comment *entity.Comment) bool {
+		return comment.PostID == id
+	})
+	maps.DeleteFunc(database.tables.postTags, func(key postTag, _ struct{}) bool {
+		return key.postID == id
+	})
+	maps.DeleteFunc(database.tables.revisions, func(_ int64, revision entity.PostRevision) bool {
+		return revision.PostID == id
+	})
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemPostRevisionRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemPostRevisionRepository) Create(ctx context.Context, revision *entity.PostRevision) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.posts[revision.PostID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	revision.ID = database.nextID("post_revisions")
+	revision.CreatedAt = now()
+	database.tables.revisions[revision.ID] = *revision
+
+	return nil
+}
+
+func (repository *MemPostRevisionRepository) Find(ctx context.Context, id int64) (*entity.PostRevision, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.revisions, id)
+}
+
+func (repository *MemPostRevisionRepository) FindAllByPostID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.PostRevision], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findPage(repository.Database.tables.revisions, filter, func(revision *entity.PostRevision) bool {
+		return revision.PostID == id
+	}, nil), nil
+}
@@ -0,0 +1,59 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemRecoveryCodeRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemRecoveryCodeRepository) Replace(ctx context.Context, id int64, codes []string) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.users[id]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	database.deleteRecoveryCodes(id)
+
+	created := now()
+	for _, code := range codes {
+		database.tables.recoveryCodes[code] = entity.RecoveryCode{ID: code, UserID: id, CreatedAt: created}
+	}
+
+	return nil
+}
+
+func (repository *MemRecoveryCodeRepository) Use(ctx context.Context, id int64, code string) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if stored, ok := database.tables.recoveryCodes[code]; !ok || stored.UserID != id {
+		return storage.ErrorNotFound
+	}
+
+	delete(database.tables.recoveryCodes, code)
+	return nil
+}
+
+func (repository *MemRecoveryCodeRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	repository.Database.deleteRecoveryCodes(id)
+	return nil
+}
+
+// Must be called while holding the write lock.
+func (database *MemDatabase) deleteRecoveryCodes(id int64) {
+	maps.DeleteFunc(database.tables.recoveryCodes, func(_ string, code entity.RecoveryCode) bool {
+		return code.UserID == id
+	})
+}
@@ -0,0 +1,61 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemRefreshTokenRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.sessions[token.SessionID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	if _, ok := database.tables.refreshTokens[token.ID]; ok {
+		return storage.ErrorDuplicate
+	}
+
+	token.CreatedAt = now()
+	database.tables.refreshTokens[token.ID] = *token
+
+	return nil
+}
+
+func (repository *MemRefreshTokenRepository) Find(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.refreshTokens, id)
+}
+
+func (repository *MemRefreshTokenRepository) Use(ctx context.Context, id string) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	token, ok := database.tables.refreshTokens[id]
+	if !ok || token.UsedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	used := now()
+	token.UsedAt = &used
+	database.tables.refreshTokens[id] = token
+
+	return nil
+}
+
+func (repository *MemRefreshTokenRepository) Delete(ctx context.Context, id string) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return deleteOne(repository.Database.tables.refreshTokens, id)
+}
@@ -0,0 +1,209 @@
+package memstorage
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+	"web_blog/internal/data/storage"
+)
+
+// Must be called while holding the write lock.
+func (database *MemDatabase) nextID(table string) int64 {
+	database.tables.sequences[table]++
+	return database.tables.sequences[table]
+}
+
+// Mirrors timestamp(0) columns, which are stored with second precision.
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// Mirrors the deleted_at columns, which are stored with microsecond precision.
+func deletedNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func findOne[K comparable, T any](table map[K]T, id K) (*T, error) {
+	element, ok := table[id]
+	if !ok {
+		return nil, storage.ErrorNotFound
+	}
+
+	return &element, nil
+}
+
+// Accessors for the fields a listing can be sorted and filtered by. Rows are
+// positioned by created_at and id, compare holds the other sort fields.
+type columns[T any] struct {
+	position  func(*T) storage.Cursor
+	compare   map[string]func(a, b *T) int
+	verified  func(*T) bool
+	userID    func(*T) int64
+	status    func(*T) string
+	deletedAt func(*T) *time.Time
+}
+
+// Mirrors the ordering and paging of the pgx repositories. Rows with columns
+// are filtered, then either sorted and paged by offset or ordered newest first
+// and paged by cursor. Other rows are ordered by key and paged by offset only.
+func findPage[K cmp.Ordered, T any](
+	table map[K]T,
+	filter storage.FilterQuery,
+	where func(*T) bool,
+	columns *columns[T],
+) *storage.Page[T] {
+	var list []*T
+	var position func(*T) storage.Cursor
+
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		element := table[key]
+		if where != nil && !where(&element) {
+			continue
+		}
+
+		if columns != nil && !columns.match(&element, filter) {
+			continue
+		}
+
+		list = append(list, &element)
+	}
+
+	if columns != nil {
+		position = columns.position
+
+		if len(filter.Sort) > 0 {
+			columns.sort(list, filter)
+		} else {
+			list = seek(list, filter.Cursor, position)
+		}
+	}
+
+	return storage.NewPage(paginate(list, filter), filter, position)
+}
+
+func (columns *columns[T]) match(element *T, filter storage.FilterQuery) bool {
+	if filter.Listing.Trashable && (columns.deletedAt(element) != nil) != filter.Deleted {
+		return false
+	}
+
+	for _, name := range filter.Filters() {
+		if !slices.Contains(filter.Listing.Filterable, name) {
+			continue
+		}
+
+		switch name {
+		case "since":
+			if columns.position(element).CreatedAt.Before(*filter.Since) {
+				return false
+			}
+		case "until":
+			if !columns.position(element).CreatedAt.Before(*filter.Until) {
+				return false
+			}
+		case "verified":
+			if columns.verified(element) != *filter.Verified {
+				return false
+			}
+		case "user_id":
+			if columns.userID(element) != *filter.UserID {
+				return false
+			}
+		case "status":
+			if columns.status(element) != *filter.Status {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Sorts by the sort fields of the filter, with id descending as the tiebreak.
+func (columns *columns[T]) sort(list []*T, filter storage.FilterQuery) {
+	slices.SortStableFunc(list, func(a, b *T) int {
+		for _, field := range filter.Sort {
+			column, descending := storage.SortField(field)
+			if !slices.Contains(filter.Listing.Sortable, column) {
+				continue
+			}
+
+			var c int
+			switch column {
+			case "id":
+				c = cmp.Compare(columns.position(a).ID, columns.position(b).ID)
+			case "created_at":
+				c = columns.position(a).CreatedAt.Compare(columns.position(b).CreatedAt)
+			default:
+				if compare, ok := columns.compare[column]; ok {
+					c = compare(a, b)
+				}
+			}
+
+			if descending {
+				c = -c
+			}
+
+			if c != 0 {
+				return c
+			}
+		}
+
+		return cmp.Compare(columns.position(b).ID, columns.position(a).ID)
+	})
+}
+
+// Orders rows newest first, or oldest first going backward, and drops the rows
+// up to and including the cursor position.
+func seek[T any](list []*T, cursor *storage.Cursor, position func(*T) storage.Cursor) []*T {
+	backward := cursor != nil && cursor.Backward
+
+	slices.SortFunc(list, func(a, b *T) int {
+		if backward {
+			return position(a).Compare(position(b))
+		}
+
+		return position(b).Compare(position(a))
+	})
+
+	if cursor == nil {
+		return list
+	}
+
+	return slices.DeleteFunc(list, func(element *T) bool {
+		c := position(element).Compare(*cursor)
+		return (backward && c <= 0) || (!backward && c >= 0)
+	})
+}
+
+// Fetches one row more than the limit, like the pgx repositories.
+func paginate[T any](list []*T, filter storage.FilterQuery) []*T {
+	if filter.Offset >= len(list) {
+		return nil
+	}
+
+	list = list[filter.Offset:]
+	if filter.Limit+1 < len(list) {
+		list = list[:filter.Limit+1]
+	}
+
+	return list
+}
+
+// Compares nullable timestamps, like IS NOT DISTINCT FROM.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+func deleteOne[K comparable, T any](table map[K]T, id K) error {
+	if _, ok := table[id]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	delete(table, id)
+	return nil
+}
@@ -0,0 +1,131 @@
+package memstorage
+
+import (
+	"slices"
+	"testing"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+// Same cases as the pgx query builder tests, the ids are the rows the
+// builder's ORDER BY and WHERE select from the same table.
+func TestFindPage(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+	deleted := t2.Add(time.Hour)
+	user := int64(2)
+	verified := true
+
+	posts := map[int64]entity.Post{
+		1: {ID: 1, UserID: 1, Title: "b", Verified: true, CreatedAt: t0},
+		2: {ID: 2, UserID: 2, Title: "a", Verified: true, CreatedAt: t0},
+		3: {ID: 3, UserID: 2, Title: "b", Verified: false, CreatedAt: t1},
+		4: {ID: 4, UserID: 1, Title: "a", Verified: true, CreatedAt: t2},
+		5: {ID: 5, UserID: 2, Title: "c", Verified: true, CreatedAt: t2, DeletedAt: &deleted},
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.FilterQuery
+		want   []int64
+		more   bool
+	}{
+		{
+			name:   "newest first",
+			filter: storage.FilterQuery{Limit: 10},
+			want:   []int64{4, 3, 2, 1},
+		},
+		{
+			name:   "limit",
+			filter: storage.FilterQuery{Limit: 2},
+			want:   []int64{4, 3},
+			more:   true,
+		},
+		{
+			name:   "offset",
+			filter: storage.FilterQuery{Limit: 2, Offset: 2},
+			want:   []int64{2, 1},
+		},
+		{
+			name:   "cursor",
+			filter: storage.FilterQuery{Limit: 10, Cursor: &storage.Cursor{CreatedAt: t1, ID: 3}},
+			want:   []int64{2, 1},
+		},
+		{
+			name:   "cursor on tie",
+			filter: storage.FilterQuery{Limit: 10, Cursor: &storage.Cursor{CreatedAt: t0, ID: 2}},
+			want:   []int64{1},
+		},
+		{
+			name:   "cursor backward",
+			filter: storage.FilterQuery{Limit: 10, Cursor: &storage.Cursor{CreatedAt: t0, ID: 2, Backward: true}},
+			want:   []int64{4, 3},
+		},
+		{
+			name:   "cursor backward limit",
+			filter: storage.FilterQuery{Limit: 1, Cursor: &storage.Cursor{CreatedAt: t0, ID: 1, Backward: true}},
+			want:   []int64{2},
+			more:   true,
+		},
+		{
+			name:   "sort with id tiebreak",
+			filter: storage.FilterQuery{Limit: 10, Sort: []string{"title"}},
+			want:   []int64{4, 2, 3, 1},
+		},
+		{
+			name:   "sort descending then id",
+			filter: storage.FilterQuery{Limit: 10, Sort: []string{"-created_at", "id"}},
+			want:   []int64{4, 3, 1, 2},
+		},
+		{
+			name:   "sort by unknown field",
+			filter: storage.FilterQuery{Limit: 10, Sort: []string{"password"}},
+			want:   []int64{4, 3, 2, 1},
+		},
+		{
+			name:   "filters",
+			filter: storage.FilterQuery{Limit: 10, Since: &t0, Until: &t2, UserID: &user, Verified: &verified},
+			want:   []int64{2},
+		},
+		{
+			name:   "trash",
+			filter: storage.FilterQuery{Limit: 10, Deleted: true},
+			want:   []int64{5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.filter.Listing = storage.PostListing
+			page := findPage(posts, test.filter, nil, &postColumns)
+
+			var ids []int64
+			for _, post := range page.Items {
+				ids = append(ids, post.ID)
+			}
+
+			if !slices.Equal(ids, test.want) {
+				t.Errorf("got %v, want %v", ids, test.want)
+			}
+
+			if page.HasMore != test.more {
+				t.Errorf("got has more %v, want %v", page.HasMore, test.more)
+			}
+		})
+	}
+}
+
+func TestFindPageWithoutColumns(t *testing.T) {
+	roles := map[int64]entity.Role{
+		3: {ID: 3},
+		1: {ID: 1},
+		2: {ID: 2},
+	}
+
+	page := findPage(roles, storage.FilterQuery{Limit: 1, Offset: 1}, nil, nil)
+	if len(page.Items) != 1 || page.Items[0].ID != 2 || !page.HasMore {
+		t.Errorf("got %+v, want role 2 with more", page)
+	}
+}
@@ -0,0 +1,154 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemRoleRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemRoleRepository) Create(ctx context.Context, role *entity.Role) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	for _, other := range database.tables.roles {
+		if other.Name == role.Name {
+			return storage.ErrorDuplicate
+		}
+	}
+
+	role.ID = database.nextID("roles")
+	stored := *role
+	stored.Permissions = nil
+	database.tables.roles[role.ID] = stored
+
+	return nil
+}
+
+func (repository *MemRoleRepository) Find(ctx context.Context, id int64) (*entity.Role, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	role, err := findOne(repository.Database.tables.roles, id)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions = repository.Database.rolePermissionNames(role.ID)
+	return role, nil
+}
+
+func (repository *MemRoleRepository) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	for _, role := range repository.Database.tables.roles {
+		if role.Name == name {
+			role.Permissions = repository.Database.rolePermissionNames(role.ID)
+			return &role, nil
+		}
+	}
+
+	return nil, storage.ErrorNotFound
+}
+
+func (repository *MemRoleRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Role], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	page := findPage(repository.Database.tables.roles, filter, nil, nil)
+	for _, role := range page.Items {
+		role.Permissions = repository.Database.rolePermissionNames(role.ID)
+	}
+
+	return page, nil
+}
+
+func (repository *MemRoleRepository) Update(ctx context.Context, role *entity.Role) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.roles[role.ID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	for _, other := range database.tables.roles {
+		if other.ID != role.ID && other.Name == role.Name {
+			return storage.ErrorDuplicate
+		}
+	}
+
+	stored := *role
+	stored.Permissions = nil
+	database.tables.roles[role.ID] = stored
+	return nil
+}
+
+func (repository *MemRoleRepository) Delete(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if err := deleteOne(database.tables.roles, id); err != nil {
+		return err
+	}
+
+	maps.DeleteFunc(database.tables.rolePerms, func(key rolePermission, _ struct{}) bool {
+		return key.roleID == id
+	})
+
+	return nil
+}
+
+// Replaces the permissions of a role with role.Permissions. Unknown
+// permissions are not found.
+func (repository *MemRoleRepository) SetPermissions(ctx context.Context, role *entity.Role) error {
+	var ids []int64
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.roles[role.ID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	for _, name := range role.Permissions {
+		permission, err := database.findPermissionByName(name)
+		if err != nil {
+			return err
+		}
+
+		ids = append(ids, permission.ID)
+	}
+
+	maps.DeleteFunc(database.tables.rolePerms, func(key rolePermission, _ struct{}) bool {
+		return key.roleID == role.ID
+	})
+
+	for _, id := range ids {
+		database.tables.rolePerms[rolePermission{roleID: role.ID, permissionID: id}] = struct{}{}
+	}
+
+	role.Permissions = database.rolePermissionNames(role.ID)
+	return nil
+}
+
+// Must be called while holding the lock.
+func (database *MemDatabase) rolePermissionNames(id int64) []string {
+	names := []string{}
+	for key := range database.tables.rolePerms {
+		if key.roleID == id {
+			names = append(names, database.tables.permissions[key.permissionID].Name)
+		}
+	}
+
+	slices.Sort(names)
+	return names
+}
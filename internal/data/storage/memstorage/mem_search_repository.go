@@ -0,0 +1,156 @@
+package memstorage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+// Words shown around the first match of a snippet, like ts_headline MaxWords.
+const snippetWords = 35
+
+type MemSearchRepository struct {
+	Database *MemDatabase
+}
+
+// Approximates the pgx search without stemming. Rows match when every term is
+// a prefix of one of their words, and rank by how often the terms occur, with
+// post titles counting twice. Only published posts and their comments are
+// searched.
+func (repository *MemSearchRepository) Search(ctx context.Context, filter storage.FilterQuery, search storage.SearchQuery) (*storage.Page[entity.SearchResult], error) {
+	var list []*entity.SearchResult
+	database := repository.Database
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+
+	terms := words(search.Text)
+	if len(terms) == 0 {
+		return storage.NewPage(list, filter, nil), nil
+	}
+
+	if search.Includes(storage.SearchPosts) {
+		for _, post := range database.tables.posts {
+			if post.Status != entity.PostPublished || post.DeletedAt != nil || (search.Approved && !post.Verified) {
+				continue
+			}
+
+			rank := 2*matches(words(post.Title), terms) + matches(words(post.Content), terms)
+			if !containsAll(words(post.Title+" "+post.Content), terms) {
+				continue
+			}
+
+			list = append(list, &entity.SearchResult{
+				Type:      "post",
+				ID:        post.ID,
+				PostID:    post.ID,
+				UserID:    post.UserID,
+				Title:     post.Title,
+				Snippet:   snippet(post.Content, terms),
+				Rank:      float64(rank),
+				CreatedAt: post.CreatedAt,
+			})
+		}
+	}
+
+	if search.Includes(storage.SearchComments) {
+		for _, comment := range database.tables.comments {
+			post := database.tables.posts[comment.PostID]
+			if post.Status != entity.PostPublished || post.DeletedAt != nil || comment.DeletedAt != nil {
+				continue
+			}
+
+			if search.Approved && (!post.Verified || !comment.Verified) {
+				continue
+			}
+
+			rank := matches(words(comment.Content), terms)
+			if !containsAll(words(comment.Content), terms) {
+				continue
+			}
+
+			list = append(list, &entity.SearchResult{
+				Type:      "comment",
+				ID:        comment.ID,
+				PostID:    comment.PostID,
+				UserID:    comment.UserID,
+				Snippet:   snippet(comment.Content, terms),
+				Rank:      float64(rank),
+				CreatedAt: comment.CreatedAt,
+			})
+		}
+	}
+
+	slices.SortFunc(list, func(a, b *entity.SearchResult) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	return storage.NewPage(paginate(list, filter), filter, nil), nil
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func matchesTerm(word string, terms []string) bool {
+	return slices.ContainsFunc(terms, func(term string) bool {
+		return strings.HasPrefix(word, term)
+	})
+}
+
+func matches(words []string, terms []string) int {
+	count := 0
+	for _, word := range words {
+		if matchesTerm(word, terms) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func containsAll(words []string, terms []string) bool {
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Highlights matched words with <b> tags, starting a few words before the
+// first match.
+func snippet(text string, terms []string) string {
+	fields := strings.Fields(text)
+	start := 0
+
+	for i, field := range fields {
+		if matches(words(field), terms) > 0 {
+			start = max(i-5, 0)
+			break
+		}
+	}
+
+	fields = fields[start:min(start+snippetWords, len(fields))]
+	for i, field := range fields {
+		if matches(words(field), terms) > 0 {
+			fields[i] = "<b>" + field + "</b>"
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
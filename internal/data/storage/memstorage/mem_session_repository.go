@@ -0,0 +1,178 @@
+package memstorage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemSessionRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemSessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.sessions[session.ID]; ok {
+		return storage.ErrorDuplicate
+	}
+
+	session.CreatedAt = now()
+	session.LastSeenAt = session.CreatedAt
+	session.Current = false
+	database.tables.sessions[session.ID] = *session
+	return nil
+}
+
+func (repository *MemSessionRepository) Find(ctx context.Context, id string) (*entity.Session, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.sessions, id)
+}
+
+func (repository *MemSessionRepository) FindWithUser(
+	ctx context.Context,
+	id string,
+) (*entity.Session, *entity.User, error) {
+	database := repository.Database
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+
+	session, ok := database.tables.sessions[id]
+	if !ok {
+		return nil, nil, storage.ErrorNotFound
+	}
+
+	user, ok := database.tables.users[session.UserID]
+	if !ok || user.DeletedAt != nil || database.isBanned(user.ID) {
+		return nil, nil, storage.ErrorNotFound
+	}
+
+	if user.Role, ok = database.tables.roles[user.RoleID]; !ok {
+		return nil, nil, storage.ErrorNotFound
+	}
+
+	return &session, &user, nil
+}
+
+// Expired sessions are left out, the most recently used first.
+func (repository *MemSessionRepository) FindAllByUserID(ctx context.Context, id int64) ([]*entity.Session, error) {
+	var list []*entity.Session
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	for _, session := range repository.Database.tables.sessions {
+		if session.UserID == id && !session.Expired(time.Now()) {
+			list = append(list, &session)
+		}
+	}
+
+	slices.SortFunc(list, func(a, b *entity.Session) int {
+		if c := b.LastSeenAt.Compare(a.LastSeenAt); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return list, nil
+}
+
+func (repository *MemSessionRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Session], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findPage(repository.Database.tables.sessions, filter, nil, nil), nil
+}
+
+// Only the expiry and the last use of a session can change.
+func (repository *MemSessionRepository) Update(ctx context.Context, session *entity.Session) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	stored, ok := database.tables.sessions[session.ID]
+	if !ok {
+		return storage.ErrorNotFound
+	}
+
+	stored.ExpiredAt = session.ExpiredAt.Truncate(time.Second)
+	stored.LastSeenAt = session.LastSeenAt.Truncate(time.Second)
+	database.tables.sessions[session.ID] = stored
+
+	return nil
+}
+
+func (repository *MemSessionRepository) Delete(ctx context.Context, id string) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	if repository.Database.deleteSessions(func(session *entity.Session) bool { return session.ID == id }) == 0 {
+		return storage.ErrorNotFound
+	}
+
+	return nil
+}
+
+func (repository *MemSessionRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	repository.Database.deleteSessions(func(session *entity.Session) bool {
+		return session.UserID == id
+	})
+
+	return nil
+}
+
+// Logs the user out of all sessions but the one with the given id.
+func (repository *MemSessionRepository) DeleteAllByUserIDExcept(ctx context.Context, id int64, except string) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	repository.Database.deleteSessions(func(session *entity.Session) bool {
+		return session.UserID == id && session.ID != except
+	})
+
+	return nil
+}
+
+func (repository *MemSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return repository.Database.deleteSessions(func(session *entity.Session) bool {
+		return session.Expired(now)
+	}), nil
+}
+
+// Mirrors the cascading foreign keys of the sessions table, returns the
+// number of deleted sessions.
+// Must be called while holding the write lock.
+func (database *MemDatabase) deleteSessions(where func(*entity.Session) bool) int64 {
+	var count int64
+	deleted := map[string]bool{}
+
+	maps.DeleteFunc(database.tables.sessions, func(id string, session entity.Session) bool {
+		if where(&session) {
+			deleted[id] = true
+			count++
+			return true
+		}
+
+		return false
+	})
+
+	maps.DeleteFunc(database.tables.refreshTokens, func(_ string, token entity.RefreshToken) bool {
+		return deleted[token.SessionID]
+	})
+
+	return count
+}
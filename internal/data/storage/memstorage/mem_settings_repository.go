@@ -0,0 +1,29 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type MemSettingsRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemSettingsRepository) Find(ctx context.Context) (*entity.Settings, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	settings := repository.Database.tables.settings
+	return &settings, nil
+}
+
+func (repository *MemSettingsRepository) Update(ctx context.Context, settings *entity.Settings) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	settings.UpdatedAt = now()
+	database.tables.settings = *settings
+
+	return nil
+}
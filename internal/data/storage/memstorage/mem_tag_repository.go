@@ -0,0 +1,216 @@
+package memstorage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemTagRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemTagRepository) Create(ctx context.Context, tag *entity.Tag) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	tag.Slug = entity.Slugify(tag.Name)
+	if _, err := database.findTagBySlug(tag.Slug); err == nil {
+		return storage.ErrorDuplicate
+	}
+
+	tag.ID = database.nextID("tags")
+	tag.PostCount = 0
+	database.tables.tags[tag.ID] = *tag
+
+	return nil
+}
+
+func (repository *MemTagRepository) Find(ctx context.Context, id int64) (*entity.Tag, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	tag, err := findOne(repository.Database.tables.tags, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.PostCount = repository.Database.tagPostCount(tag.ID)
+	return tag, nil
+}
+
+func (repository *MemTagRepository) FindBySlug(ctx context.Context, slug string) (*entity.Tag, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	tag, err := repository.Database.findTagBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.PostCount = repository.Database.tagPostCount(tag.ID)
+	return tag, nil
+}
+
+// Most used tags first.
+func (repository *MemTagRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Tag], error) {
+	var list []*entity.Tag
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	for _, tag := range repository.Database.tables.tags {
+		tag.PostCount = repository.Database.tagPostCount(tag.ID)
+		list = append(list, &tag)
+	}
+
+	slices.SortFunc(list, func(a, b *entity.Tag) int {
+		if c := cmp.Compare(b.PostCount, a.PostCount); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Slug, b.Slug)
+	})
+
+	return storage.NewPage(paginate(list, filter), filter, nil), nil
+}
+
+// Renames the tag, which also changes its slug.
+func (repository *MemTagRepository) Update(ctx context.Context, tag *entity.Tag) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	stored, ok := database.tables.tags[tag.ID]
+	if !ok {
+		return storage.ErrorNotFound
+	}
+
+	tag.Slug = entity.Slugify(tag.Name)
+	if other, err := database.findTagBySlug(tag.Slug); err == nil && other.ID != tag.ID {
+		return storage.ErrorDuplicate
+	}
+
+	stored.Name = tag.Name
+	stored.Slug = tag.Slug
+	database.tables.tags[tag.ID] = stored
+
+	return nil
+}
+
+func (repository *MemTagRepository) Delete(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	return database.deleteTag(id)
+}
+
+// Replaces the tags of a post with post.Tags, read as tag names. Missing tags
+// are created, and post.Tags is set to the slugs of the post's tags.
+func (repository *MemTagRepository) SetPostTags(ctx context.Context, post *entity.Post) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	maps.DeleteFunc(database.tables.postTags, func(key postTag, _ struct{}) bool {
+		return key.postID == post.ID
+	})
+
+	for _, name := range post.Tags {
+		slug := entity.Slugify(name)
+		if slug == "" {
+			continue
+		}
+
+		tag, err := database.findTagBySlug(slug)
+		if err != nil {
+			tag = &entity.Tag{ID: database.nextID("tags"), Name: name, Slug: slug}
+			database.tables.tags[tag.ID] = *tag
+		}
+
+		database.tables.postTags[postTag{postID: post.ID, tagID: tag.ID}] = struct{}{}
+	}
+
+	post.Tags = database.postTagSlugs(post.ID)
+	return nil
+}
+
+// Moves the posts of a tag over to another tag and deletes it.
+func (repository *MemTagRepository) Merge(ctx context.Context, from *entity.Tag, into *entity.Tag) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.tags[into.ID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	for key := range database.tables.postTags {
+		if key.tagID == from.ID {
+			database.tables.postTags[postTag{postID: key.postID, tagID: into.ID}] = struct{}{}
+		}
+	}
+
+	if err := database.deleteTag(from.ID); err != nil {
+		return err
+	}
+
+	into.PostCount = database.tagPostCount(into.ID)
+	return nil
+}
+
+// Must be called while holding the lock.
+func (database *MemDatabase) findTagBySlug(slug string) (*entity.Tag, error) {
+	for _, tag := range database.tables.tags {
+		if tag.Slug == slug {
+			return &tag, nil
+		}
+	}
+
+	return nil, storage.ErrorNotFound
+}
+
+// Only published posts are counted.
+// Must be called while holding the lock.
+func (database *MemDatabase) tagPostCount(id int64) int64 {
+	var count int64
+	for key := range database.tables.postTags {
+		post := database.tables.posts[key.postID]
+		if key.tagID == id && post.Status == entity.PostPublished && post.DeletedAt == nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Must be called while holding the lock.
+func (database *MemDatabase) postTagSlugs(id int64) []string {
+	slugs := []string{}
+	for key := range database.tables.postTags {
+		if key.postID == id {
+			slugs = append(slugs, database.tables.tags[key.tagID].Slug)
+		}
+	}
+
+	slices.Sort(slugs)
+	return slugs
+}
+
+// Must be called while holding the write lock.
+func (database *MemDatabase) deleteTag(id int64) error {
+	if err := deleteOne(database.tables.tags, id); err != nil {
+		return err
+	}
+
+	maps.DeleteFunc(database.tables.postTags, func(key postTag, _ struct{}) bool {
+		return key.tagID == id
+	})
+
+	return nil
+}
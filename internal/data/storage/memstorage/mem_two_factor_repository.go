@@ -0,0 +1,99 @@
+package memstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type MemTwoFactorRepository struct {
+	Database *MemDatabase
+}
+
+// Replaces a pending secret of the user, enabled ones are duplicates.
+func (repository *MemTwoFactorRepository) Create(ctx context.Context, twoFactor *entity.TwoFactor) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if _, ok := database.tables.users[twoFactor.UserID]; !ok {
+		return storage.ErrorNotFound
+	}
+
+	if stored, ok := database.tables.twoFactors[twoFactor.UserID]; ok && stored.Enabled() {
+		return storage.ErrorDuplicate
+	}
+
+	twoFactor.LastStep = 0
+	twoFactor.EnabledAt = nil
+	twoFactor.CreatedAt = now()
+	database.tables.twoFactors[twoFactor.UserID] = *twoFactor
+
+	return nil
+}
+
+func (repository *MemTwoFactorRepository) Find(ctx context.Context, id int64) (*entity.TwoFactor, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.twoFactors, id)
+}
+
+// Enables the pending secret of the user, with the step of the code that
+// confirmed it as used.
+func (repository *MemTwoFactorRepository) Enable(ctx context.Context, id int64, step int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	twoFactor, ok := database.tables.twoFactors[id]
+	if !ok || twoFactor.Enabled() {
+		return storage.ErrorNotFound
+	}
+
+	enabled := now()
+	twoFactor.EnabledAt = &enabled
+	twoFactor.LastStep = step
+	database.tables.twoFactors[id] = twoFactor
+	database.setTwoFactor(id, true)
+
+	return nil
+}
+
+func (repository *MemTwoFactorRepository) Use(ctx context.Context, id int64, step int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	twoFactor, ok := database.tables.twoFactors[id]
+	if !ok || !twoFactor.Enabled() || twoFactor.LastStep >= step {
+		return storage.ErrorNotFound
+	}
+
+	twoFactor.LastStep = step
+	database.tables.twoFactors[id] = twoFactor
+
+	return nil
+}
+
+func (repository *MemTwoFactorRepository) Delete(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if err := deleteOne(database.tables.twoFactors, id); err != nil {
+		return err
+	}
+
+	database.setTwoFactor(id, false)
+	return nil
+}
+
+// Mirrors the two factor column of users, which is read from two_factors.
+// Must be called while holding the write lock.
+func (database *MemDatabase) setTwoFactor(id int64, enabled bool) {
+	if user, ok := database.tables.users[id]; ok {
+		user.TwoFactor = enabled
+		database.tables.users[id] = user
+	}
+}
@@ -0,0 +1,116 @@
+package memstorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+func twoFactorStorage(t *testing.T) (storage.Storage, *entity.User, *entity.User) {
+	ctx := context.Background()
+
+	database := &MemDatabase{}
+	if err := database.Open(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStorage(database)
+
+	ann := &entity.User{RoleID: 1, Email: "ann@example.com", Username: "ann"}
+	bob := &entity.User{RoleID: 1, Email: "bob@example.com", Username: "bob"}
+	for _, user := range []*entity.User{ann, bob} {
+		if err := store.Users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store, ann, bob
+}
+
+// Codes are only accepted for steps after the last used one, so a code can
+// not be replayed within the skew window.
+func TestTwoFactorUse(t *testing.T) {
+	ctx := context.Background()
+	store, ann, _ := twoFactorStorage(t)
+
+	if err := store.TwoFactors.Create(ctx, &entity.TwoFactor{UserID: ann.ID, Secret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.TwoFactors.Use(ctx, ann.ID, 10); !errors.Is(err, storage.ErrorNotFound) {
+		t.Errorf("got %v using a pending secret", err)
+	}
+
+	if err := store.TwoFactors.Enable(ctx, ann.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		step int64
+		want error
+	}{
+		{name: "confirming step", step: 10, want: storage.ErrorNotFound},
+		{name: "next step", step: 11, want: nil},
+		{name: "replayed step", step: 11, want: storage.ErrorNotFound},
+		{name: "earlier step", step: 10, want: storage.ErrorNotFound},
+		{name: "later step", step: 12, want: nil},
+	}
+
+	for _, test := range tests {
+		if err := store.TwoFactors.Use(ctx, ann.ID, test.step); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	if err := store.TwoFactors.Create(ctx, &entity.TwoFactor{UserID: ann.ID, Secret: "other"}); !errors.Is(err, storage.ErrorDuplicate) {
+		t.Errorf("got %v replacing an enabled secret", err)
+	}
+}
+
+func TestRecoveryCodeUse(t *testing.T) {
+	ctx := context.Background()
+	store, ann, bob := twoFactorStorage(t)
+
+	if err := store.RecoveryCodes.Replace(ctx, ann.ID, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RecoveryCodes.Replace(ctx, bob.ID, []string{"c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		user int64
+		code string
+		want error
+	}{
+		{name: "unused code", user: ann.ID, code: "a", want: nil},
+		{name: "used code", user: ann.ID, code: "a", want: storage.ErrorNotFound},
+		{name: "code of another user", user: ann.ID, code: "c", want: storage.ErrorNotFound},
+		{name: "unknown code", user: ann.ID, code: "z", want: storage.ErrorNotFound},
+		{name: "other unused code", user: ann.ID, code: "b", want: nil},
+	}
+
+	for _, test := range tests {
+		if err := store.RecoveryCodes.Use(ctx, test.user, test.code); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Replacing the codes invalidates the previous ones.
+	if err := store.RecoveryCodes.Replace(ctx, bob.ID, []string{"d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RecoveryCodes.Use(ctx, bob.ID, "c"); !errors.Is(err, storage.ErrorNotFound) {
+		t.Errorf("got %v using a replaced code", err)
+	}
+
+	if err := store.RecoveryCodes.Use(ctx, bob.ID, "d"); err != nil {
+		t.Errorf("got %v using a new code", err)
+	}
+}
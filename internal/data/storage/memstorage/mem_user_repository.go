@@ -0,0 +1,328 @@
+package memstorage
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+
+	"github.com/google/uuid"
+)
+
+type MemUserRepository struct {
+	Database *MemDatabase
+}
+
+var userColumns = columns[entity.User]{
+	position: storage.UserPosition,
+	compare: map[string]func(a, b *entity.User) int{
+		"updated_at": func(a, b *entity.User) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+		"username":   func(a, b *entity.User) int { return strings.Compare(a.Username, b.Username) },
+		"email":      func(a, b *entity.User) int { return strings.Compare(a.Email, b.Email) },
+	},
+	verified:  func(user *entity.User) bool { return user.Verified },
+	deletedAt: func(user *entity.User) *time.Time { return user.DeletedAt },
+}
+
+func (repository *MemUserRepository) Verify(ctx context.Context, id uuid.UUID, user *entity.User) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	verification, ok := database.tables.verifications[id]
+	if !ok || !verification.ExpiredAt.After(now()) || verification.UsedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	found, ok := database.tables.users[verification.UserID]
+	if !ok || found.DeletedAt != nil || (found.Verified && verification.Email == nil) {
+		return storage.ErrorNotFound
+	}
+
+	if verification.Email != nil {
+		found.Email = *verification.Email
+		if repository.isDuplicate(&found) {
+			return storage.ErrorDuplicate
+		}
+	}
+
+	found.Verified = true
+	found.UpdatedAt = now()
+	database.tables.users[found.ID] = found
+
+	*user = found
+	user.Password = entity.Password{}
+	return nil
+}
+
+func (repository *MemUserRepository) Create(ctx context.Context, user *entity.User) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if repository.isDuplicate(user) {
+		return storage.ErrorDuplicate
+	}
+
+	user.ID = database.nextID("users")
+	user.Verified = false
+	user.TwoFactor = false
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+
+	stored := *user
+	stored.Role = entity.Role{}
+	stored.Ban = nil
+	stored.Password = entity.Password{Hash: user.Password.Hash}
+	database.tables.users[user.ID] = stored
+
+	return nil
+}
+
+func (repository *MemUserRepository) Find(ctx context.Context, id int64) (*entity.User, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	user, err := findOne(repository.Database.tables.users, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeletedAt != nil {
+		return nil, storage.ErrorNotFound
+	}
+
+	return user, nil
+}
+
+func (repository *MemUserRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	for _, user := range repository.Database.tables.users {
+		if strings.EqualFold(user.Email, email) && user.DeletedAt == nil {
+			return &user, nil
+		}
+	}
+
+	return nil, storage.ErrorNotFound
+}
+
+func (repository *MemUserRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.User], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findPage(repository.Database.tables.users, filter, nil, &userColumns), nil
+}
+
+func (repository *MemUserRepository) Update(ctx context.Context, user *entity.User) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	stored, ok := database.tables.users[user.ID]
+	if !ok {
+		return storage.ErrorNotFound
+	}
+
+	if repository.isDuplicate(user) {
+		return storage.ErrorDuplicate
+	}
+
+	stored.RoleID = user.RoleID
+	stored.Email = user.Email
+	stored.Username = user.Username
+	stored.Password = entity.Password{Hash: user.Password.Hash}
+	stored.Bio = user.Bio
+	stored.AvatarURL = user.AvatarURL
+	stored.Verified = user.Verified
+	stored.UpdatedAt = now()
+	database.tables.users[user.ID] = stored
+
+	user.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (repository *MemUserRepository) SoftDelete(ctx context.Context, id int64, by int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	user, ok := database.tables.users[id]
+	if !ok || user.DeletedAt != nil {
+		return storage.ErrorNotFound
+	}
+
+	deletedAt := deletedNow()
+	user.DeletedAt = &deletedAt
+	user.DeletedBy = &by
+	database.tables.users[id] = user
+
+	database.moveUserContent(id, nil, user.DeletedAt, &by)
+	return nil
+}
+
+func (repository *MemUserRepository) Restore(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	user, ok := database.tables.users[id]
+	if !ok || user.DeletedAt == nil {
+		return storage.ErrorNotFound
+	}
+
+	deletedAt := user.DeletedAt
+	user.DeletedAt = nil
+	user.DeletedBy = nil
+	database.tables.users[id] = user
+
+	database.moveUserContent(id, deletedAt, nil, nil)
+	return nil
+}
+
+func (repository *MemUserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	for id, user := range database.tables.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(before) {
+			database.deleteUser(id)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Mirrors moveUserContent of the pgx repositories.
+// Must be called while holding the write lock.
+func (database *MemDatabase) moveUserContent(id int64, from *time.Time, to *time.Time, by *int64) {
+	for key, post := range database.tables.posts {
+		if post.UserID == id && sameTime(post.DeletedAt, from) {
+			post.DeletedAt = to
+			post.DeletedBy = by
+			database.tables.posts[key] = post
+		}
+	}
+
+	database.moveComments(func(comment *entity.Comment) bool {
+		return comment.UserID == id || database.tables.posts[comment.PostID].UserID == id
+	}, from, to, by)
+}
+
+func (repository *MemUserRepository) Delete(ctx context.Context, id int64) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return repository.Database.deleteUser(id)
+}
+
+// Mirrors the cascading foreign keys of the users table.
+// Must be called while holding the write lock.
+func (database *MemDatabase) deleteUser(id int64) error {
+	if err := deleteOne(database.tables.users, id); err != nil {
+		return err
+	}
+
+	for _, post := range database.tables.posts {
+		if post.UserID == id {
+			database.deletePost(post.ID)
+		}
+	}
+
+	database.deleteComments(func(comment *entity.Comment) bool {
+		return comment.UserID == id
+	})
+	database.deleteSessions(func(session *entity.Session) bool {
+		return session.UserID == id
+	})
+	maps.DeleteFunc(database.tables.passwordResets, func(_ string, reset entity.PasswordReset) bool {
+		return reset.UserID == id
+	})
+	maps.DeleteFunc(database.tables.challenges, func(_ string, challenge entity.LoginChallenge) bool {
+		return challenge.UserID == id
+	})
+	database.deleteRecoveryCodes(id)
+	delete(database.tables.twoFactors, id)
+	maps.DeleteFunc(database.tables.verifications, func(_ uuid.UUID, verification entity.Verification) bool {
+		return verification.UserID == id
+	})
+	delete(database.tables.bans, id)
+
+	for key, ban := range database.tables.bans {
+		if ban.BannedBy != nil && *ban.BannedBy == id {
+			ban.BannedBy = nil
+			database.tables.bans[key] = ban
+		}
+	}
+
+	for key, entry := range database.tables.auditLog {
+		if entry.ActorID != nil && *entry.ActorID == id {
+			entry.ActorID = nil
+		}
+
+		if entry.UserID != nil && *entry.UserID == id {
+			entry.UserID = nil
+		}
+
+		database.tables.auditLog[key] = entry
+	}
+
+	for key, post := range database.tables.posts {
+		if post.DeletedBy != nil && *post.DeletedBy == id {
+			post.DeletedBy = nil
+			database.tables.posts[key] = post
+		}
+	}
+
+	for key, comment := range database.tables.comments {
+		if comment.DeletedBy != nil && *comment.DeletedBy == id {
+			comment.DeletedBy = nil
+			database.tables.comments[key] = comment
+		}
+	}
+
+	for key, user := range database.tables.users {
+		if user.DeletedBy != nil && *user.DeletedBy == id {
+			user.DeletedBy = nil
+			database.tables.users[key] = user
+		}
+	}
+
+	for key, revision := range database.tables.revisions {
+		if revision.EditorID != nil && *revision.EditorID == id {
+			revision.EditorID = nil
+			database.tables.revisions[key] = revision
+		}
+	}
+
+	for key, edit := range database.tables.commentEdits {
+		if edit.EditorID != nil && *edit.EditorID == id {
+			edit.EditorID = nil
+			database.tables.commentEdits[key] = edit
+		}
+	}
+
+	return nil
+}
+
+// Emails are compared case insensitively, like the citext column.
+// Must be called while holding the lock.
+func (repository *MemUserRepository) isDuplicate(user *entity.User) bool {
+	for _, other := range repository.Database.tables.users {
+		if other.ID == user.ID {
+			continue
+		}
+
+		if strings.EqualFold(other.Email, user.Email) || other.Username == user.Username {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,178 @@
+package memstorage
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+
+	"github.com/google/uuid"
+)
+
+// Mirrors the expired_at default of the verifications table.
+const verificationDuration = time.Hour * 24
+
+type MemVerificationRepository struct {
+	Database *MemDatabase
+}
+
+func (repository *MemVerificationRepository) Create(ctx context.Context, verification *entity.Verification) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	verification.UUID = uuid.New()
+	verification.CreatedAt = now()
+	verification.ExpiredAt = verification.CreatedAt.Add(verificationDuration)
+	verification.UsedAt = nil
+	database.tables.verifications[verification.UUID] = *verification
+
+	return nil
+}
+
+func (repository *MemVerificationRepository) Find(ctx context.Context, id uuid.UUID) (*entity.Verification, error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return findOne(repository.Database.tables.verifications, id)
+}
+
+// Used verifications are included.
+func (repository *MemVerificationRepository) FindLatestByUserID(ctx context.Context, id int64) (*entity.Verification, error) {
+	var latest *entity.Verification
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	for _, verification := range repository.Database.tables.verifications {
+		if verification.UserID == id && (latest == nil || verification.CreatedAt.After(latest.CreatedAt)) {
+			latest = &verification
+		}
+	}
+
+	if latest == nil {
+		return nil, storage.ErrorNotFound
+	}
+
+	return latest, nil
+}
+
+func (repository *MemVerificationRepository) FindAllByUserID(
+	ctx context.Context,
+	filter storage.FilterQuery,
+	id int64,
+) (*storage.Page[entity.Verification], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return repository.findAll(filter, func(verification *entity.Verification) bool {
+		return verification.UserID == id
+	}), nil
+}
+
+func (repository *MemVerificationRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Verification], error) {
+	repository.Database.mutex.RLock()
+	defer repository.Database.mutex.RUnlock()
+
+	return repository.findAll(filter, nil), nil
+}
+
+func (repository *MemVerificationRepository) Update(ctx context.Context, verification *entity.Verification) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	stored, ok := database.tables.verifications[verification.UUID]
+	if !ok {
+		return storage.ErrorNotFound
+	}
+
+	stored.ExpiredAt = verification.ExpiredAt
+	database.tables.verifications[verification.UUID] = stored
+
+	return nil
+}
+
+// Marks the pending verifications of the user as used.
+func (repository *MemVerificationRepository) UseAllByUserID(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	used := now()
+	for key, verification := range database.tables.verifications {
+		if verification.UserID == id && verification.UsedAt == nil {
+			verification.UsedAt = &used
+			database.tables.verifications[key] = verification
+		}
+	}
+
+	return nil
+}
+
+func (repository *MemVerificationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	repository.Database.mutex.Lock()
+	defer repository.Database.mutex.Unlock()
+
+	return deleteOne(repository.Database.tables.verifications, id)
+}
+
+func (repository *MemVerificationRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	deleted := 0
+	for key, verification := range database.tables.verifications {
+		if verification.UserID == id {
+			delete(database.tables.verifications, key)
+			deleted++
+		}
+	}
+
+	if deleted == 0 {
+		return storage.ErrorNotFound
+	}
+
+	return nil
+}
+
+func (repository *MemVerificationRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	database := repository.Database
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	for key, verification := range database.tables.verifications {
+		if !verification.ExpiredAt.After(now) {
+			delete(database.tables.verifications, key)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// UUID keys are not ordered, so verifications are sorted by their bytes.
+// Must be called while holding the lock.
+func (repository *MemVerificationRepository) findAll(
+	filter storage.FilterQuery,
+	where func(*entity.Verification) bool,
+) *storage.Page[entity.Verification] {
+	var list []*entity.Verification
+
+	for _, verification := range repository.Database.tables.verifications {
+		if where != nil && !where(&verification) {
+			continue
+		}
+
+		list = append(list, &verification)
+	}
+
+	slices.SortFunc(list, func(a, b *entity.Verification) int {
+		return bytes.Compare(a.UUID[:], b.UUID[:])
+	})
+
+	return storage.NewPage(paginate(list, filter), filter, nil)
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"net/http"
+	"slices"
+)
+
+const (
+	ModerationPosts    string = "posts"
+	ModerationComments string = "comments"
+)
+
+// The moderation queue is ordered oldest first and paged by offset.
+var ModerationListing = Listing{}
+
+type ModerationQuery struct {
+	Types []string `json:"type" validate:"required,dive,oneof=posts comments"`
+}
+
+func (moderationQuery *ModerationQuery) Parse(r *http.Request) error {
+	moderationQuery.Types = parseTypes(r.URL.Query().Get("type"), ModerationPosts, ModerationComments)
+	return nil
+}
+
+func (moderationQuery *ModerationQuery) Includes(kind string) bool {
+	return slices.Contains(moderationQuery.Types, kind)
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+var ErrorInvalidCursor = errors.New("cursor is invalid")
+
+// Position of a row in the default newest first ordering, encoded into an
+// opaque string for clients. Backward cursors page towards newer rows.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+func (cursor Cursor) Encode() string {
+	bytes, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// Orders cursors by created_at and id.
+func (cursor Cursor) Compare(other Cursor) int {
+	if c := cursor.CreatedAt.Compare(other.CreatedAt); c != 0 {
+		return c
+	}
+
+	switch {
+	case cursor.ID < other.ID:
+		return -1
+	case cursor.ID > other.ID:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func DecodeCursor(text string) (*Cursor, error) {
+	var cursor Cursor
+	var bytes []byte
+	var err error
+
+	if bytes, err = base64.RawURLEncoding.DecodeString(text); err != nil {
+		return nil, ErrorInvalidCursor
+	}
+
+	if err = json.Unmarshal(bytes, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, ErrorInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+type Page[T any] struct {
+	Items      []*T
+	NextCursor string
+	PrevCursor string
+	HasMore    bool
+}
+
+// Builds a page from rows fetched in query order, with at most one row more
+// than the filter limit. Backward pages are fetched oldest first and are
+// reversed here. Without a position function, or when sorted, the page has no
+// cursors.
+func NewPage[T any](items []*T, filter FilterQuery, position func(*T) Cursor) *Page[T] {
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	page := &Page[T]{Items: []*T{}}
+
+	if len(items) > filter.Limit {
+		page.HasMore = true
+		items = items[:filter.Limit]
+	}
+
+	if backward {
+		items = slices.Clone(items)
+		slices.Reverse(items)
+	}
+
+	page.Items = append(page.Items, items...)
+	if position == nil || len(filter.Sort) > 0 || len(items) == 0 {
+		return page
+	}
+
+	first, last := position(items[0]), position(items[len(items)-1])
+	first.Backward = true
+
+	if page.HasMore || backward {
+		page.NextCursor = last.Encode()
+	}
+
+	if (page.HasMore && backward) || (!backward && (filter.Cursor != nil || filter.Offset > 0)) {
+		page.PrevCursor = first.Encode()
+	}
+
+	return page
+}
+
+func UserPosition(user *entity.User) Cursor {
+	return Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
+}
+
+func PostPosition(post *entity.Post) Cursor {
+	return Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+func CommentPosition(comment *entity.Comment) Cursor {
+	return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
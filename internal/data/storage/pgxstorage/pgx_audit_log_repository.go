@@ -0,0 +1,29 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxAuditLogRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxAuditLogRepository) Create(ctx context.Context, entry *entity.AuditEntry) error {
+	sql := `
+		INSERT INTO audit_log (action, actor_id, user_id, ip, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return query(
+		databasePayload[entity.AuditEntry]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{entry.Action, entry.ActorID, entry.UserID, entry.IP, entry.Details},
+			scan: func(_ *entity.AuditEntry) []any {
+				return []any{&entry.ID, &entry.CreatedAt}
+			},
+		},
+	)
+}
@@ -0,0 +1,64 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxBanRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxBanRepository) Create(ctx context.Context, ban *entity.Ban) error {
+	sql := `
+		INSERT INTO user_bans (user_id, banned_by, reason, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET banned_by = EXCLUDED.banned_by, reason = EXCLUDED.reason,
+			expires_at = EXCLUDED.expires_at, created_at = NOW()
+		RETURNING created_at
+	`
+	return query(
+		databasePayload[entity.Ban]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{ban.UserID, ban.BannedBy, ban.Reason, ban.ExpiresAt},
+			scan: func(_ *entity.Ban) []any {
+				return []any{&ban.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxBanRepository) Find(ctx context.Context, id int64) (*entity.Ban, error) {
+	sql := `
+		SELECT user_id, banned_by, reason, expires_at, created_at FROM user_bans WHERE user_id = $1
+	`
+	return queryOne(
+		databasePayload[entity.Ban]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(ban *entity.Ban) []any {
+				return []any{&ban.UserID, &ban.BannedBy, &ban.Reason, &ban.ExpiresAt, &ban.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxBanRepository) Delete(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM user_bans WHERE user_id = $1
+	`
+	return execute(
+		databasePayload[entity.Ban]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
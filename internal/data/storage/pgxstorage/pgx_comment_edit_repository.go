@@ -0,0 +1,50 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type PgxCommentEditRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxCommentEditRepository) Create(ctx context.Context, edit *entity.CommentEdit) error {
+	sql := `
+		INSERT INTO comment_edits (comment_id, editor_id, content)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	return query(
+		databasePayload[entity.CommentEdit]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{edit.CommentID, edit.EditorID, edit.Content},
+			scan: func(_ *entity.CommentEdit) []any {
+				return []any{&edit.ID, &edit.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxCommentEditRepository) FindAllByCommentID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.CommentEdit], error) {
+	sql, args := newSelectQuery("comment_edits", `SELECT id, comment_id, editor_id, content, created_at FROM comment_edits`).
+		where("comment_edits.comment_id = ?", id).
+		paginateBy("comment_edits.id", filter)
+
+	return queryPage(
+		databasePayload[entity.CommentEdit]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: func(e *entity.CommentEdit) []any {
+				return []any{&e.ID, &e.CommentID, &e.EditorID, &e.Content, &e.CreatedAt}
+			},
+		},
+		filter,
+		nil,
+	)
+}
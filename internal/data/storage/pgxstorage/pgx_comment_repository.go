@@ -2,28 +2,51 @@ package pgxstorage
 
 import (
 	"context"
+	"time"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
-
-	"github.com/jackc/pgx"
 )
 
 type PgxCommentRepository struct {
 	Database *PgxDatabase
 }
 
-func (repository *PgxCommentRepository) Create(ctx context.Context, tx *pgx.Tx, comment *entity.Comment) error {
+// Selected explicitly, as the table also holds the search vector.
+const commentColumns = `comments.id, comments.user_id, comments.post_id, comments.parent_id, comments.depth,
+	comments.content, comments.verified,
+	(SELECT COUNT(*) FROM comments AS replies WHERE replies.parent_id = comments.id AND replies.deleted_at IS NULL),
+	comments.edited_at, comments.created_at, comments.updated_at, comments.deleted_at, comments.deleted_by`
+
+func scanComment(comment *entity.Comment) []any {
+	return []any{
+		&comment.ID,
+		&comment.UserID,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Content,
+		&comment.Verified,
+		&comment.ReplyCount,
+		&comment.EditedAt,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.DeletedAt,
+		&comment.DeletedBy,
+	}
+}
+
+func (repository *PgxCommentRepository) Create(ctx context.Context, comment *entity.Comment) error {
 	sql := `
-		INSERT INTO comments (user_id, post_id, content) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO comments (user_id, post_id, parent_id, depth, content)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, verified, created_at, updated_at
 	`
 	return query(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{comment.UserID, comment.PostID, comment.Content},
+			args: []any{comment.UserID, comment.PostID, comment.ParentID, comment.Depth, comment.Content},
 			scan: func(_ *entity.Comment) []any {
 				return []any{&comment.ID, &comment.Verified, &comment.CreatedAt, &comment.UpdatedAt}
 			},
@@ -31,111 +54,295 @@ func (repository *PgxCommentRepository) Create(ctx context.Context, tx *pgx.Tx,
 	)
 }
 
-func (repository *PgxCommentRepository) Find(ctx context.Context, tx *pgx.Tx, id int64) (*entity.Comment, error) {
+func (repository *PgxCommentRepository) Find(ctx context.Context, id int64) (*entity.Comment, error) {
 	sql := `
-		SELECT * FROM comments WHERE id = $1
+		SELECT ` + commentColumns + ` FROM comments WHERE id = $1 AND deleted_at IS NULL
 	`
 	return queryOne(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
-			scan: func(c *entity.Comment) []any {
-				return []any{&c.ID, &c.UserID, &c.PostID, &c.Content, &c.Verified, &c.CreatedAt, &c.UpdatedAt}
-			},
+			scan: scanComment,
+		},
+	)
+}
+
+func (repository *PgxCommentRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Comment], error) {
+	sql, args := newSelectQuery("comments", `SELECT `+commentColumns+` FROM comments`).
+		paginate(filter)
+
+	return queryPage(
+		databasePayload[entity.Comment]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanComment,
+		},
+		filter,
+		storage.CommentPosition,
+	)
+}
+
+func (repository *PgxCommentRepository) FindAllByUserID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Comment], error) {
+	sql, args := newSelectQuery("comments", `SELECT `+commentColumns+` FROM comments`).
+		where("comments.user_id = ?", id).
+		paginate(filter)
+
+	return queryPage(
+		databasePayload[entity.Comment]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanComment,
+		},
+		filter,
+		storage.CommentPosition,
+	)
+}
+
+func (repository *PgxCommentRepository) FindAllByPostID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Comment], error) {
+	sql, args := newSelectQuery("comments", `SELECT `+commentColumns+` FROM comments`).
+		where("comments.post_id = ?", id).
+		paginate(filter)
+
+	return queryPage(
+		databasePayload[entity.Comment]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanComment,
 		},
+		filter,
+		storage.CommentPosition,
 	)
 }
 
-func (repository *PgxCommentRepository) FindAll(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery) ([]*entity.Comment, error) {
+func (repository *PgxCommentRepository) FindAllByParentID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Comment], error) {
+	sql, args := newSelectQuery("comments", `SELECT `+commentColumns+` FROM comments`).
+		where("comments.parent_id = ?", id).
+		paginate(filter)
+
+	return queryPage(
+		databasePayload[entity.Comment]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanComment,
+		},
+		filter,
+		storage.CommentPosition,
+	)
+}
+
+func (repository *PgxCommentRepository) FindThreadsByPostID(ctx context.Context, filter storage.FilterQuery, id int64, replies int) (*storage.Page[entity.Comment], error) {
+	sql, args := newSelectQuery("comments", `SELECT `+commentColumns+` FROM comments`).
+		where("comments.post_id = ?", id).
+		where("comments.parent_id IS NULL").
+		paginate(filter)
+
+	return repository.findThreads(ctx, sql, args, filter, replies)
+}
+
+func (repository *PgxCommentRepository) FindThreadsByParentID(ctx context.Context, filter storage.FilterQuery, id int64, replies int) (*storage.Page[entity.Comment], error) {
+	sql, args := newSelectQuery("comments", `SELECT `+commentColumns+` FROM comments`).
+		where("comments.parent_id = ?", id).
+		paginate(filter)
+
+	return repository.findThreads(ctx, sql, args, filter, replies)
+}
+
+// Pages the thread roots, then walks down from them with a recursive query
+// that takes at most the given number of replies of every comment.
+func (repository *PgxCommentRepository) findThreads(ctx context.Context, sql string, args []any, filter storage.FilterQuery, limit int) (*storage.Page[entity.Comment], error) {
+	var page *storage.Page[entity.Comment]
+	var replies []*entity.Comment
+	var err error
+
+	if page, err = queryPage(
+		databasePayload[entity.Comment]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanComment,
+		},
+		filter,
+		storage.CommentPosition,
+	); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) == 0 || limit <= 0 {
+		return page, nil
+	}
+
+	roots := make([]int64, len(page.Items))
+	for i, comment := range page.Items {
+		roots[i] = comment.ID
+	}
+
+	// Replies are ordered by depth, so parents are nested before their own
+	// replies are.
+	sql = `
+		WITH RECURSIVE thread (id) AS (
+			SELECT unnest($1::bigint[])
+			UNION ALL
+			SELECT replies.id FROM thread CROSS JOIN LATERAL (
+				SELECT comments.id FROM comments
+				WHERE comments.parent_id = thread.id AND comments.deleted_at IS NULL
+				AND ($3::boolean IS NULL OR comments.verified = $3)
+				ORDER BY comments.created_at, comments.id
+				LIMIT $2
+			) AS replies
+		)
+		SELECT ` + commentColumns + ` FROM thread JOIN comments ON comments.id = thread.id
+		WHERE NOT (thread.id = ANY($1))
+		ORDER BY comments.depth, comments.created_at, comments.id
+	`
+	if replies, err = queryAll(
+		databasePayload[entity.Comment]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{roots, limit, filter.Verified},
+			scan: scanComment,
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	storage.NestReplies(page.Items, replies)
+	return page, nil
+}
+
+func (repository *PgxCommentRepository) Approve(ctx context.Context, id int64) error {
 	sql := `
-		SELECT * FROM comments
-		LIMIT $1
-		OFFSET $2
+		UPDATE comments SET verified = true WHERE id = $1 AND deleted_at IS NULL
 	`
-	return queryAll(
+	return execute(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{filter.Limit, filter.Offset},
-			scan: func(c *entity.Comment) []any {
-				return []any{&c.ID, &c.UserID, &c.PostID, &c.Content, &c.Verified, &c.CreatedAt, &c.UpdatedAt}
-			},
+			args: []any{id},
+			scan: nil,
 		},
 	)
 }
 
-func (repository *PgxCommentRepository) FindAllByUserID(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery, id int64) ([]*entity.Comment, error) {
+func (repository *PgxCommentRepository) SoftDelete(ctx context.Context, id int64, by int64) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		now := time.Now()
+		return moveComments(ctx, database, "comments.id = $1", id, nil, &now, &by)
+	})
+}
+
+// Replies can only be restored while their parent is not deleted.
+func (repository *PgxCommentRepository) Restore(ctx context.Context, id int64) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		sql := `
+			SELECT comments.deleted_at FROM comments
+			JOIN posts ON posts.id = comments.post_id
+			JOIN users ON users.id = comments.user_id
+			LEFT JOIN comments AS parents ON parents.id = comments.parent_id
+			WHERE comments.id = $1 AND comments.deleted_at IS NOT NULL
+			AND posts.deleted_at IS NULL AND users.deleted_at IS NULL AND parents.deleted_at IS NULL
+		`
+		comment, err := queryOne(
+			databasePayload[entity.Comment]{
+				conn: database.conn(),
+				ctx:  ctx,
+				sql:  sql,
+				args: []any{id},
+				scan: func(comment *entity.Comment) []any {
+					return []any{&comment.DeletedAt}
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return moveComments(ctx, database, "comments.id = $1", id, comment.DeletedAt, nil, nil)
+	})
+}
+
+func (repository *PgxCommentRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
 	sql := `
-		SELECT comments.* FROM comments
-		INNER JOIN users ON comments.user_id = users.id
-		WHERE users.id = $1
-		LIMIT $2
-		OFFSET $3
+		DELETE FROM comments WHERE deleted_at < $1
 	`
-	return queryAll(
+	return executeCount(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{id, filter.Limit, filter.Offset},
-			scan: func(c *entity.Comment) []any {
-				return []any{&c.ID, &c.UserID, &c.PostID, &c.Content, &c.Verified, &c.CreatedAt, &c.UpdatedAt}
-			},
+			args: []any{before},
+			scan: nil,
 		},
 	)
 }
 
-func (repository *PgxCommentRepository) FindAllByPostID(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery, id int64) ([]*entity.Comment, error) {
+// Moves the comments matched by the condition, which can use the comments
+// and posts tables and $1 for the id, along with their replies from the
+// deleted_at from to the deleted_at to. Soft deleting moves live comments,
+// whose deleted_at is nil, and restoring moves them back. Reports not found
+// when no comment is moved.
+func moveComments(ctx context.Context, database *PgxDatabase, condition string, id int64, from *time.Time, to *time.Time, by *int64) error {
 	sql := `
-		SELECT comments.* FROM comments
-		INNER JOIN posts ON comments.post_id = posts.id 
-		WHERE posts.id = $1
-		LIMIT $2
-		OFFSET $3
+		WITH RECURSIVE thread (id) AS (
+			SELECT comments.id FROM comments JOIN posts ON posts.id = comments.post_id
+			WHERE comments.deleted_at IS NOT DISTINCT FROM $2::timestamptz AND (` + condition + `)
+			UNION
+			SELECT comments.id FROM comments JOIN thread ON comments.parent_id = thread.id
+			WHERE comments.deleted_at IS NOT DISTINCT FROM $2::timestamptz
+		)
+		UPDATE comments SET deleted_at = $3::timestamptz, deleted_by = $4::bigint
+		FROM thread WHERE comments.id = thread.id
 	`
-	return queryAll(
+	return execute(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{id, filter.Limit, filter.Offset},
-			scan: func(c *entity.Comment) []any {
-				return []any{&c.ID, &c.UserID, &c.PostID, &c.Content, &c.Verified, &c.CreatedAt, &c.UpdatedAt}
-			},
+			args: []any{id, from, to, by},
+			scan: nil,
 		},
 	)
 }
 
-func (repository *PgxCommentRepository) Update(ctx context.Context, tx *pgx.Tx, comment *entity.Comment) error {
+func (repository *PgxCommentRepository) Update(ctx context.Context, comment *entity.Comment) error {
 	sql := `
-		UPDATE comments 
-		SET content = $1, updated_at = NOW() 
-		WHERE id = $2
-		RETURNING updated_at
+		UPDATE comments
+		SET content = $1, verified = $2, edited_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+		RETURNING edited_at, updated_at
 	`
 	return query(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{comment.Content, comment.ID},
-			scan: func(comment *entity.Comment) []any {
-				return []any{&comment.UpdatedAt}
+			args: []any{comment.Content, comment.Verified, comment.ID},
+			scan: func(_ *entity.Comment) []any {
+				return []any{&comment.EditedAt, &comment.UpdatedAt}
 			},
 		},
 	)
 }
 
-func (repository *PgxCommentRepository) Delete(ctx context.Context, tx *pgx.Tx, id int64) error {
+func (repository *PgxCommentRepository) Delete(ctx context.Context, id int64) error {
 	sql := `
 		DELETE FROM comments WHERE id = $1
 	`
 	return execute(
 		databasePayload[entity.Comment]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
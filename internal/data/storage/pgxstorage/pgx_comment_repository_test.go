@@ -0,0 +1,86 @@
+package pgxstorage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+// Runs against the migrated database configured by the DB_ variables, and is
+// skipped without one. Rows are written within a transaction that is rolled
+// back.
+func TestCommentUpdate(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set")
+	}
+
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+
+	database := &PgxDatabase{}
+	if err := database.Open(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(ctx)
+
+	err := database.WithinTx(ctx, func(store *storage.Storage) error {
+		user := &entity.User{RoleID: 1, Email: "comment-update@example.com", Username: "comment-update"}
+		if err := user.Password.Set("password"); err != nil {
+			return err
+		}
+
+		if err := store.Users.Create(ctx, user); err != nil {
+			return err
+		}
+
+		post := &entity.Post{UserID: user.ID, Title: "title", Content: "content", Status: entity.PostPublished}
+		if err := store.Posts.Create(ctx, post); err != nil {
+			return err
+		}
+
+		comment := &entity.Comment{UserID: user.ID, PostID: post.ID, Content: "before"}
+		if err := store.Comments.Create(ctx, comment); err != nil {
+			return err
+		}
+
+		edit := &entity.CommentEdit{CommentID: comment.ID, EditorID: &user.ID, Content: comment.Content}
+		if err := store.CommentEdits.Create(ctx, edit); err != nil {
+			return err
+		}
+
+		comment.Content = "after"
+		if err := store.Comments.Update(ctx, comment); err != nil {
+			return err
+		}
+
+		if comment.EditedAt == nil || !comment.EditedAt.Equal(comment.UpdatedAt) || comment.UpdatedAt.Before(comment.CreatedAt) {
+			t.Errorf("got edited at %v, updated at %v, created at %v", comment.EditedAt, comment.UpdatedAt, comment.CreatedAt)
+		}
+
+		stored, err := store.Comments.Find(ctx, comment.ID)
+		if err != nil {
+			return err
+		}
+
+		if stored.Content != "after" || stored.EditedAt == nil || !stored.UpdatedAt.Equal(comment.UpdatedAt) {
+			t.Errorf("got stored %+v", stored)
+		}
+
+		page, err := store.CommentEdits.FindAllByCommentID(ctx, storage.FilterQuery{Limit: 20, Listing: storage.CommentEditListing}, comment.ID)
+		if err != nil {
+			return err
+		}
+
+		if len(page.Items) != 1 || page.Items[0].Content != "before" || *page.Items[0].EditorID != user.ID {
+			t.Errorf("got edits %+v", page.Items)
+		}
+
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+}
@@ -3,6 +3,7 @@ package pgxstorage
 import (
 	"context"
 	"time"
+	"web_blog/internal/data/storage"
 	"web_blog/internal/env"
 
 	// Postgress database driver.
@@ -11,38 +12,110 @@ import (
 
 // Postgress database.
 type PgxDatabase struct {
-	Connection *pgx.Conn
-	Config     *pgx.ConnConfig
+	Pool   *pgx.ConnPool
+	Config *pgx.ConnPoolConfig
+
+	// Set on copies handed out by WithinTx.
+	tx *pgx.Tx
 }
 
 func (database *PgxDatabase) Open(ctx context.Context, config any) error {
-	conf, ok := config.(pgx.ConnConfig)
+	conf, ok := config.(pgx.ConnPoolConfig)
 	if !ok {
-		conf = pgx.ConnConfig{
-			Host:     env.GetString("DB_HOST", ""),
-			Port:     uint16(env.GetInt("DB_PORT", 5432)),
-			Database: env.GetString("DB_NAME", ""),
-			User:     env.GetString("DB_USER", ""),
-			Password: env.GetString("DB_PASSWORD", ""),
+		conf = pgx.ConnPoolConfig{
+			ConnConfig: pgx.ConnConfig{
+				Host:     env.GetString("DB_HOST", ""),
+				Port:     uint16(env.GetInt("DB_PORT", 5432)),
+				Database: env.GetString("DB_NAME", ""),
+				User:     env.GetString("DB_USER", ""),
+				Password: env.GetString("DB_PASSWORD", ""),
+			},
+			MaxConnections: env.GetInt("DB_MAX_CONNECTIONS", 10),
+			AcquireTimeout: env.GetDuration("DB_ACQUIRE_TIMEOUT", time.Second*5),
 		}
 	}
 
+	storage.DatabaseQueryTimeout = env.GetDuration("DB_QUERY_TIMEOUT", storage.DatabaseQueryTimeout)
+
 	database.Config = &conf
-	conn, err := pgx.Connect(conf)
+	pool, err := pgx.NewConnPool(conf)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pool.Acquire()
 	if err != nil {
+		pool.Close()
 		return err
 	}
+	defer pool.Release(conn)
 
 	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*500)
 	defer cancel()
 	if err := conn.Ping(ctx); err != nil {
+		pool.Close()
 		return err
 	}
 
-	database.Connection = conn
+	database.Pool = pool
 	return nil
 }
 
 func (database *PgxDatabase) Close(ctx context.Context) error {
-	return database.Connection.Close()
+	database.Pool.Close()
+	return nil
+}
+
+func (database *PgxDatabase) WithinTx(ctx context.Context, fn func(*storage.Storage) error) error {
+	return database.within(ctx, func(database *PgxDatabase) error {
+		store := NewStorage(database)
+		return fn(&store)
+	})
+}
+
+// Runs fn with a copy of the database bound to a transaction, or with the
+// database itself when it already is.
+func (database *PgxDatabase) within(ctx context.Context, fn func(*PgxDatabase) error) error {
+	if database.tx != nil {
+		return fn(database)
+	}
+
+	return withTx(ctx, database.Pool, func(tx *pgx.Tx) error {
+		return fn(&PgxDatabase{Pool: database.Pool, Config: database.Config, tx: tx})
+	})
+}
+
+func (database *PgxDatabase) conn() connection {
+	if database.tx != nil {
+		return database.tx
+	}
+
+	return database.Pool
+}
+
+func NewStorage(database *PgxDatabase) storage.Storage {
+	return storage.Storage{
+		Database:        database,
+		Users:           &PgxUserRepository{Database: database},
+		Posts:           &PgxPostRepository{Database: database},
+		Comments:        &PgxCommentRepository{Database: database},
+		CommentEdits:    &PgxCommentEditRepository{Database: database},
+		Verifications:   &PgxVerificationRepository{Database: database},
+		Sessions:        &PgxSessionRepository{Database: database},
+		RefreshTokens:   &PgxRefreshTokenRepository{Database: database},
+		Bans:            &PgxBanRepository{Database: database},
+		Roles:           &PgxRoleRepository{Database: database},
+		Permissions:     &PgxPermissionRepository{Database: database},
+		Search:          &PgxSearchRepository{Database: database},
+		Moderation:      &PgxModerationRepository{Database: database},
+		Tags:            &PgxTagRepository{Database: database},
+		Revisions:       &PgxPostRevisionRepository{Database: database},
+		PasswordResets:  &PgxPasswordResetRepository{Database: database},
+		TwoFactors:      &PgxTwoFactorRepository{Database: database},
+		RecoveryCodes:   &PgxRecoveryCodeRepository{Database: database},
+		LoginChallenges: &PgxLoginChallengeRepository{Database: database},
+		LoginFailures:   &PgxLoginFailureRepository{Database: database},
+		AuditLog:        &PgxAuditLogRepository{Database: database},
+		Settings:        &PgxSettingsRepository{Database: database},
+	}
 }
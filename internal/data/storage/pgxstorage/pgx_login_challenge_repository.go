@@ -0,0 +1,77 @@
+package pgxstorage
+
+import (
+	"context"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+type PgxLoginChallengeRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxLoginChallengeRepository) Create(ctx context.Context, challenge *entity.LoginChallenge) error {
+	sql := `
+		INSERT INTO login_challenges (id, user_id, expired_at)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	return query(
+		databasePayload[entity.LoginChallenge]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{challenge.ID, challenge.UserID, challenge.ExpiredAt},
+			scan: func(_ *entity.LoginChallenge) []any {
+				return []any{&challenge.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxLoginChallengeRepository) Find(ctx context.Context, id string) (*entity.LoginChallenge, error) {
+	sql := `
+		SELECT id, user_id, expired_at, created_at FROM login_challenges WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.LoginChallenge]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(challenge *entity.LoginChallenge) []any {
+				return []any{&challenge.ID, &challenge.UserID, &challenge.ExpiredAt, &challenge.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxLoginChallengeRepository) Delete(ctx context.Context, id string) error {
+	sql := `
+		DELETE FROM login_challenges WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.LoginChallenge]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxLoginChallengeRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	sql := `
+		DELETE FROM login_challenges WHERE expired_at <= $1
+	`
+	return executeCount(
+		databasePayload[entity.LoginChallenge]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{now},
+			scan: nil,
+		},
+	)
+}
@@ -0,0 +1,96 @@
+package pgxstorage
+
+import (
+	"context"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+type PgxLoginFailureRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxLoginFailureRepository) Find(ctx context.Context, id string) (*entity.LoginFailure, error) {
+	sql := `
+		SELECT id, failures, locked_until, last_failed_at FROM login_failures WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.LoginFailure]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(failure *entity.LoginFailure) []any {
+				return []any{&failure.ID, &failure.Failures, &failure.LockedUntil, &failure.LastFailedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxLoginFailureRepository) Increment(ctx context.Context, id string, since time.Time) (*entity.LoginFailure, error) {
+	sql := `
+		INSERT INTO login_failures (id) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failed_at < $2 THEN 1 ELSE login_failures.failures + 1 END,
+			last_failed_at = NOW()
+		RETURNING id, failures, locked_until, last_failed_at
+	`
+	return queryOne(
+		databasePayload[entity.LoginFailure]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id, since},
+			scan: func(failure *entity.LoginFailure) []any {
+				return []any{&failure.ID, &failure.Failures, &failure.LockedUntil, &failure.LastFailedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxLoginFailureRepository) Lock(ctx context.Context, id string, until time.Time) error {
+	sql := `
+		UPDATE login_failures SET locked_until = $2 WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.LoginFailure]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id, until},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxLoginFailureRepository) Delete(ctx context.Context, id string) error {
+	sql := `
+		DELETE FROM login_failures WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.LoginFailure]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+// Deletes failures made before the given time that no longer lock anything.
+func (repository *PgxLoginFailureRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	sql := `
+		DELETE FROM login_failures
+		WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until <= NOW())
+	`
+	return executeCount(
+		databasePayload[entity.LoginFailure]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{before},
+			scan: nil,
+		},
+	)
+}
@@ -0,0 +1,55 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type PgxModerationRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxModerationRepository) FindQueue(ctx context.Context, filter storage.FilterQuery, queue storage.ModerationQuery) (*storage.Page[entity.ModerationItem], error) {
+	sql := `
+		SELECT * FROM (
+			SELECT 'post'::text, posts.id, posts.id, posts.user_id, posts.title, posts.content, posts.created_at
+			FROM posts
+			WHERE $1::boolean AND posts.verified = false AND posts.deleted_at IS NULL
+			AND posts.status <> 'draft'
+			UNION ALL
+			SELECT 'comment'::text, comments.id, comments.post_id, comments.user_id, '', comments.content, comments.created_at
+			FROM comments JOIN posts ON posts.id = comments.post_id
+			WHERE $2::boolean AND comments.verified = false AND comments.deleted_at IS NULL
+			AND posts.status <> 'draft'
+		) items (type, id, post_id, user_id, title, content, created_at)
+		ORDER BY created_at, type DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	return queryPage(
+		databasePayload[entity.ModerationItem]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{
+				queue.Includes(storage.ModerationPosts),
+				queue.Includes(storage.ModerationComments),
+				filter.Limit + 1,
+				filter.Offset,
+			},
+			scan: func(item *entity.ModerationItem) []any {
+				return []any{
+					&item.Type,
+					&item.ID,
+					&item.PostID,
+					&item.UserID,
+					&item.Title,
+					&item.Content,
+					&item.CreatedAt,
+				}
+			},
+		},
+		filter,
+		nil,
+	)
+}
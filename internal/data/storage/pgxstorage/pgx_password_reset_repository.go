@@ -0,0 +1,76 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxPasswordResetRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxPasswordResetRepository) Create(ctx context.Context, reset *entity.PasswordReset) error {
+	sql := `
+		INSERT INTO password_resets (id, user_id)
+		VALUES ($1, $2)
+		RETURNING expired_at, created_at
+	`
+	return query(
+		databasePayload[entity.PasswordReset]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{reset.ID, reset.UserID},
+			scan: func(_ *entity.PasswordReset) []any {
+				return []any{&reset.ExpiredAt, &reset.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxPasswordResetRepository) Find(ctx context.Context, id string) (*entity.PasswordReset, error) {
+	sql := `
+		SELECT id, user_id, expired_at, created_at FROM password_resets WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.PasswordReset]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(reset *entity.PasswordReset) []any {
+				return []any{&reset.ID, &reset.UserID, &reset.ExpiredAt, &reset.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxPasswordResetRepository) Delete(ctx context.Context, id string) error {
+	sql := `
+		DELETE FROM password_resets WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.PasswordReset]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxPasswordResetRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM password_resets WHERE user_id = $1
+	`
+	return execute(
+		databasePayload[entity.PasswordReset]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
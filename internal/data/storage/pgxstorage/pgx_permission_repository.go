@@ -0,0 +1,30 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type PgxPermissionRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxPermissionRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Permission], error) {
+	sql, args := newSelectQuery("permissions", `SELECT id, name, COALESCE(description, '') FROM permissions`).
+		paginateBy("permissions.id", filter)
+
+	return queryPage(
+		databasePayload[entity.Permission]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: func(permission *entity.Permission) []any {
+				return []any{&permission.ID, &permission.Name, &permission.Description}
+			},
+		},
+		filter,
+		nil,
+	)
+}
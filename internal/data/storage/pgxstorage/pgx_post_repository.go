@@ -2,143 +2,258 @@ package pgxstorage
 
 import (
 	"context"
+	"time"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
-
-	"github.com/jackc/pgx"
 )
 
 type PgxPostRepository struct {
 	Database *PgxDatabase
 }
 
-func (repository *PgxPostRepository) Create(ctx context.Context, tx *pgx.Tx, post *entity.Post) error {
+// Selected explicitly, as the table also holds the search vector. Tags are
+// selected as an array of slugs.
+const postColumns = `posts.id, posts.user_id, posts.title, posts.content, posts.status, posts.publish_at,
+	posts.verified, posts.created_at, posts.updated_at, posts.deleted_at, posts.deleted_by,
+	ARRAY(
+		SELECT tags.slug FROM post_tags JOIN tags ON tags.id = post_tags.tag_id
+		WHERE post_tags.post_id = posts.id ORDER BY tags.slug
+	)`
+
+func scanPost(post *entity.Post) []any {
+	return []any{
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		&post.Status,
+		&post.PublishAt,
+		&post.Verified,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.DeletedAt,
+		&post.DeletedBy,
+		&post.Tags,
+	}
+}
+
+func (repository *PgxPostRepository) Create(ctx context.Context, post *entity.Post) error {
 	sql := `
-		INSERT INTO posts (user_id, title, content) 
-		VALUES ($1, $2, $3) 
-		RETURNING id, verified, created_at, updated_at
+		INSERT INTO posts (user_id, title, content, status, publish_at) 
+		VALUES ($1, $2, $3, $4, COALESCE($5, CASE WHEN $4::text = 'published' THEN NOW() END)) 
+		RETURNING id, publish_at, verified, created_at, updated_at
 	`
 	return query(
 		databasePayload[entity.Post]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{post.UserID, post.Title, post.Content},
+			args: []any{post.UserID, post.Title, post.Content, post.Status, post.PublishAt},
 			scan: func(_ *entity.Post) []any {
-				return []any{&post.ID, &post.Verified, &post.CreatedAt, &post.UpdatedAt}
+				return []any{&post.ID, &post.PublishAt, &post.Verified, &post.CreatedAt, &post.UpdatedAt}
 			},
 		},
 	)
 }
 
-func (repository *PgxPostRepository) Find(ctx context.Context, tx *pgx.Tx, id int64) (*entity.Post, error) {
+func (repository *PgxPostRepository) Find(ctx context.Context, id int64) (*entity.Post, error) {
 	sql := `
-		SELECT * FROM posts WHERE id = $1
+		SELECT ` + postColumns + ` FROM posts WHERE id = $1 AND deleted_at IS NULL
 	`
 	return queryOne(
 		databasePayload[entity.Post]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
-			scan: func(post *entity.Post) []any {
-				return []any{
-					&post.ID,
-					&post.UserID,
-					&post.Title,
-					&post.Content,
-					&post.Verified,
-					&post.CreatedAt,
-					&post.UpdatedAt,
-				}
-			},
+			scan: scanPost,
 		},
 	)
 }
 
-func (repository *PgxPostRepository) FindAllByUserID(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery, id int64) ([]*entity.Post, error) {
-	sql := `
-		SELECT posts.* FROM posts
-		INNER JOIN users ON posts.user_id = users.id
-		WHERE users.id = $1
-		LIMIT $2
-		OFFSET $3
-	`
-	return queryAll(
+func (repository *PgxPostRepository) FindAllByUserID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Post], error) {
+	sql, args := newSelectQuery("posts", `SELECT `+postColumns+` FROM posts`).
+		where("posts.user_id = ?", id).
+		paginate(filter)
+
+	return queryPage(
 		databasePayload[entity.Post]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{id, filter.Limit, filter.Offset},
-			scan: func(post *entity.Post) []any {
-				return []any{
-					&post.ID,
-					&post.UserID,
-					&post.Title,
-					&post.Content,
-					&post.Verified,
-					&post.CreatedAt,
-					&post.UpdatedAt,
-				}
-			},
+			args: args,
+			scan: scanPost,
 		},
+		filter,
+		storage.PostPosition,
 	)
 }
 
-func (repository *PgxPostRepository) FindAll(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery) ([]*entity.Post, error) {
-	sql := `
-		SELECT * FROM posts
-		LIMIT $1
-		OFFSET $2
-	`
-	return queryAll(
+func (repository *PgxPostRepository) FindAllByTagID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.Post], error) {
+	sql, args := newSelectQuery("posts", `SELECT `+postColumns+` FROM posts`).
+		where("EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id AND post_tags.tag_id = ?)", id).
+		paginate(filter)
+
+	return queryPage(
 		databasePayload[entity.Post]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{filter.Limit, filter.Offset},
-			scan: func(post *entity.Post) []any {
-				return []any{
-					&post.ID,
-					&post.UserID,
-					&post.Title,
-					&post.Content,
-					&post.Verified,
-					&post.CreatedAt,
-					&post.UpdatedAt,
-				}
-			},
+			args: args,
+			scan: scanPost,
+		},
+		filter,
+		storage.PostPosition,
+	)
+}
+
+func (repository *PgxPostRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Post], error) {
+	sql, args := newSelectQuery("posts", `SELECT `+postColumns+` FROM posts`).
+		paginate(filter)
+
+	return queryPage(
+		databasePayload[entity.Post]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanPost,
 		},
+		filter,
+		storage.PostPosition,
 	)
 }
 
-func (repository *PgxPostRepository) Update(ctx context.Context, tx *pgx.Tx, post *entity.Post) error {
+func (repository *PgxPostRepository) Update(ctx context.Context, post *entity.Post) error {
 	sql := `
 		UPDATE posts 
-		SET title=$1, content=$2, updated_at=NOW()
-		WHERE id = $3
-		RETURNING updated_at
+		SET title=$1, content=$2, status=$3,
+			publish_at=COALESCE($4, CASE WHEN $3::text = 'published' THEN NOW() END), verified=$5, updated_at=NOW()
+		WHERE id = $6
+		RETURNING publish_at, updated_at
 		`
 	return query(
 		databasePayload[entity.Post]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{post.Title, post.Content, post.ID},
+			args: []any{post.Title, post.Content, post.Status, post.PublishAt, post.Verified, post.ID},
 			scan: func(_ *entity.Post) []any {
-				return []any{&post.UpdatedAt}
+				return []any{&post.PublishAt, &post.UpdatedAt}
 			},
 		},
 	)
 }
 
-func (repository *PgxPostRepository) Delete(ctx context.Context, tx *pgx.Tx, id int64) error {
+// Publishes scheduled posts whose publish_at time is not after now.
+func (repository *PgxPostRepository) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	sql := `
+		UPDATE posts
+		SET status = 'published', updated_at = NOW()
+		WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+	`
+	return executeCount(
+		databasePayload[entity.Post]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{now},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxPostRepository) Approve(ctx context.Context, id int64) error {
+	sql := `
+		UPDATE posts SET verified = true WHERE id = $1 AND deleted_at IS NULL
+	`
+	return execute(
+		databasePayload[entity.Post]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxPostRepository) SoftDelete(ctx context.Context, id int64, by int64) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		sql := `
+			UPDATE posts SET deleted_at = NOW(), deleted_by = $2
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING deleted_at
+		`
+		post, err := queryOne(
+			databasePayload[entity.Post]{
+				conn: database.conn(),
+				ctx:  ctx,
+				sql:  sql,
+				args: []any{id, by},
+				scan: func(post *entity.Post) []any {
+					return []any{&post.DeletedAt}
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return moveComments(ctx, database, "comments.post_id = $1", id, nil, post.DeletedAt, &by)
+	})
+}
+
+func (repository *PgxPostRepository) Restore(ctx context.Context, id int64) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		sql := `
+			UPDATE posts SET deleted_at = NULL, deleted_by = NULL
+			FROM posts AS deleted JOIN users ON users.id = deleted.user_id
+			WHERE posts.id = $1 AND deleted.id = posts.id
+			AND deleted.deleted_at IS NOT NULL AND users.deleted_at IS NULL
+			RETURNING deleted.deleted_at
+		`
+		post, err := queryOne(
+			databasePayload[entity.Post]{
+				conn: database.conn(),
+				ctx:  ctx,
+				sql:  sql,
+				args: []any{id},
+				scan: func(post *entity.Post) []any {
+					return []any{&post.DeletedAt}
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return moveComments(ctx, database, "comments.post_id = $1", id, post.DeletedAt, nil, nil)
+	})
+}
+
+func (repository *PgxPostRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	sql := `
+		DELETE FROM posts WHERE deleted_at < $1
+	`
+	return executeCount(
+		databasePayload[entity.Post]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{before},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxPostRepository) Delete(ctx context.Context, id int64) error {
 	sql := `
 		DELETE FROM posts WHERE id = $1
 	`
 	return execute(
 		databasePayload[entity.Post]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
@@ -0,0 +1,67 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type PgxPostRevisionRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxPostRevisionRepository) Create(ctx context.Context, revision *entity.PostRevision) error {
+	sql := `
+		INSERT INTO post_revisions (post_id, editor_id, title, content)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return query(
+		databasePayload[entity.PostRevision]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{revision.PostID, revision.EditorID, revision.Title, revision.Content},
+			scan: func(_ *entity.PostRevision) []any {
+				return []any{&revision.ID, &revision.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxPostRevisionRepository) Find(ctx context.Context, id int64) (*entity.PostRevision, error) {
+	sql := `
+		SELECT id, post_id, editor_id, title, content, created_at FROM post_revisions WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.PostRevision]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(r *entity.PostRevision) []any {
+				return []any{&r.ID, &r.PostID, &r.EditorID, &r.Title, &r.Content, &r.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxPostRevisionRepository) FindAllByPostID(ctx context.Context, filter storage.FilterQuery, id int64) (*storage.Page[entity.PostRevision], error) {
+	sql, args := newSelectQuery("post_revisions", `SELECT id, post_id, editor_id, title, content, created_at FROM post_revisions`).
+		where("post_revisions.post_id = ?", id).
+		paginateBy("post_revisions.id", filter)
+
+	return queryPage(
+		databasePayload[entity.PostRevision]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: func(r *entity.PostRevision) []any {
+				return []any{&r.ID, &r.PostID, &r.EditorID, &r.Title, &r.Content, &r.CreatedAt}
+			},
+		},
+		filter,
+		nil,
+	)
+}
@@ -0,0 +1,143 @@
+package pgxstorage
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"web_blog/internal/data/storage"
+)
+
+// Builds paged SELECT statements. Conditions use ? placeholders, which are
+// numbered in the order the conditions are added.
+type selectQuery struct {
+	table      string
+	sql        string
+	conditions []string
+	args       []any
+}
+
+func newSelectQuery(table string, sql string) *selectQuery {
+	return &selectQuery{table: table, sql: sql}
+}
+
+func (query *selectQuery) where(condition string, args ...any) *selectQuery {
+	for _, arg := range args {
+		query.args = append(query.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(query.args)), 1)
+	}
+
+	query.conditions = append(query.conditions, condition)
+	return query
+}
+
+// Applies the filters allowed by the listing. Column names never come from the
+// request, only the values, which are passed as arguments.
+func (query *selectQuery) filter(filter storage.FilterQuery) *selectQuery {
+	if filter.Listing.Trashable {
+		if filter.Deleted {
+			query.where(query.table + ".deleted_at IS NOT NULL")
+		} else {
+			query.where(query.table + ".deleted_at IS NULL")
+		}
+	}
+
+	for _, name := range filter.Filters() {
+		if !slices.Contains(filter.Listing.Filterable, name) {
+			continue
+		}
+
+		switch name {
+		case "since":
+			query.where(query.table+".created_at >= ?", *filter.Since)
+		case "until":
+			query.where(query.table+".created_at < ?", *filter.Until)
+		case "verified":
+			query.where(query.table+".verified = ?", *filter.Verified)
+		case "user_id":
+			query.where(query.table+".user_id = ?", *filter.UserID)
+		case "status":
+			query.where(query.table+".status = ?", *filter.Status)
+		}
+	}
+
+	return query
+}
+
+// Orders rows newest first by created_at and id. With a cursor the rows start
+// after its position, or before it going backward, otherwise the offset is
+// used. One extra row is fetched so storage.NewPage can tell if there are more.
+//
+// Sorted filters are ordered by their sort fields, with id as the tiebreak,
+// and paged by offset.
+func (query *selectQuery) paginate(filter storage.FilterQuery) (string, []any) {
+	query.filter(filter)
+	if len(filter.Sort) > 0 {
+		return query.build(query.order(filter), filter)
+	}
+
+	direction := "DESC"
+
+	if cursor := filter.Cursor; cursor != nil {
+		operator := "<"
+		if cursor.Backward {
+			operator, direction = ">", "ASC"
+		}
+
+		query.where(
+			fmt.Sprintf("(%[1]s.created_at, %[1]s.id) %[2]s (?, ?)", query.table, operator),
+			cursor.CreatedAt,
+			cursor.ID,
+		)
+	}
+
+	order := fmt.Sprintf("%[1]s.created_at %[2]s, %[1]s.id %[2]s", query.table, direction)
+	return query.build(order, filter)
+}
+
+func (query *selectQuery) order(filter storage.FilterQuery) string {
+	var order []string
+
+	for _, field := range filter.Sort {
+		column, descending := storage.SortField(field)
+		if !slices.Contains(filter.Listing.Sortable, column) {
+			continue
+		}
+
+		if descending {
+			order = append(order, query.table+"."+column+" DESC")
+		} else {
+			order = append(order, query.table+"."+column+" ASC")
+		}
+	}
+
+	if !slices.Contains(order, query.table+".id ASC") && !slices.Contains(order, query.table+".id DESC") {
+		order = append(order, query.table+".id DESC")
+	}
+
+	return strings.Join(order, ", ")
+}
+
+// Orders rows by the given clause and pages them by offset only.
+func (query *selectQuery) paginateBy(order string, filter storage.FilterQuery) (string, []any) {
+	return query.build(order, filter)
+}
+
+func (query *selectQuery) build(order string, filter storage.FilterQuery) (string, []any) {
+	var sb strings.Builder
+
+	sb.WriteString(query.sql)
+	for i, condition := range query.conditions {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(condition)
+	}
+
+	args := append(query.args, filter.Limit+1, filter.Offset)
+	fmt.Fprintf(&sb, " ORDER BY %s LIMIT $%d OFFSET $%d", order, len(args)-1, len(args))
+
+	return sb.String(), args
+}
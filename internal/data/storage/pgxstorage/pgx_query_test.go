@@ -0,0 +1,97 @@
+package pgxstorage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"web_blog/internal/data/storage"
+)
+
+// Checks the statements built for pages of a listing, the rows they select are
+// covered by the memstorage findPage tests.
+func TestPaginate(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+	user := int64(2)
+	verified := true
+
+	const selectPosts = "SELECT * FROM posts"
+
+	tests := []struct {
+		name   string
+		filter storage.FilterQuery
+		sql    string
+		args   []any
+	}{
+		{
+			name:   "newest first",
+			filter: storage.FilterQuery{Limit: 10},
+			sql:    " WHERE posts.deleted_at IS NULL ORDER BY posts.created_at DESC, posts.id DESC LIMIT $1 OFFSET $2",
+			args:   []any{11, 0},
+		},
+		{
+			name:   "offset",
+			filter: storage.FilterQuery{Limit: 2, Offset: 2},
+			sql:    " WHERE posts.deleted_at IS NULL ORDER BY posts.created_at DESC, posts.id DESC LIMIT $1 OFFSET $2",
+			args:   []any{3, 2},
+		},
+		{
+			name:   "cursor",
+			filter: storage.FilterQuery{Limit: 10, Cursor: &storage.Cursor{CreatedAt: t1, ID: 3}},
+			sql:    " WHERE posts.deleted_at IS NULL AND (posts.created_at, posts.id) < ($1, $2) ORDER BY posts.created_at DESC, posts.id DESC LIMIT $3 OFFSET $4",
+			args:   []any{t1, int64(3), 11, 0},
+		},
+		{
+			name:   "cursor backward",
+			filter: storage.FilterQuery{Limit: 10, Cursor: &storage.Cursor{CreatedAt: t0, ID: 2, Backward: true}},
+			sql:    " WHERE posts.deleted_at IS NULL AND (posts.created_at, posts.id) > ($1, $2) ORDER BY posts.created_at ASC, posts.id ASC LIMIT $3 OFFSET $4",
+			args:   []any{t0, int64(2), 11, 0},
+		},
+		{
+			name:   "sort with id tiebreak",
+			filter: storage.FilterQuery{Limit: 10, Sort: []string{"title"}},
+			sql:    " WHERE posts.deleted_at IS NULL ORDER BY posts.title ASC, posts.id DESC LIMIT $1 OFFSET $2",
+			args:   []any{11, 0},
+		},
+		{
+			name:   "sort descending then id",
+			filter: storage.FilterQuery{Limit: 10, Sort: []string{"-created_at", "id"}},
+			sql:    " WHERE posts.deleted_at IS NULL ORDER BY posts.created_at DESC, posts.id ASC LIMIT $1 OFFSET $2",
+			args:   []any{11, 0},
+		},
+		{
+			name:   "sort by unknown field",
+			filter: storage.FilterQuery{Limit: 10, Sort: []string{"password"}},
+			sql:    " WHERE posts.deleted_at IS NULL ORDER BY posts.id DESC LIMIT $1 OFFSET $2",
+			args:   []any{11, 0},
+		},
+		{
+			name:   "filters",
+			filter: storage.FilterQuery{Limit: 10, Since: &t0, Until: &t2, UserID: &user, Verified: &verified},
+			sql:    " WHERE posts.deleted_at IS NULL AND posts.created_at >= $1 AND posts.created_at < $2 AND posts.verified = $3 AND posts.user_id = $4 ORDER BY posts.created_at DESC, posts.id DESC LIMIT $5 OFFSET $6",
+			args:   []any{t0, t2, true, int64(2), 11, 0},
+		},
+		{
+			name:   "trash",
+			filter: storage.FilterQuery{Limit: 10, Deleted: true},
+			sql:    " WHERE posts.deleted_at IS NOT NULL ORDER BY posts.created_at DESC, posts.id DESC LIMIT $1 OFFSET $2",
+			args:   []any{11, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.filter.Listing = storage.PostListing
+			sql, args := newSelectQuery("posts", selectPosts).paginate(test.filter)
+
+			if want := selectPosts + test.sql; sql != want {
+				t.Errorf("got sql\n%s\nwant\n%s", sql, want)
+			}
+
+			if !reflect.DeepEqual(args, test.args) {
+				t.Errorf("got args %v, want %v", args, test.args)
+			}
+		})
+	}
+}
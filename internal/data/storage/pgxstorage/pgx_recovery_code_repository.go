@@ -0,0 +1,64 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxRecoveryCodeRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxRecoveryCodeRepository) Replace(ctx context.Context, id int64, codes []string) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		err := executeAny(databasePayload[entity.RecoveryCode]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql:  `DELETE FROM recovery_codes WHERE user_id = $1`,
+			args: []any{id},
+		})
+		if err != nil {
+			return err
+		}
+
+		return executeAny(databasePayload[entity.RecoveryCode]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql: `
+				INSERT INTO recovery_codes (id, user_id)
+				SELECT code, $1 FROM UNNEST($2::text[]) AS code
+			`,
+			args: []any{id, codes},
+		})
+	})
+}
+
+func (repository *PgxRecoveryCodeRepository) Use(ctx context.Context, id int64, code string) error {
+	sql := `
+		DELETE FROM recovery_codes WHERE id = $1 AND user_id = $2
+	`
+	return execute(
+		databasePayload[entity.RecoveryCode]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{code, id},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxRecoveryCodeRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM recovery_codes WHERE user_id = $1
+	`
+	return executeAny(
+		databasePayload[entity.RecoveryCode]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
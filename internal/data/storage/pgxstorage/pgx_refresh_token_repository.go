@@ -0,0 +1,76 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxRefreshTokenRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	sql := `
+		INSERT INTO refresh_tokens (id, session_id, user_id, expired_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+	return query(
+		databasePayload[entity.RefreshToken]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{token.ID, token.SessionID, token.UserID, token.ExpiredAt},
+			scan: func(_ *entity.RefreshToken) []any {
+				return []any{&token.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxRefreshTokenRepository) Find(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	sql := `
+		SELECT id, session_id, user_id, expired_at, used_at, created_at FROM refresh_tokens WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.RefreshToken]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(token *entity.RefreshToken) []any {
+				return []any{&token.ID, &token.SessionID, &token.UserID, &token.ExpiredAt, &token.UsedAt, &token.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxRefreshTokenRepository) Use(ctx context.Context, id string) error {
+	sql := `
+		UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL
+	`
+	return execute(
+		databasePayload[entity.RefreshToken]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxRefreshTokenRepository) Delete(ctx context.Context, id string) error {
+	sql := `
+		DELETE FROM refresh_tokens WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.RefreshToken]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
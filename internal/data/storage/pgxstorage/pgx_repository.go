@@ -2,6 +2,7 @@ package pgxstorage
 
 import (
 	"context"
+	"errors"
 	"web_blog/internal/data/storage"
 
 	"github.com/jackc/pgx"
@@ -21,28 +22,38 @@ type databasePayload[T any] struct {
 	scan func(*T) []any
 }
 
-func provideConn(conn *pgx.Tx, alt *pgx.Conn) connection {
-	if conn != nil {
-		return conn
-	}
-
-	return alt
-}
-
-func withTx(conn *pgx.Conn, fn func(*pgx.Tx) error) error {
+// Rolls back when fn fails or panics, the deferred rollback is a no-op once
+// the transaction is committed.
+func withTx(ctx context.Context, pool *pgx.ConnPool, fn func(*pgx.Tx) error) error {
 	var tx *pgx.Tx
 	var err error
 
-	if tx, err = conn.Begin(); err != nil {
+	if tx, err = pool.BeginEx(ctx, nil); err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
 	if err = fn(tx); err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	return tx.Commit()
+	return tx.CommitEx(ctx)
+}
+
+// Postgres error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+func translateError(err error) error {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return storage.ErrorDuplicate
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrorNotFound
+	}
+
+	return err
 }
 
 func execute[T any](dp databasePayload[T]) error {
@@ -53,7 +64,7 @@ func execute[T any](dp databasePayload[T]) error {
 	defer cancel()
 
 	if com, err = dp.conn.ExecEx(ctx, dp.sql, nil, dp.args...); err != nil {
-		return err
+		return translateError(err)
 	}
 
 	if com.RowsAffected() <= 0 {
@@ -63,13 +74,34 @@ func execute[T any](dp databasePayload[T]) error {
 	return nil
 }
 
+// Like execute, but affecting no rows is not an error.
+func executeAny[T any](dp databasePayload[T]) error {
+	_, err := executeCount(dp)
+	return err
+}
+
+// Returns the number of affected rows.
+func executeCount[T any](dp databasePayload[T]) (int64, error) {
+	var com pgx.CommandTag
+	var err error
+
+	ctx, cancel := context.WithTimeout(dp.ctx, storage.DatabaseQueryTimeout)
+	defer cancel()
+
+	if com, err = dp.conn.ExecEx(ctx, dp.sql, nil, dp.args...); err != nil {
+		return 0, translateError(err)
+	}
+
+	return com.RowsAffected(), nil
+}
+
 func query[T any](dp databasePayload[T]) error {
 	ctx, cancel := context.WithTimeout(dp.ctx, storage.DatabaseQueryTimeout)
 	defer cancel()
 
-	return dp.conn.
+	return translateError(dp.conn.
 		QueryRowEx(ctx, dp.sql, nil, dp.args...).
-		Scan(dp.scan(nil)...)
+		Scan(dp.scan(nil)...))
 }
 
 func queryOne[T any](dp databasePayload[T]) (*T, error) {
@@ -78,9 +110,9 @@ func queryOne[T any](dp databasePayload[T]) (*T, error) {
 
 	element := new(T)
 
-	return element, dp.conn.
+	return element, translateError(dp.conn.
 		QueryRowEx(ctx, dp.sql, nil, dp.args...).
-		Scan(dp.scan(element)...)
+		Scan(dp.scan(element)...))
 }
 
 func queryAll[T any](dp databasePayload[T]) ([]*T, error) {
@@ -92,7 +124,7 @@ func queryAll[T any](dp databasePayload[T]) ([]*T, error) {
 	defer cancel()
 
 	if rows, err = dp.conn.QueryEx(ctx, dp.sql, nil, dp.args...); err != nil {
-		return list, err
+		return list, translateError(err)
 	}
 
 	defer rows.Close()
@@ -106,3 +138,18 @@ func queryAll[T any](dp databasePayload[T]) ([]*T, error) {
 
 	return list, err
 }
+
+func queryPage[T any](
+	dp databasePayload[T],
+	filter storage.FilterQuery,
+	position func(*T) storage.Cursor,
+) (*storage.Page[T], error) {
+	var list []*T
+	var err error
+
+	if list, err = queryAll(dp); err != nil {
+		return nil, err
+	}
+
+	return storage.NewPage(list, filter, position), nil
+}
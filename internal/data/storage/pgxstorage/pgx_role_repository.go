@@ -2,54 +2,174 @@ package pgxstorage
 
 import (
 	"context"
+	"slices"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
-
-	"github.com/jackc/pgx"
 )
 
 type PgxRoleRepository struct {
 	Database *PgxDatabase
 }
 
-func (repository *PgxRoleRepository) Create(ctx context.Context, tx *pgx.Tx, session *entity.Role) error {
-	return nil
+const roleColumns = `roles.id, roles.level, roles.name, COALESCE(roles.description, ''),
+	ARRAY(
+		SELECT permissions.name FROM role_permissions
+		JOIN permissions ON permissions.id = role_permissions.permission_id
+		WHERE role_permissions.role_id = roles.id ORDER BY permissions.name
+	) AS permissions`
+
+func scanRole(role *entity.Role) []any {
+	return []any{
+		&role.ID,
+		&role.Level,
+		&role.Name,
+		&role.Description,
+		&role.Permissions,
+	}
 }
 
-func (repository *PgxRoleRepository) Find(ctx context.Context, tx *pgx.Tx, id int64) (*entity.Role, error) {
-	return nil, nil
+func (repository *PgxRoleRepository) Create(ctx context.Context, role *entity.Role) error {
+	sql := `
+		INSERT INTO roles (level, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+	return query(
+		databasePayload[entity.Role]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{role.Level, role.Name, role.Description},
+			scan: func(_ *entity.Role) []any {
+				return []any{&role.ID}
+			},
+		},
+	)
+}
+
+func (repository *PgxRoleRepository) Find(ctx context.Context, id int64) (*entity.Role, error) {
+	sql := `
+		SELECT ` + roleColumns + ` FROM roles WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.Role]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: scanRole,
+		},
+	)
 }
 
-func (repository *PgxRoleRepository) FindByName(ctx context.Context, tx *pgx.Tx, name string) (*entity.Role, error) {
+func (repository *PgxRoleRepository) FindByName(ctx context.Context, name string) (*entity.Role, error) {
 	sql := `
-		SELECT * FROM roles WHERE name = $1
+		SELECT ` + roleColumns + ` FROM roles WHERE name = $1
 	`
 	return queryOne(
 		databasePayload[entity.Role]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{name},
-			scan: func(role *entity.Role) []any {
-				return []any{
-					&role.ID,
-					&role.Level,
-					&role.Name,
-					&role.Description,
-				}
-			},
+			scan: scanRole,
 		},
 	)
 }
 
-func (repository *PgxRoleRepository) FindAll(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery) ([]*entity.Role, error) {
-	return nil, nil
+func (repository *PgxRoleRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Role], error) {
+	sql, args := newSelectQuery("roles", `SELECT `+roleColumns+` FROM roles`).
+		paginateBy("roles.id", filter)
+
+	return queryPage(
+		databasePayload[entity.Role]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanRole,
+		},
+		filter,
+		nil,
+	)
 }
 
-func (repository *PgxRoleRepository) Update(ctx context.Context, tx *pgx.Tx, session *entity.Role) error {
-	return nil
+func (repository *PgxRoleRepository) Update(ctx context.Context, role *entity.Role) error {
+	sql := `
+		UPDATE roles
+		SET level = $1, name = $2, description = $3
+		WHERE id = $4
+	`
+	return execute(
+		databasePayload[entity.Role]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{role.Level, role.Name, role.Description, role.ID},
+			scan: nil,
+		},
+	)
 }
 
-func (repository *PgxRoleRepository) Delete(ctx context.Context, tx *pgx.Tx, id int64) error {
-	return nil
+func (repository *PgxRoleRepository) Delete(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM roles WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.Role]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+// Replaces the permissions of a role with role.Permissions. Unknown
+// permissions are not found.
+func (repository *PgxRoleRepository) SetPermissions(ctx context.Context, role *entity.Role) error {
+	var names []string
+
+	for _, name := range role.Permissions {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		var count int64
+		var err error
+
+		err = executeAny(databasePayload[entity.Role]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql:  `DELETE FROM role_permissions WHERE role_id = $1`,
+			args: []any{role.ID},
+		})
+		if err != nil {
+			return err
+		}
+
+		count, err = executeCount(databasePayload[entity.Role]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql: `
+				INSERT INTO role_permissions (role_id, permission_id)
+				SELECT $1, permissions.id FROM permissions WHERE permissions.name = ANY($2::text[])
+			`,
+			args: []any{role.ID, names},
+		})
+		if err != nil {
+			return err
+		}
+
+		if count != int64(len(names)) {
+			return storage.ErrorNotFound
+		}
+
+		slices.Sort(names)
+		role.Permissions = append([]string{}, names...)
+		return nil
+	})
 }
@@ -0,0 +1,69 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type PgxSearchRepository struct {
+	Database *PgxDatabase
+}
+
+// Ranks posts and comments together against a web search style query, so
+// quoted phrases, "or" and "-term" work as users expect. Only published posts
+// and their comments are searched.
+func (repository *PgxSearchRepository) Search(ctx context.Context, filter storage.FilterQuery, search storage.SearchQuery) (*storage.Page[entity.SearchResult], error) {
+	sql := `
+		WITH search AS (
+			SELECT websearch_to_tsquery('english', $1) AS query
+		)
+		SELECT * FROM (
+			SELECT 'post'::text, posts.id, posts.id, posts.user_id, posts.title,
+				ts_headline('english', posts.content, search.query, $2),
+				ts_rank(posts.search, search.query)::float8, posts.created_at
+			FROM posts, search
+			WHERE $3::boolean AND posts.status = 'published' AND posts.deleted_at IS NULL
+				AND (posts.verified OR NOT $7::boolean) AND posts.search @@ search.query
+			UNION ALL
+			SELECT 'comment'::text, comments.id, comments.post_id, comments.user_id, '',
+				ts_headline('english', comments.content, search.query, $2),
+				ts_rank(comments.search, search.query)::float8, comments.created_at
+			FROM comments JOIN posts ON posts.id = comments.post_id, search
+			WHERE $4::boolean AND posts.status = 'published' AND comments.deleted_at IS NULL
+				AND ((posts.verified AND comments.verified) OR NOT $7::boolean) AND comments.search @@ search.query
+		) results (type, id, post_id, user_id, title, snippet, rank, created_at)
+		ORDER BY rank DESC, created_at DESC, id DESC
+		LIMIT $5 OFFSET $6
+	`
+	return queryPage(
+		databasePayload[entity.SearchResult]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{
+				search.Text,
+				"StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15",
+				search.Includes(storage.SearchPosts),
+				search.Includes(storage.SearchComments),
+				filter.Limit + 1,
+				filter.Offset,
+				search.Approved,
+			},
+			scan: func(result *entity.SearchResult) []any {
+				return []any{
+					&result.Type,
+					&result.ID,
+					&result.PostID,
+					&result.UserID,
+					&result.Title,
+					&result.Snippet,
+					&result.Rank,
+					&result.CreatedAt,
+				}
+			},
+		},
+		filter,
+		nil,
+	)
+}
@@ -2,52 +2,66 @@ package pgxstorage
 
 import (
 	"context"
+	"time"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
-
-	"github.com/jackc/pgx"
 )
 
 type PgxSessionRepository struct {
 	Database *PgxDatabase
 }
 
-func (repository *PgxSessionRepository) Create(ctx context.Context, tx *pgx.Tx, session *entity.Session) error {
+const sessionColumns = `sessions.id, sessions.user_id, sessions.ip, sessions.user_agent, sessions.expired_at,
+	sessions.last_seen_at, sessions.created_at`
+
+func scanSession(session *entity.Session) []any {
+	return []any{
+		&session.ID,
+		&session.UserID,
+		&session.IP,
+		&session.UserAgent,
+		&session.ExpiredAt,
+		&session.LastSeenAt,
+		&session.CreatedAt,
+	}
+}
+
+func (repository *PgxSessionRepository) Create(ctx context.Context, session *entity.Session) error {
 	sql := `
-		INSERT INTO sessions (id, user_id, expired_at) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO sessions (id, user_id, ip, user_agent, expired_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING last_seen_at, created_at
 	`
-	return execute(
+	return query(
 		databasePayload[entity.Session]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{session.ID, session.UserID, session.ExpiredAt},
-			scan: nil,
+			args: []any{session.ID, session.UserID, session.IP, session.UserAgent, session.ExpiredAt},
+			scan: func(_ *entity.Session) []any {
+				return []any{&session.LastSeenAt, &session.CreatedAt}
+			},
 		},
 	)
 }
 
-func (repository *PgxSessionRepository) Find(ctx context.Context, tx *pgx.Tx, id string) (*entity.Session, error) {
+func (repository *PgxSessionRepository) Find(ctx context.Context, id string) (*entity.Session, error) {
 	sql := `
-		SELECT * FROM sessions WHERE id = $1
+		SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1
 	`
 	return queryOne(
 		databasePayload[entity.Session]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
-			scan: func(session *entity.Session) []any {
-				return []any{&session.ID, &session.UserID, &session.ExpiredAt}
-			},
+			scan: scanSession,
 		},
 	)
 }
 
 func (repository *PgxSessionRepository) FindWithUser(
 	ctx context.Context,
-	tx *pgx.Tx,
 	id string,
 ) (*entity.Session, *entity.User, error) {
 	type sessionWithUserPayload struct {
@@ -56,91 +70,103 @@ func (repository *PgxSessionRepository) FindWithUser(
 	}
 
 	sql := `
-		SELECT * FROM sessions
+		SELECT ` + sessionColumns + `, ` + userColumns + `,
+			roles.id, roles.level, roles.name, COALESCE(roles.description, '')
+		FROM sessions
 		INNER JOIN users ON sessions.user_id = users.id
 		INNER JOIN roles ON users.role_id = roles.id
-		WHERE sessions.id = $1
+		WHERE sessions.id = $1 AND users.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM user_bans
+			WHERE user_bans.user_id = users.id
+			AND (user_bans.expires_at IS NULL OR user_bans.expires_at > NOW())
+		)
 	`
 
 	payload, err := queryOne(
 		databasePayload[sessionWithUserPayload]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
 			scan: func(payload *sessionWithUserPayload) []any {
-				return []any{
-					&payload.session.ID,
-					&payload.session.UserID,
-					&payload.session.ExpiredAt,
-
-					&payload.user.ID,
-					&payload.user.RoleID,
-					&payload.user.Email,
-					&payload.user.Username,
-					&payload.user.Password.Hash,
-					&payload.user.Verified,
-					&payload.user.CreatedAt,
-					&payload.user.UpdatedAt,
-
+				return append(
+					append(scanSession(&payload.session), scanUser(&payload.user)...),
 					&payload.user.Role.ID,
 					&payload.user.Role.Level,
 					&payload.user.Role.Name,
 					&payload.user.Role.Description,
-				}
+				)
 			},
 		},
 	)
 
-	return &payload.session, &payload.user, err
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &payload.session, &payload.user, nil
 }
 
-func (repository *PgxSessionRepository) FindAll(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery) ([]*entity.Session, error) {
+// Expired sessions are left out, the most recently used first.
+func (repository *PgxSessionRepository) FindAllByUserID(ctx context.Context, id int64) ([]*entity.Session, error) {
 	sql := `
-		SELECT * FROM sessions
-		LIMIT $1
-		OFFSET $2
+		SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id = $1 AND expired_at > NOW()
+		ORDER BY last_seen_at DESC, created_at DESC
 	`
 	return queryAll(
 		databasePayload[entity.Session]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{filter.Limit, filter.Offset},
-			scan: func(session *entity.Session) []any {
-				return []any{&session.ID, &session.UserID, &session.ExpiredAt}
-			},
+			args: []any{id},
+			scan: scanSession,
 		},
 	)
 }
 
-func (repository *PgxSessionRepository) Update(ctx context.Context, tx *pgx.Tx, session *entity.Session) error {
+func (repository *PgxSessionRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Session], error) {
+	sql, args := newSelectQuery("sessions", `SELECT `+sessionColumns+` FROM sessions`).
+		paginateBy("sessions.id", filter)
+
+	return queryPage(
+		databasePayload[entity.Session]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: scanSession,
+		},
+		filter,
+		nil,
+	)
+}
+
+// Only the expiry and the last use of a session can change.
+func (repository *PgxSessionRepository) Update(ctx context.Context, session *entity.Session) error {
 	sql := `
-		UPDATE sessions 
-		SET id=$1, expired_at=$2
-		WHERE id = $3
-		RETURNING id, user_id, expired_at
-		`
-	return query(
+		UPDATE sessions
+		SET expired_at = $2, last_seen_at = $3
+		WHERE id = $1
+	`
+	return execute(
 		databasePayload[entity.Session]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{session.ID, session.ExpiredAt, session.UserID},
-			scan: func(_ *entity.Session) []any {
-				return []any{&session.ID, &session.UserID, &session.ExpiredAt}
-			},
+			args: []any{session.ID, session.ExpiredAt, session.LastSeenAt},
+			scan: nil,
 		},
 	)
 }
 
-func (repository *PgxSessionRepository) Delete(ctx context.Context, tx *pgx.Tx, id string) error {
+func (repository *PgxSessionRepository) Delete(ctx context.Context, id string) error {
 	sql := `
 		DELETE FROM sessions WHERE id = $1
 	`
 	return execute(
 		databasePayload[entity.Session]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
@@ -148,3 +174,49 @@ func (repository *PgxSessionRepository) Delete(ctx context.Context, tx *pgx.Tx,
 		},
 	)
 }
+
+func (repository *PgxSessionRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM sessions WHERE user_id = $1
+	`
+	return executeAny(
+		databasePayload[entity.Session]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+// Logs the user out of all sessions but the one with the given id.
+func (repository *PgxSessionRepository) DeleteAllByUserIDExcept(ctx context.Context, id int64, except string) error {
+	sql := `
+		DELETE FROM sessions WHERE user_id = $1 AND id <> $2
+	`
+	return executeAny(
+		databasePayload[entity.Session]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id, except},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	sql := `
+		DELETE FROM sessions WHERE expired_at <= $1
+	`
+	return executeCount(
+		databasePayload[entity.Session]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{now},
+			scan: nil,
+		},
+	)
+}
@@ -0,0 +1,45 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxSettingsRepository struct {
+	Database *PgxDatabase
+}
+
+func (repository *PgxSettingsRepository) Find(ctx context.Context) (*entity.Settings, error) {
+	sql := `
+		SELECT two_factor_level, updated_at FROM settings
+	`
+	return queryOne(
+		databasePayload[entity.Settings]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: nil,
+			scan: func(settings *entity.Settings) []any {
+				return []any{&settings.TwoFactorLevel, &settings.UpdatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxSettingsRepository) Update(ctx context.Context, settings *entity.Settings) error {
+	sql := `
+		UPDATE settings SET two_factor_level = $1, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return query(
+		databasePayload[entity.Settings]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{settings.TwoFactorLevel},
+			scan: func(_ *entity.Settings) []any {
+				return []any{&settings.UpdatedAt}
+			},
+		},
+	)
+}
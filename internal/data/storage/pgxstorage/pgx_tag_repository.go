@@ -0,0 +1,232 @@
+package pgxstorage
+
+import (
+	"context"
+	"slices"
+	"web_blog/internal/data/entity"
+	"web_blog/internal/data/storage"
+)
+
+type PgxTagRepository struct {
+	Database *PgxDatabase
+}
+
+// Only published posts are counted.
+const tagColumns = `tags.id, tags.name, tags.slug,
+	(
+		SELECT COUNT(*) FROM post_tags JOIN posts ON posts.id = post_tags.post_id
+		WHERE post_tags.tag_id = tags.id AND posts.status = 'published' AND posts.deleted_at IS NULL
+	) AS post_count`
+
+func (repository *PgxTagRepository) Create(ctx context.Context, tag *entity.Tag) error {
+	sql := `
+		INSERT INTO tags (name, slug)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+	tag.Slug = entity.Slugify(tag.Name)
+
+	return query(
+		databasePayload[entity.Tag]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{tag.Name, tag.Slug},
+			scan: func(_ *entity.Tag) []any {
+				return []any{&tag.ID}
+			},
+		},
+	)
+}
+
+func (repository *PgxTagRepository) Find(ctx context.Context, id int64) (*entity.Tag, error) {
+	sql := `
+		SELECT ` + tagColumns + ` FROM tags WHERE id = $1
+	`
+	return queryOne(
+		databasePayload[entity.Tag]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(tag *entity.Tag) []any {
+				return []any{&tag.ID, &tag.Name, &tag.Slug, &tag.PostCount}
+			},
+		},
+	)
+}
+
+func (repository *PgxTagRepository) FindBySlug(ctx context.Context, slug string) (*entity.Tag, error) {
+	sql := `
+		SELECT ` + tagColumns + ` FROM tags WHERE slug = $1
+	`
+	return queryOne(
+		databasePayload[entity.Tag]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{slug},
+			scan: func(tag *entity.Tag) []any {
+				return []any{&tag.ID, &tag.Name, &tag.Slug, &tag.PostCount}
+			},
+		},
+	)
+}
+
+// Most used tags first.
+func (repository *PgxTagRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Tag], error) {
+	sql, args := newSelectQuery("tags", `SELECT `+tagColumns+` FROM tags`).
+		paginateBy("post_count DESC, tags.slug", filter)
+
+	return queryPage(
+		databasePayload[entity.Tag]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: args,
+			scan: func(tag *entity.Tag) []any {
+				return []any{&tag.ID, &tag.Name, &tag.Slug, &tag.PostCount}
+			},
+		},
+		filter,
+		nil,
+	)
+}
+
+// Renames the tag, which also changes its slug.
+func (repository *PgxTagRepository) Update(ctx context.Context, tag *entity.Tag) error {
+	sql := `
+		UPDATE tags
+		SET name = $1, slug = $2
+		WHERE id = $3
+	`
+	tag.Slug = entity.Slugify(tag.Name)
+
+	return execute(
+		databasePayload[entity.Tag]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{tag.Name, tag.Slug, tag.ID},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxTagRepository) Delete(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM tags WHERE id = $1
+	`
+	return execute(
+		databasePayload[entity.Tag]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+// Replaces the tags of a post with post.Tags, read as tag names. Missing tags
+// are created, and post.Tags is set to the slugs of the post's tags.
+func (repository *PgxTagRepository) SetPostTags(ctx context.Context, post *entity.Post) error {
+	var names []string
+	var slugs []string
+
+	for _, name := range post.Tags {
+		if slug := entity.Slugify(name); slug != "" && !slices.Contains(slugs, slug) {
+			names = append(names, name)
+			slugs = append(slugs, slug)
+		}
+	}
+
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		var err error
+
+		err = executeAny(databasePayload[entity.Tag]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql: `
+				INSERT INTO tags (name, slug)
+				SELECT * FROM unnest($1::text[], $2::text[])
+				ON CONFLICT (slug) DO NOTHING
+			`,
+			args: []any{names, slugs},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = executeAny(databasePayload[entity.Tag]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql:  `DELETE FROM post_tags WHERE post_id = $1`,
+			args: []any{post.ID},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = executeAny(databasePayload[entity.Tag]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql: `
+				INSERT INTO post_tags (post_id, tag_id)
+				SELECT $1, tags.id FROM tags WHERE tags.slug = ANY($2::text[])
+			`,
+			args: []any{post.ID, slugs},
+		})
+		if err != nil {
+			return err
+		}
+
+		slices.Sort(slugs)
+		post.Tags = append([]string{}, slugs...)
+		return nil
+	})
+}
+
+// Moves the posts of a tag over to another tag and deletes it.
+func (repository *PgxTagRepository) Merge(ctx context.Context, from *entity.Tag, into *entity.Tag) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		var err error
+
+		err = executeAny(databasePayload[entity.Tag]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql: `
+				INSERT INTO post_tags (post_id, tag_id)
+				SELECT post_id, $2 FROM post_tags WHERE tag_id = $1
+				ON CONFLICT DO NOTHING
+			`,
+			args: []any{from.ID, into.ID},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = execute(databasePayload[entity.Tag]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql:  `DELETE FROM tags WHERE id = $1`,
+			args: []any{from.ID},
+		})
+		if err != nil {
+			return err
+		}
+
+		return query(databasePayload[entity.Tag]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql: `
+				SELECT COUNT(*) FROM post_tags JOIN posts ON posts.id = post_tags.post_id
+				WHERE post_tags.tag_id = $1 AND posts.status = 'published' AND posts.deleted_at IS NULL
+			`,
+			args: []any{into.ID},
+			scan: func(_ *entity.Tag) []any {
+				return []any{&into.PostCount}
+			},
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package pgxstorage
+
+import (
+	"context"
+	"web_blog/internal/data/entity"
+)
+
+type PgxTwoFactorRepository struct {
+	Database *PgxDatabase
+}
+
+// Replaces a pending secret of the user, enabled ones are duplicates.
+func (repository *PgxTwoFactorRepository) Create(ctx context.Context, twoFactor *entity.TwoFactor) error {
+	sql := `
+		INSERT INTO two_factors (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+		WHERE two_factors.enabled_at IS NULL
+		RETURNING last_step, enabled_at, created_at
+	`
+	return query(
+		databasePayload[entity.TwoFactor]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{twoFactor.UserID, twoFactor.Secret},
+			scan: func(_ *entity.TwoFactor) []any {
+				return []any{&twoFactor.LastStep, &twoFactor.EnabledAt, &twoFactor.CreatedAt}
+			},
+		},
+	)
+}
+
+func (repository *PgxTwoFactorRepository) Find(ctx context.Context, id int64) (*entity.TwoFactor, error) {
+	sql := `
+		SELECT user_id, secret, last_step, enabled_at, created_at FROM two_factors WHERE user_id = $1
+	`
+	return queryOne(
+		databasePayload[entity.TwoFactor]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: func(twoFactor *entity.TwoFactor) []any {
+				return []any{
+					&twoFactor.UserID,
+					&twoFactor.Secret,
+					&twoFactor.LastStep,
+					&twoFactor.EnabledAt,
+					&twoFactor.CreatedAt,
+				}
+			},
+		},
+	)
+}
+
+// Enables the pending secret of the user, with the step of the code that
+// confirmed it as used.
+func (repository *PgxTwoFactorRepository) Enable(ctx context.Context, id int64, step int64) error {
+	sql := `
+		UPDATE two_factors SET enabled_at = NOW(), last_step = $2 WHERE user_id = $1 AND enabled_at IS NULL
+	`
+	return execute(
+		databasePayload[entity.TwoFactor]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id, step},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxTwoFactorRepository) Use(ctx context.Context, id int64, step int64) error {
+	sql := `
+		UPDATE two_factors SET last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_step < $2
+	`
+	return execute(
+		databasePayload[entity.TwoFactor]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id, step},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxTwoFactorRepository) Delete(ctx context.Context, id int64) error {
+	sql := `
+		DELETE FROM two_factors WHERE user_id = $1
+	`
+	return execute(
+		databasePayload[entity.TwoFactor]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
@@ -2,76 +2,59 @@ package pgxstorage
 
 import (
 	"context"
+	"errors"
+	"time"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx"
 )
 
 type PgxUserRepository struct {
 	Database *PgxDatabase
 }
 
-func (repository *PgxUserRepository) CreateWithVerification(
-	ctx context.Context,
-	tx *pgx.Tx,
-	vrepository storage.IVerificationRepository,
-	user *entity.User,
-) error {
-	return withTx(repository.Database.Connection, func(tx *pgx.Tx) error {
-		var verification *entity.Verification
-		var err error
-
-		if err = repository.Create(ctx, tx, user); err != nil {
-			return err
-		}
-
-		verification = &entity.Verification{UserID: user.ID}
+const userColumns = `users.id, users.role_id, users.email, users.username, users.password,
+	users.bio, users.avatar_url, users.verified, ` + userTwoFactorColumn + `, users.created_at, users.updated_at, users.deleted_at, users.deleted_by`
 
-		if err = vrepository.Create(ctx, tx, verification); err != nil {
-			return err
-		}
+const userTwoFactorColumn = `EXISTS (
+		SELECT 1 FROM two_factors WHERE two_factors.user_id = users.id AND two_factors.enabled_at IS NOT NULL
+	)`
 
-		return nil
-	})
+func scanUser(user *entity.User) []any {
+	return []any{
+		&user.ID,
+		&user.RoleID,
+		&user.Email,
+		&user.Username,
+		&user.Password.Hash,
+		&user.Bio,
+		&user.AvatarURL,
+		&user.Verified,
+		&user.TwoFactor,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
+		&user.DeletedBy,
+	}
 }
 
-func (repository *PgxUserRepository) Verify(
-	ctx context.Context,
-	tx *pgx.Tx,
-	vrepository storage.IVerificationRepository,
-	id uuid.UUID,
-	user *entity.User,
-) error {
-	return withTx(repository.Database.Connection, func(tx *pgx.Tx) error {
-		var err error
-
-		if err = repository.VerifyActive(ctx, tx, id, user); err != nil {
-			return err
-		}
-
-		if err = vrepository.DeleteAllByUserID(ctx, tx, user.ID); err != nil {
-			return err
-		}
-
-		return nil
-	})
-}
-
-func (repository *PgxUserRepository) VerifyActive(ctx context.Context, tx *pgx.Tx, id uuid.UUID, user *entity.User) error {
+func (repository *PgxUserRepository) Verify(ctx context.Context, id uuid.UUID, user *entity.User) error {
 	sql := `
-		UPDATE users SET verified = true, updated_at = NOW()
-		FROM verifications 
+		UPDATE users SET verified = true, email = COALESCE(verifications.email, users.email), updated_at = NOW()
+		FROM verifications
 		WHERE users.id = verifications.user_id
-		AND users.verified = false
-		AND verifications.expired_at > NOW() 
+		AND (users.verified = false OR verifications.email IS NOT NULL)
+		AND verifications.expired_at > NOW()
+		AND verifications.used_at IS NULL
 		AND verifications.id = $1
-		RETURNING users.id, users.role_id, users.email, users.username, users.verified, users.created_at, users.updated_at 
+		AND users.deleted_at IS NULL
+		RETURNING users.id, users.role_id, users.email, users.username, users.bio, users.avatar_url,
+			users.verified, ` + userTwoFactorColumn + `, users.created_at, users.updated_at
 	`
 	return query(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
@@ -81,7 +64,10 @@ func (repository *PgxUserRepository) VerifyActive(ctx context.Context, tx *pgx.T
 					&user.RoleID,
 					&user.Email,
 					&user.Username,
+					&user.Bio,
+					&user.AvatarURL,
 					&user.Verified,
+					&user.TwoFactor,
 					&user.CreatedAt,
 					&user.UpdatedAt,
 				}
@@ -90,18 +76,18 @@ func (repository *PgxUserRepository) VerifyActive(ctx context.Context, tx *pgx.T
 	)
 }
 
-func (repository *PgxUserRepository) Create(ctx context.Context, tx *pgx.Tx, user *entity.User) error {
+func (repository *PgxUserRepository) Create(ctx context.Context, user *entity.User) error {
 	sql := `
-		INSERT INTO users (role_id, email, username, password) 
-		VALUES ($1, $2, $3, $4) 
+		INSERT INTO users (role_id, email, username, password, bio, avatar_url)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, verified, created_at, updated_at
 	`
 	return query(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{user.RoleID, user.Email, user.Username, user.Password.Hash},
+			args: []any{user.RoleID, user.Email, user.Username, user.Password.Hash, user.Bio, user.AvatarURL},
 			scan: func(_ *entity.User) []any {
 				return []any{
 					&user.ID,
@@ -114,99 +100,70 @@ func (repository *PgxUserRepository) Create(ctx context.Context, tx *pgx.Tx, use
 	)
 }
 
-func (repository *PgxUserRepository) Find(ctx context.Context, tx *pgx.Tx, id int64) (*entity.User, error) {
+func (repository *PgxUserRepository) Find(ctx context.Context, id int64) (*entity.User, error) {
 	sql := `
-		SELECT * FROM users WHERE id = $1
+		SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL
 	`
 	return queryOne(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
-			scan: func(user *entity.User) []any {
-				return []any{
-					&user.ID,
-					&user.RoleID,
-					&user.Email,
-					&user.Username,
-					&user.Password.Hash,
-					&user.Verified,
-					&user.CreatedAt,
-					&user.UpdatedAt,
-				}
-			},
+			scan: scanUser,
 		},
 	)
 }
 
-func (repository *PgxUserRepository) FindByEmail(ctx context.Context, tx *pgx.Tx, email string) (*entity.User, error) {
+func (repository *PgxUserRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
 	sql := `
-		SELECT * FROM users WHERE users.email = $1
+		SELECT ` + userColumns + ` FROM users WHERE users.email = $1 AND users.deleted_at IS NULL
 	`
 	return queryOne(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{email},
-			scan: func(user *entity.User) []any {
-				return []any{
-					&user.ID,
-					&user.RoleID,
-					&user.Email,
-					&user.Username,
-					&user.Password.Hash,
-					&user.Verified,
-					&user.CreatedAt,
-					&user.UpdatedAt,
-				}
-			},
+			scan: scanUser,
 		},
 	)
 }
 
-func (repository *PgxUserRepository) FindAll(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery) ([]*entity.User, error) {
-	sql := `
-		SELECT * FROM users
-		LIMIT $1
-		OFFSET $2
-	`
-	return queryAll(
+func (repository *PgxUserRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.User], error) {
+	sql, args := newSelectQuery("users", `SELECT `+userColumns+` FROM users`).
+		paginate(filter)
+
+	return queryPage(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{filter.Limit, filter.Offset},
-			scan: func(user *entity.User) []any {
-				return []any{
-					&user.ID,
-					&user.RoleID,
-					&user.Email,
-					&user.Username,
-					&user.Password.Hash,
-					&user.Verified,
-					&user.CreatedAt,
-					&user.UpdatedAt,
-				}
-			},
+			args: args,
+			scan: scanUser,
 		},
+		filter,
+		storage.UserPosition,
 	)
 }
 
-func (repository *PgxUserRepository) Update(ctx context.Context, tx *pgx.Tx, user *entity.User) error {
+func (repository *PgxUserRepository) Update(ctx context.Context, user *entity.User) error {
 	sql := `
 		UPDATE users 
-		SET role_id = $1, email = $2, username = $3, password = $4, verified = $5 updated_at = NOW()
-		WHERE id = $6
+		SET role_id = $1, email = $2, username = $3, password = $4, bio = $5, avatar_url = $6,
+			verified = $7, updated_at = NOW()
+		WHERE id = $8
 		RETURNING updated_at
 	`
 	return query(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{user.RoleID, user.Email, user.Username, user.Password, user.Verified, user.ID},
+			args: []any{
+				user.RoleID, user.Email, user.Username, user.Password.Hash,
+				user.Bio, user.AvatarURL, user.Verified, user.ID,
+			},
 			scan: func(_ *entity.User) []any {
 				return []any{&user.UpdatedAt}
 			},
@@ -214,13 +171,109 @@ func (repository *PgxUserRepository) Update(ctx context.Context, tx *pgx.Tx, use
 	)
 }
 
-func (repository *PgxUserRepository) Delete(ctx context.Context, tx *pgx.Tx, id int64) error {
+func (repository *PgxUserRepository) SoftDelete(ctx context.Context, id int64, by int64) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		sql := `
+			UPDATE users SET deleted_at = NOW(), deleted_by = $2
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING deleted_at
+		`
+		user, err := queryOne(
+			databasePayload[entity.User]{
+				conn: database.conn(),
+				ctx:  ctx,
+				sql:  sql,
+				args: []any{id, by},
+				scan: func(user *entity.User) []any {
+					return []any{&user.DeletedAt}
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return moveUserContent(ctx, database, id, nil, user.DeletedAt, &by)
+	})
+}
+
+func (repository *PgxUserRepository) Restore(ctx context.Context, id int64) error {
+	return repository.Database.within(ctx, func(database *PgxDatabase) error {
+		sql := `
+			UPDATE users SET deleted_at = NULL, deleted_by = NULL
+			FROM users AS deleted
+			WHERE users.id = $1 AND deleted.id = users.id AND deleted.deleted_at IS NOT NULL
+			RETURNING deleted.deleted_at
+		`
+		user, err := queryOne(
+			databasePayload[entity.User]{
+				conn: database.conn(),
+				ctx:  ctx,
+				sql:  sql,
+				args: []any{id},
+				scan: func(user *entity.User) []any {
+					return []any{&user.DeletedAt}
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return moveUserContent(ctx, database, id, user.DeletedAt, nil, nil)
+	})
+}
+
+func (repository *PgxUserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	sql := `
+		DELETE FROM users WHERE deleted_at < $1
+	`
+	return executeCount(
+		databasePayload[entity.User]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{before},
+			scan: nil,
+		},
+	)
+}
+
+// Moves the posts of the user and the comments by them or on their posts
+// between deleted_at values, the same way moveComments does. A user without
+// any content is not an error.
+func moveUserContent(ctx context.Context, database *PgxDatabase, id int64, from *time.Time, to *time.Time, by *int64) error {
+	sql := `
+		UPDATE posts SET deleted_at = $3::timestamptz, deleted_by = $4::bigint
+		WHERE user_id = $1 AND deleted_at IS NOT DISTINCT FROM $2::timestamptz
+	`
+	err := executeAny(
+		databasePayload[entity.Post]{
+			conn: database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id, from, to, by},
+			scan: nil,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = moveComments(ctx, database, "comments.user_id = $1 OR posts.user_id = $1", id, from, to, by)
+	if err != nil && !errors.Is(err, storage.ErrorNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (repository *PgxUserRepository) Delete(ctx context.Context, id int64) error {
 	sql := `
 		DELETE FROM users WHERE id = $1 
 	`
 	return execute(
 		databasePayload[entity.User]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
@@ -2,107 +2,150 @@ package pgxstorage
 
 import (
 	"context"
+	"time"
 	"web_blog/internal/data/entity"
 	"web_blog/internal/data/storage"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx"
 )
 
 type PgxVerificationRepository struct {
 	Database *PgxDatabase
 }
 
-func (repository *PgxVerificationRepository) Create(ctx context.Context, tx *pgx.Tx, verification *entity.Verification) error {
+const verificationColumns = `verifications.id, verifications.user_id, verifications.email, verifications.expired_at,
+	verifications.used_at, verifications.created_at`
+
+func scanVerification(verification *entity.Verification) []any {
+	return []any{
+		&verification.UUID,
+		&verification.UserID,
+		&verification.Email,
+		&verification.ExpiredAt,
+		&verification.UsedAt,
+		&verification.CreatedAt,
+	}
+}
+
+func (repository *PgxVerificationRepository) Create(ctx context.Context, verification *entity.Verification) error {
 	sql := `
-		INSERT INTO verifications (user_id) 
-		VALUES ($1) 
-		RETURNING id, expired_at
+		INSERT INTO verifications (user_id, email)
+		VALUES ($1, $2)
+		RETURNING id, expired_at, created_at
 	`
 	return query(
 		databasePayload[entity.Verification]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{verification.UserID},
+			args: []any{verification.UserID, verification.Email},
 			scan: func(_ *entity.Verification) []any {
-				return []any{&verification.UUID, &verification.ExpiredAt}
+				return []any{&verification.UUID, &verification.ExpiredAt, &verification.CreatedAt}
 			},
 		},
 	)
 }
 
-func (repository *PgxVerificationRepository) Find(ctx context.Context, tx *pgx.Tx, id uuid.UUID) (*entity.Verification, error) {
+func (repository *PgxVerificationRepository) Find(ctx context.Context, id uuid.UUID) (*entity.Verification, error) {
 	sql := `
-		SELECT * FROM verifications WHERE id = $1
+		SELECT ` + verificationColumns + ` FROM verifications WHERE id = $1
 	`
 	return queryOne(
 		databasePayload[entity.Verification]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
-			scan: func(verifiction *entity.Verification) []any {
-				return []any{&verifiction.UUID, &verifiction.UserID, &verifiction.ExpiredAt}
-			},
+			scan: scanVerification,
+		},
+	)
+}
+
+// Used verifications are included.
+func (repository *PgxVerificationRepository) FindLatestByUserID(ctx context.Context, id int64) (*entity.Verification, error) {
+	sql := `
+		SELECT ` + verificationColumns + ` FROM verifications
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	return queryOne(
+		databasePayload[entity.Verification]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: scanVerification,
 		},
 	)
 }
 
 func (repository *PgxVerificationRepository) FindAllByUserID(
 	ctx context.Context,
-	tx *pgx.Tx,
 	filter storage.FilterQuery,
 	id int64,
-) ([]*entity.Verification, error) {
-	sql := `
-		SELECT * FROM verifications WHERE id = $1
-		LIMIT $2
-		OFFSET $3
-	`
-	return queryAll(
+) (*storage.Page[entity.Verification], error) {
+	sql, args := newSelectQuery("verifications", `SELECT `+verificationColumns+` FROM verifications`).
+		where("verifications.user_id = ?", id).
+		paginateBy("verifications.id", filter)
+
+	return queryPage(
 		databasePayload[entity.Verification]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{id, filter.Limit, filter.Offset},
-			scan: func(verification *entity.Verification) []any {
-				return []any{&verification.UUID, &verification.UserID, &verification.ExpiredAt}
-			},
+			args: args,
+			scan: scanVerification,
 		},
+		filter,
+		nil,
 	)
 }
 
-func (repository *PgxVerificationRepository) FindAll(ctx context.Context, tx *pgx.Tx, filter storage.FilterQuery) ([]*entity.Verification, error) {
-	sql := `
-		SELECT * FROM verifications
-		LIMIT $1
-		OFFSET $2
-	`
-	return queryAll(
+func (repository *PgxVerificationRepository) FindAll(ctx context.Context, filter storage.FilterQuery) (*storage.Page[entity.Verification], error) {
+	sql, args := newSelectQuery("verifications", `SELECT `+verificationColumns+` FROM verifications`).
+		paginateBy("verifications.id", filter)
+
+	return queryPage(
 		databasePayload[entity.Verification]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
-			args: []any{filter.Limit, filter.Offset},
-			scan: func(verification *entity.Verification) []any {
-				return []any{&verification.UUID, &verification.UserID, &verification.ExpiredAt}
-			},
+			args: args,
+			scan: scanVerification,
 		},
+		filter,
+		nil,
 	)
 }
 
-func (repository *PgxVerificationRepository) Update(ctx context.Context, tx *pgx.Tx, user_ver *entity.Verification) error {
+func (repository *PgxVerificationRepository) Update(ctx context.Context, user_ver *entity.Verification) error {
 	return nil
 }
 
-func (repository *PgxVerificationRepository) Delete(ctx context.Context, tx *pgx.Tx, id uuid.UUID) error {
+// Marks the pending verifications of the user as used.
+func (repository *PgxVerificationRepository) UseAllByUserID(ctx context.Context, id int64) error {
+	sql := `
+		UPDATE verifications SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL
+	`
+	return executeAny(
+		databasePayload[entity.Verification]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{id},
+			scan: nil,
+		},
+	)
+}
+
+func (repository *PgxVerificationRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	sql := `
 		DELETE FROM verifications WHERE id = $1 
 	`
 	return execute(
 		databasePayload[entity.Verification]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
@@ -111,13 +154,13 @@ func (repository *PgxVerificationRepository) Delete(ctx context.Context, tx *pgx
 	)
 }
 
-func (repository *PgxVerificationRepository) DeleteAllByUserID(ctx context.Context, tx *pgx.Tx, id int64) error {
+func (repository *PgxVerificationRepository) DeleteAllByUserID(ctx context.Context, id int64) error {
 	sql := `
 		DELETE FROM verifications WHERE user_id = $1 
 	`
 	return execute(
 		databasePayload[entity.Verification]{
-			conn: provideConn(tx, repository.Database.Connection),
+			conn: repository.Database.conn(),
 			ctx:  ctx,
 			sql:  sql,
 			args: []any{id},
@@ -125,3 +168,18 @@ func (repository *PgxVerificationRepository) DeleteAllByUserID(ctx context.Conte
 		},
 	)
 }
+
+func (repository *PgxVerificationRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	sql := `
+		DELETE FROM verifications WHERE expired_at <= $1
+	`
+	return executeCount(
+		databasePayload[entity.Verification]{
+			conn: repository.Database.conn(),
+			ctx:  ctx,
+			sql:  sql,
+			args: []any{now},
+			scan: nil,
+		},
+	)
+}
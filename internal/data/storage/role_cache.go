@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+var RoleCacheTTL = time.Minute
+
+// Caches roles with their permissions, which are read on every authorized
+// request but rarely change. Writes made through the cache clear it, writes
+// made elsewhere, like by other instances, are seen once entries expire.
+type RoleCache struct {
+	IRoleRepository
+
+	mutex  sync.RWMutex
+	ttl    time.Duration
+	byID   map[int64]cachedRole
+	byName map[string]cachedRole
+}
+
+type cachedRole struct {
+	role    entity.Role
+	expires time.Time
+}
+
+func NewRoleCache(roles IRoleRepository, ttl time.Duration) *RoleCache {
+	return &RoleCache{
+		IRoleRepository: roles,
+		ttl:             ttl,
+		byID:            map[int64]cachedRole{},
+		byName:          map[string]cachedRole{},
+	}
+}
+
+func (cache *RoleCache) Find(ctx context.Context, id int64) (*entity.Role, error) {
+	if role, ok := lookup(cache, cache.byID, id); ok {
+		return role, nil
+	}
+
+	return cache.load(cache.IRoleRepository.Find(ctx, id))
+}
+
+func (cache *RoleCache) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	if role, ok := lookup(cache, cache.byName, name); ok {
+		return role, nil
+	}
+
+	return cache.load(cache.IRoleRepository.FindByName(ctx, name))
+}
+
+func (cache *RoleCache) Create(ctx context.Context, role *entity.Role) error {
+	defer cache.Clear()
+	return cache.IRoleRepository.Create(ctx, role)
+}
+
+func (cache *RoleCache) Update(ctx context.Context, role *entity.Role) error {
+	defer cache.Clear()
+	return cache.IRoleRepository.Update(ctx, role)
+}
+
+func (cache *RoleCache) Delete(ctx context.Context, id int64) error {
+	defer cache.Clear()
+	return cache.IRoleRepository.Delete(ctx, id)
+}
+
+func (cache *RoleCache) SetPermissions(ctx context.Context, role *entity.Role) error {
+	defer cache.Clear()
+	return cache.IRoleRepository.SetPermissions(ctx, role)
+}
+
+func (cache *RoleCache) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	clear(cache.byID)
+	clear(cache.byName)
+}
+
+// Callers get copies, so they can not change the cached roles.
+func (cache *RoleCache) load(role *entity.Role, err error) (*entity.Role, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := cachedRole{role: *role, expires: time.Now().Add(cache.ttl)}
+	entry.role.Permissions = slices.Clone(role.Permissions)
+	cache.byID[role.ID] = entry
+	cache.byName[role.Name] = entry
+
+	return role, nil
+}
+
+func lookup[K comparable](cache *RoleCache, entries map[K]cachedRole, key K) (*entity.Role, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	entry, ok := entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	role := entry.role
+	role.Permissions = slices.Clone(entry.role.Permissions)
+	return &role, true
+}
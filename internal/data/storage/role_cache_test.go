@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+// Roles kept in a map, counting the reads that get past the cache.
+type countingRoles struct {
+	IRoleRepository
+
+	mutex sync.Mutex
+	roles map[int64]entity.Role
+	finds int
+}
+
+func (repository *countingRoles) Find(ctx context.Context, id int64) (*entity.Role, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.finds++
+	role, ok := repository.roles[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	role.Permissions = slices.Clone(role.Permissions)
+	return &role, nil
+}
+
+func (repository *countingRoles) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.finds++
+	for _, role := range repository.roles {
+		if role.Name == name {
+			role.Permissions = slices.Clone(role.Permissions)
+			return &role, nil
+		}
+	}
+
+	return nil, ErrorNotFound
+}
+
+func (repository *countingRoles) Update(ctx context.Context, role *entity.Role) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored := repository.roles[role.ID]
+	stored.Name, stored.Level = role.Name, role.Level
+	repository.roles[role.ID] = stored
+	return nil
+}
+
+func (repository *countingRoles) SetPermissions(ctx context.Context, role *entity.Role) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored := repository.roles[role.ID]
+	stored.Permissions = slices.Clone(role.Permissions)
+	repository.roles[role.ID] = stored
+	return nil
+}
+
+func (repository *countingRoles) reads() int {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return repository.finds
+}
+
+func newCountingRoles() *countingRoles {
+	return &countingRoles{roles: map[int64]entity.Role{
+		1: {ID: 1, Level: 1, Name: "user", Permissions: []string{entity.PermissionPostsCreate}},
+		2: {ID: 2, Level: 2, Name: "moderator", Permissions: []string{entity.PermissionContentModerate}},
+	}}
+}
+
+func TestRoleCacheHits(t *testing.T) {
+	ctx := context.Background()
+	repository := newCountingRoles()
+	cache := NewRoleCache(repository, time.Minute)
+
+	for range 3 {
+		role, err := cache.Find(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if role.Name != "user" || !role.Can(entity.PermissionPostsCreate) {
+			t.Fatalf("got %+v", role)
+		}
+
+		// Changing a returned copy leaves the cached role alone.
+		role.Permissions[0] = entity.PermissionRolesManage
+	}
+
+	// Roles loaded by id are cached by name too.
+	if role, err := cache.FindByName(ctx, "user"); err != nil || role.ID != 1 || !role.Can(entity.PermissionPostsCreate) {
+		t.Errorf("got %+v, %v", role, err)
+	}
+
+	if reads := repository.reads(); reads != 1 {
+		t.Errorf("got %d reads, want 1", reads)
+	}
+
+	// Missing roles are not cached.
+	for range 2 {
+		if _, err := cache.Find(ctx, 9); err != ErrorNotFound {
+			t.Errorf("got %v, want %v", err, ErrorNotFound)
+		}
+	}
+
+	if reads := repository.reads(); reads != 3 {
+		t.Errorf("got %d reads, want 3", reads)
+	}
+}
+
+func TestRoleCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		update func(*RoleCache, *entity.Role) error
+		check  func(*entity.Role) bool
+	}{
+		{
+			name: "role update",
+			update: func(cache *RoleCache, role *entity.Role) error {
+				role.Level = 5
+				return cache.Update(ctx, role)
+			},
+			check: func(role *entity.Role) bool { return role.Level == 5 },
+		},
+		{
+			name: "permission update",
+			update: func(cache *RoleCache, role *entity.Role) error {
+				role.Permissions = []string{entity.PermissionTagsManage}
+				return cache.SetPermissions(ctx, role)
+			},
+			check: func(role *entity.Role) bool {
+				return role.Can(entity.PermissionTagsManage) && !role.Can(entity.PermissionContentModerate)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := NewRoleCache(newCountingRoles(), time.Minute)
+
+			role, err := cache.Find(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Cached under both keys before the update.
+			if _, err := cache.FindByName(ctx, "moderator"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := test.update(cache, role); err != nil {
+				t.Fatal(err)
+			}
+
+			if role, err := cache.Find(ctx, 2); err != nil || !test.check(role) {
+				t.Errorf("got %+v, %v by id", role, err)
+			}
+
+			if role, err := cache.FindByName(ctx, "moderator"); err != nil || !test.check(role) {
+				t.Errorf("got %+v, %v by name", role, err)
+			}
+		})
+	}
+}
+
+// Updates made elsewhere are seen once entries expire.
+func TestRoleCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	repository := newCountingRoles()
+	cache := NewRoleCache(repository, 0)
+
+	if _, err := cache.Find(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repository.Update(ctx, &entity.Role{ID: 1, Level: 4, Name: "user"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if role, err := cache.Find(ctx, 1); err != nil || role.Level != 4 {
+		t.Errorf("got %+v, %v from an expired cache", role, err)
+	}
+}
+
+// Run with -race, readers and writers share the cache.
+func TestRoleCacheConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRoleCache(newCountingRoles(), time.Minute)
+
+	var group sync.WaitGroup
+	for i := range 8 {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			for j := range 100 {
+				role, err := cache.Find(ctx, int64(1+(i+j)%2))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if _, err := cache.FindByName(ctx, role.Name); err != nil {
+					t.Error(err)
+					return
+				}
+
+				if j%10 == 0 {
+					role.Permissions = append(role.Permissions, entity.PermissionTagsManage)
+					if err := cache.SetPermissions(ctx, role); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	group.Wait()
+
+	for _, id := range []int64{1, 2} {
+		if role, err := cache.Find(ctx, id); err != nil || !role.Can(entity.PermissionTagsManage) {
+			t.Errorf("got %+v, %v", role, err)
+		}
+	}
+}
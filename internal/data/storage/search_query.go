@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	SearchPosts    string = "posts"
+	SearchComments string = "comments"
+)
+
+// Search results are ranked by relevance and paged by offset.
+var SearchListing = Listing{}
+
+type SearchQuery struct {
+	Text  string   `json:"q" validate:"required,max=256"`
+	Types []string `json:"type" validate:"required,dive,oneof=posts comments"`
+	// Only searches approved posts and their approved comments.
+	Approved bool `json:"-" validate:"-"`
+}
+
+func (searchQuery *SearchQuery) Parse(r *http.Request) error {
+	query := r.URL.Query()
+
+	searchQuery.Text = strings.TrimSpace(query.Get("q"))
+	searchQuery.Types = parseTypes(query.Get("type"), SearchPosts, SearchComments)
+
+	return nil
+}
+
+// Splits a comma separated list of types, which are all types when empty.
+func parseTypes(value string, all ...string) []string {
+	var types []string
+	if value == "" {
+		return all
+	}
+
+	for _, kind := range strings.Split(value, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			types = append(types, kind)
+		}
+	}
+
+	return types
+}
+
+func (searchQuery *SearchQuery) Includes(kind string) bool {
+	return slices.Contains(searchQuery.Types, kind)
+}
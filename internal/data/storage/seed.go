@@ -80,7 +80,7 @@ func SeedUsers(repository IUserRepository) error {
 				},
 			}
 
-			if err := repository.Create(context.TODO(), nil, user); err != nil {
+			if err := repository.Create(context.TODO(), user); err != nil {
 				return err
 			}
 		}
@@ -99,9 +99,10 @@ func SeedPosts(repository IPostRepository) error {
 				UserID:  rand.Int63n(int64(userAmount-2)) + 1,
 				Title:   p.Title,
 				Content: p.Body,
+				Status:  entity.PostPublished,
 			}
 
-			if err := repository.Create(response.Request.Context(), nil, post); err != nil {
+			if err := repository.Create(response.Request.Context(), post); err != nil {
 				return err
 			}
 		}
@@ -122,7 +123,7 @@ func SeedComments(repository ICommentRepository) error {
 				Content: c.Body,
 			}
 
-			if err := repository.Create(response.Request.Context(), nil, comment); err != nil {
+			if err := repository.Create(response.Request.Context(), comment); err != nil {
 				return err
 			}
 		}
@@ -133,7 +134,7 @@ func SeedComments(repository ICommentRepository) error {
 
 func SeedUserVerifications(repository IVerificationRepository) error {
 	for i := 0; i < commentAmmount; i++ {
-		if err := repository.Create(context.TODO(), nil, &entity.Verification{
+		if err := repository.Create(context.TODO(), &entity.Verification{
 			UserID: rand.Int63n(int64(userAmount-2)) + 1,
 		}); err != nil {
 			return err
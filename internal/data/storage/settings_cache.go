@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+var SettingsCacheTTL = time.Minute
+
+// Caches the settings, which are read on authenticated requests. Like the role
+// cache, updates made through it clear it, updates made elsewhere are seen once
+// it expires.
+type SettingsCache struct {
+	ISettingsRepository
+
+	mutex    sync.RWMutex
+	ttl      time.Duration
+	settings *entity.Settings
+	expires  time.Time
+}
+
+func NewSettingsCache(settings ISettingsRepository, ttl time.Duration) *SettingsCache {
+	return &SettingsCache{ISettingsRepository: settings, ttl: ttl}
+}
+
+// Callers get copies, so they can not change the cached settings.
+func (cache *SettingsCache) Find(ctx context.Context) (*entity.Settings, error) {
+	cache.mutex.RLock()
+	if cache.settings != nil && time.Now().Before(cache.expires) {
+		settings := *cache.settings
+		cache.mutex.RUnlock()
+		return &settings, nil
+	}
+	cache.mutex.RUnlock()
+
+	settings, err := cache.ISettingsRepository.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cached := *settings
+	cache.settings = &cached
+	cache.expires = time.Now().Add(cache.ttl)
+
+	return settings, nil
+}
+
+func (cache *SettingsCache) Update(ctx context.Context, settings *entity.Settings) error {
+	defer cache.Clear()
+	return cache.ISettingsRepository.Update(ctx, settings)
+}
+
+func (cache *SettingsCache) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.settings = nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+	"web_blog/internal/data/entity"
+)
+
+// Counts the reads that get past the cache.
+type countingSettings struct {
+	settings entity.Settings
+	finds    int
+}
+
+func (repository *countingSettings) Find(ctx context.Context) (*entity.Settings, error) {
+	repository.finds++
+	settings := repository.settings
+	return &settings, nil
+}
+
+func (repository *countingSettings) Update(ctx context.Context, settings *entity.Settings) error {
+	repository.settings = *settings
+	return nil
+}
+
+func TestSettingsCache(t *testing.T) {
+	ctx := context.Background()
+	repository := &countingSettings{settings: entity.Settings{TwoFactorLevel: 2}}
+	cache := NewSettingsCache(repository, time.Minute)
+
+	for range 3 {
+		settings, err := cache.Find(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if settings.TwoFactorLevel != 2 {
+			t.Fatalf("got level %d", settings.TwoFactorLevel)
+		}
+
+		// Changing a returned copy leaves the cached settings alone.
+		settings.TwoFactorLevel = 9
+	}
+
+	if repository.finds != 1 {
+		t.Errorf("got %d reads, want 1", repository.finds)
+	}
+
+	if err := cache.Update(ctx, &entity.Settings{TwoFactorLevel: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if settings, _ := cache.Find(ctx); settings.TwoFactorLevel != 3 || repository.finds != 2 {
+		t.Errorf("got level %d after %d reads, want the update", settings.TwoFactorLevel, repository.finds)
+	}
+
+	// Updates made elsewhere are seen once the cache expires.
+	expiring := NewSettingsCache(repository, 0)
+	expiring.Find(ctx)
+	repository.settings.TwoFactorLevel = 4
+
+	if settings, _ := expiring.Find(ctx); settings.TwoFactorLevel != 4 {
+		t.Errorf("got level %d from an expired cache", settings.TwoFactorLevel)
+	}
+}
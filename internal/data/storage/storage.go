@@ -7,7 +7,6 @@ import (
 	"web_blog/internal/data/entity"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx"
 )
 
 var (
@@ -19,56 +18,226 @@ var (
 type Database interface {
 	Open(context.Context, any) error
 	Close(context.Context) error
+	WithinTx(context.Context, func(*Storage) error) error
 }
 
 type IRepository[T any, ID any] interface {
-	Create(context.Context, *pgx.Tx, *T) error
-	Find(context.Context, *pgx.Tx, ID) (*T, error)
-	FindAll(context.Context, *pgx.Tx, FilterQuery) ([]*T, error)
-	Update(context.Context, *pgx.Tx, *T) error
-	Delete(context.Context, *pgx.Tx, ID) error
+	Create(context.Context, *T) error
+	Find(context.Context, ID) (*T, error)
+	FindAll(context.Context, FilterQuery) (*Page[T], error)
+	Update(context.Context, *T) error
+	Delete(context.Context, ID) error
 }
 
+// Soft deleted rows are only found by listing the trash, until they are
+// restored or purged. Soft deleting a row also soft deletes the rows that
+// would cascade from it, which share its deleted_at and are restored with it.
+// Rows can not be restored while a row they belong to is deleted. Purging
+// deletes rows soft deleted before the given time, with their cascades.
+type ITrashRepository[ID any] interface {
+	SoftDelete(context.Context, ID, int64) error
+	Restore(context.Context, ID) error
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// Deleting a user cascades to their posts and comments.
 type IUserRepository interface {
 	IRepository[entity.User, int64]
-	CreateWithVerification(context.Context, *pgx.Tx, IVerificationRepository, *entity.User) error
-	Verify(context.Context, *pgx.Tx, IVerificationRepository, uuid.UUID, *entity.User) error
-	FindByEmail(context.Context, *pgx.Tx, string) (*entity.User, error)
+	ITrashRepository[int64]
+	Verify(context.Context, uuid.UUID, *entity.User) error
+	FindByEmail(context.Context, string) (*entity.User, error)
 }
 
+// Deleting a post cascades to its comments. Approving a post verifies it
+// without counting as an update.
 type IPostRepository interface {
 	IRepository[entity.Post, int64]
-	FindAllByUserID(context.Context, *pgx.Tx, FilterQuery, int64) ([]*entity.Post, error)
+	ITrashRepository[int64]
+	Approve(context.Context, int64) error
+	FindAllByUserID(context.Context, FilterQuery, int64) (*Page[entity.Post], error)
+	FindAllByTagID(context.Context, FilterQuery, int64) (*Page[entity.Post], error)
+	PublishDue(context.Context, time.Time) (int64, error)
 }
 
+// Comments are read with their number of direct replies, and deleting one
+// cascades to its replies. Threads are pages of comments without a parent, or
+// of the replies of a comment, with up to the given number of replies nested
+// under each comment at every depth, oldest first, and matching the verified
+// filter of the threads. Approving a comment verifies it without counting as
+// an edit.
 type ICommentRepository interface {
 	IRepository[entity.Comment, int64]
-	FindAllByUserID(context.Context, *pgx.Tx, FilterQuery, int64) ([]*entity.Comment, error)
-	FindAllByPostID(context.Context, *pgx.Tx, FilterQuery, int64) ([]*entity.Comment, error)
+	ITrashRepository[int64]
+	Approve(context.Context, int64) error
+	FindAllByUserID(context.Context, FilterQuery, int64) (*Page[entity.Comment], error)
+	FindAllByPostID(context.Context, FilterQuery, int64) (*Page[entity.Comment], error)
+	FindAllByParentID(context.Context, FilterQuery, int64) (*Page[entity.Comment], error)
+	FindThreadsByPostID(context.Context, FilterQuery, int64, int) (*Page[entity.Comment], error)
+	FindThreadsByParentID(context.Context, FilterQuery, int64, int) (*Page[entity.Comment], error)
 }
 
 type IVerificationRepository interface {
 	IRepository[entity.Verification, uuid.UUID]
-	FindAllByUserID(context.Context, *pgx.Tx, FilterQuery, int64) ([]*entity.Verification, error)
-	DeleteAllByUserID(context.Context, *pgx.Tx, int64) error
+	FindAllByUserID(context.Context, FilterQuery, int64) (*Page[entity.Verification], error)
+	FindLatestByUserID(context.Context, int64) (*entity.Verification, error)
+	UseAllByUserID(context.Context, int64) error
+	DeleteAllByUserID(context.Context, int64) error
+	DeleteExpired(context.Context, time.Time) (int64, error)
 }
 
+// Sessions of banned users are not found with their user.
 type ISessionRepository interface {
 	IRepository[entity.Session, string]
-	FindWithUser(context.Context, *pgx.Tx, string) (*entity.Session, *entity.User, error)
+	FindWithUser(context.Context, string) (*entity.Session, *entity.User, error)
+	FindAllByUserID(context.Context, int64) ([]*entity.Session, error)
+	DeleteAllByUserID(context.Context, int64) error
+	DeleteAllByUserIDExcept(context.Context, int64, string) error
+	DeleteExpired(context.Context, time.Time) (int64, error)
+}
+
+// Refresh tokens are deleted with their session. Use only marks unused
+// tokens, and reports used ones as not found.
+type IRefreshTokenRepository interface {
+	Create(context.Context, *entity.RefreshToken) error
+	Find(context.Context, string) (*entity.RefreshToken, error)
+	Use(context.Context, string) error
+	Delete(context.Context, string) error
+}
+
+// Password resets are single use, so they are deleted instead of updated.
+type IPasswordResetRepository interface {
+	Create(context.Context, *entity.PasswordReset) error
+	Find(context.Context, string) (*entity.PasswordReset, error)
+	Delete(context.Context, string) error
+	DeleteAllByUserID(context.Context, int64) error
+}
+
+// Users have at most one TOTP secret, keyed by their id. Creating one replaces
+// a pending secret, but not an enabled one. Use only accepts steps after the
+// last used one, so codes can not be replayed.
+type ITwoFactorRepository interface {
+	Create(context.Context, *entity.TwoFactor) error
+	Find(context.Context, int64) (*entity.TwoFactor, error)
+	Enable(context.Context, int64, int64) error
+	Use(context.Context, int64, int64) error
+	Delete(context.Context, int64) error
+}
+
+// Recovery codes are single use, so they are deleted when used. Replacing the
+// codes of a user deletes the previous ones.
+type IRecoveryCodeRepository interface {
+	Replace(context.Context, int64, []string) error
+	Use(context.Context, int64, string) error
+	DeleteAllByUserID(context.Context, int64) error
+}
+
+// Login challenges are single use, so they are deleted instead of updated.
+type ILoginChallengeRepository interface {
+	Create(context.Context, *entity.LoginChallenge) error
+	Find(context.Context, string) (*entity.LoginChallenge, error)
+	Delete(context.Context, string) error
+	DeleteExpired(context.Context, time.Time) (int64, error)
+}
+
+// Failed logins are counted per key. Incrementing forgets failures made
+// before the given time, and locking only sets when the key is locked until.
+type ILoginFailureRepository interface {
+	Find(context.Context, string) (*entity.LoginFailure, error)
+	Increment(context.Context, string, time.Time) (*entity.LoginFailure, error)
+	Lock(context.Context, string, time.Time) error
+	Delete(context.Context, string) error
+	DeleteExpired(context.Context, time.Time) (int64, error)
+}
+
+// Audit entries are append only.
+type IAuditLogRepository interface {
+	Create(context.Context, *entity.AuditEntry) error
+}
+
+// Users have at most one ban, keyed by their id. Creating a ban replaces the
+// previous one.
+type IBanRepository interface {
+	Create(context.Context, *entity.Ban) error
+	Find(context.Context, int64) (*entity.Ban, error)
+	Delete(context.Context, int64) error
 }
 
+// Roles are read with their permissions.
 type IRoleRepository interface {
 	IRepository[entity.Role, int64]
-	FindByName(context.Context, *pgx.Tx, string) (*entity.Role, error)
+	FindByName(context.Context, string) (*entity.Role, error)
+	SetPermissions(context.Context, *entity.Role) error
+}
+
+// Permissions are seeded by migrations and can only be read.
+type IPermissionRepository interface {
+	FindAll(context.Context, FilterQuery) (*Page[entity.Permission], error)
+}
+
+// Comment edits are immutable, so they can only be created and read.
+type ICommentEditRepository interface {
+	Create(context.Context, *entity.CommentEdit) error
+	FindAllByCommentID(context.Context, FilterQuery, int64) (*Page[entity.CommentEdit], error)
+}
+
+// Revisions are immutable, so they can only be created and read.
+type IPostRevisionRepository interface {
+	Create(context.Context, *entity.PostRevision) error
+	Find(context.Context, int64) (*entity.PostRevision, error)
+	FindAllByPostID(context.Context, FilterQuery, int64) (*Page[entity.PostRevision], error)
+}
+
+type ITagRepository interface {
+	IRepository[entity.Tag, int64]
+	FindBySlug(context.Context, string) (*entity.Tag, error)
+	SetPostTags(context.Context, *entity.Post) error
+	Merge(context.Context, *entity.Tag, *entity.Tag) error
+}
+
+type ISearchRepository interface {
+	Search(context.Context, FilterQuery, SearchQuery) (*Page[entity.SearchResult], error)
+}
+
+// The queue holds the unverified posts and comments that are not deleted,
+// leaving out drafts and comments on them, oldest first.
+type IModerationRepository interface {
+	FindQueue(context.Context, FilterQuery, ModerationQuery) (*Page[entity.ModerationItem], error)
+}
+
+// Settings are a single row, which always exists.
+type ISettingsRepository interface {
+	Find(context.Context) (*entity.Settings, error)
+	Update(context.Context, *entity.Settings) error
 }
 
 type Storage struct {
-	Database      Database
-	Users         IUserRepository
-	Posts         IPostRepository
-	Comments      ICommentRepository
-	Verifications IVerificationRepository
-	Sessions      ISessionRepository
-	Roles         IRoleRepository
+	Database        Database
+	Users           IUserRepository
+	Posts           IPostRepository
+	Comments        ICommentRepository
+	CommentEdits    ICommentEditRepository
+	Verifications   IVerificationRepository
+	Sessions        ISessionRepository
+	RefreshTokens   IRefreshTokenRepository
+	Bans            IBanRepository
+	Roles           IRoleRepository
+	Permissions     IPermissionRepository
+	Search          ISearchRepository
+	Moderation      IModerationRepository
+	Tags            ITagRepository
+	Revisions       IPostRevisionRepository
+	PasswordResets  IPasswordResetRepository
+	TwoFactors      ITwoFactorRepository
+	RecoveryCodes   IRecoveryCodeRepository
+	LoginChallenges ILoginChallengeRepository
+	LoginFailures   ILoginFailureRepository
+	AuditLog        IAuditLogRepository
+	Settings        ISettingsRepository
+}
+
+// Runs fn with a storage whose repositories share a single transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise.
+// Calling WithinTx on a storage that is already inside a transaction joins it.
+func (storage *Storage) WithinTx(ctx context.Context, fn func(*Storage) error) error {
+	return storage.Database.WithinTx(ctx, fn)
 }
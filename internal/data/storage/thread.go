@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"net/http"
+	"strconv"
+	"web_blog/internal/data/entity"
+)
+
+const (
+	ThreadViewFlat string = "flat"
+	ThreadViewTree string = "tree"
+)
+
+// Comments are listed flat by default. As a tree, the listing pages the
+// threads and nests up to Replies replies under every comment.
+type ThreadQuery struct {
+	View    string `json:"view" validate:"oneof=flat tree"`
+	Replies int    `json:"replies" validate:"gte=0,lte=20"`
+}
+
+func (threadQuery *ThreadQuery) Parse(r *http.Request) error {
+	query := r.URL.Query()
+
+	if threadQuery.View == "" {
+		threadQuery.View = ThreadViewFlat
+	}
+
+	if v := query.Get("view"); v != "" {
+		threadQuery.View = v
+	}
+
+	if n := query.Get("replies"); n != "" {
+		replies, err := strconv.Atoi(n)
+		if err != nil {
+			return err
+		}
+
+		threadQuery.Replies = replies
+	}
+
+	return nil
+}
+
+func (threadQuery *ThreadQuery) Tree() bool {
+	return threadQuery.View == ThreadViewTree
+}
+
+// Appends replies to their parents, which are either roots or replies that
+// come before them. Replies whose parent is neither are dropped.
+func NestReplies(roots []*entity.Comment, replies []*entity.Comment) {
+	parents := make(map[int64]*entity.Comment, len(roots)+len(replies))
+	for _, comment := range roots {
+		parents[comment.ID] = comment
+	}
+
+	for _, reply := range replies {
+		if reply.ParentID == nil {
+			continue
+		}
+
+		if parent, ok := parents[*reply.ParentID]; ok {
+			parent.Replies = append(parent.Replies, reply)
+			parents[reply.ID] = reply
+		}
+	}
+}
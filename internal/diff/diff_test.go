@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []Line
+	}{
+		{
+			name: "both empty",
+			from: "",
+			to:   "",
+			want: []Line{},
+		},
+		{
+			name: "identical",
+			from: "a\nb",
+			to:   "a\nb",
+			want: []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			name: "from empty",
+			from: "",
+			to:   "a\nb",
+			want: []Line{{Insert, "a"}, {Insert, "b"}},
+		},
+		{
+			name: "to empty",
+			from: "a\nb",
+			to:   "",
+			want: []Line{{Delete, "a"}, {Delete, "b"}},
+		},
+		{
+			name: "insertion in the middle",
+			from: "a\nc",
+			to:   "a\nb\nc",
+			want: []Line{{Equal, "a"}, {Insert, "b"}, {Equal, "c"}},
+		},
+		{
+			name: "insertions at both ends",
+			from: "b",
+			to:   "a\nb\nc",
+			want: []Line{{Insert, "a"}, {Equal, "b"}, {Insert, "c"}},
+		},
+		{
+			name: "deletion in the middle",
+			from: "a\nb\nc",
+			to:   "a\nc",
+			want: []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}},
+		},
+		{
+			name: "edit in the middle",
+			from: "a\nb\nc",
+			to:   "a\nx\nc",
+			want: []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "x"}, {Equal, "c"}},
+		},
+		{
+			name: "every line changed",
+			from: "a\nb",
+			to:   "c\nd",
+			want: []Line{{Delete, "a"}, {Delete, "b"}, {Insert, "c"}, {Insert, "d"}},
+		},
+		{
+			name: "moved line",
+			from: "a\nb\nc",
+			to:   "b\nc\na",
+			want: []Line{{Delete, "a"}, {Equal, "b"}, {Equal, "c"}, {Insert, "a"}},
+		},
+		{
+			name: "windows line endings",
+			from: "a\r\nb",
+			to:   "a\nb",
+			want: []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			name: "trailing newline",
+			from: "a\n",
+			to:   "a",
+			want: []Line{{Equal, "a"}, {Delete, ""}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Lines(test.from, test.to); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
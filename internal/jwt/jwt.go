@@ -0,0 +1,170 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	HS256 = "HS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrorMalformed  = errors.New("token is malformed")
+	ErrorUnknownKey = errors.New("token is signed with an unknown key")
+	ErrorSignature  = errors.New("token signature is invalid")
+	ErrorExpired    = errors.New("token has expired")
+)
+
+var encoding = base64.RawURLEncoding
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Claims of RFC 7519 that are checked when parsing, embedded by the claims of
+// each token type.
+type RegisteredClaims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// Keys used for signing and verifying tokens. Tokens are signed with the
+// current key, and verified with the key named by their kid header, so older
+// keys can be kept around while the tokens they signed expire.
+type KeySet struct {
+	Current string
+	Keys    map[string]Key
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	Secret    []byte
+	Private   ed25519.PrivateKey
+}
+
+func (keys *KeySet) Sign(claims any) (string, error) {
+	key, ok := keys.Keys[keys.Current]
+	if !ok {
+		return "", ErrorUnknownKey
+	}
+
+	head, err := json.Marshal(header{Algorithm: key.Algorithm, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := encoding.EncodeToString(head) + "." + encoding.EncodeToString(payload)
+	signature, err := key.sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// Verifies the token and decodes its payload into claims. Tokens that are
+// expired or not yet valid at now are refused.
+func (keys *KeySet) Parse(token string, claims any, now time.Time) error {
+	var head header
+	var registered RegisteredClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrorMalformed
+	}
+
+	if err := decode(parts[0], &head); err != nil {
+		return err
+	}
+
+	key, ok := keys.Keys[head.KeyID]
+	if !ok {
+		return ErrorUnknownKey
+	}
+
+	// The algorithm comes from the key, never from the token.
+	if head.Algorithm != key.Algorithm {
+		return ErrorSignature
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrorMalformed
+	}
+
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return ErrorSignature
+	}
+
+	if err := decode(parts[1], &registered); err != nil {
+		return err
+	}
+
+	if registered.ExpiresAt != 0 && now.Unix() >= registered.ExpiresAt {
+		return ErrorExpired
+	}
+
+	if registered.NotBefore != 0 && now.Unix() < registered.NotBefore {
+		return ErrorExpired
+	}
+
+	return decode(parts[1], claims)
+}
+
+func (key Key) sign(data []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case EdDSA:
+		return ed25519.Sign(key.Private, data), nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", key.Algorithm)
+	}
+}
+
+func (key Key) verify(data []byte, signature []byte) bool {
+	switch key.Algorithm {
+	case HS256:
+		expected, _ := key.sign(data)
+		return hmac.Equal(expected, signature)
+	case EdDSA:
+		return ed25519.Verify(key.Private.Public().(ed25519.PublicKey), data, signature)
+	default:
+		return false
+	}
+}
+
+func decode(part string, value any) error {
+	data, err := encoding.DecodeString(part)
+	if err != nil {
+		return ErrorMalformed
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return ErrorMalformed
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	RegisteredClaims
+	Name string `json:"name"`
+}
+
+func testKeys(t *testing.T) *KeySet {
+	keys, err := ParseKeySet("hs:HS256:" + secret(32) + ",ed:EdDSA:" + secret(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+func TestSignParse(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	for _, id := range []string{"hs", "ed"} {
+		t.Run(id, func(t *testing.T) {
+			keys := testKeys(t)
+			keys.Current = id
+
+			claims := testClaims{RegisteredClaims: RegisteredClaims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()}, Name: "ann"}
+			token, err := keys.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var head header
+			if err := decode(strings.Split(token, ".")[0], &head); err != nil {
+				t.Fatal(err)
+			}
+
+			if head.KeyID != id || head.Algorithm != keys.Keys[id].Algorithm || head.Type != "JWT" {
+				t.Errorf("got header %+v", head)
+			}
+
+			var parsed testClaims
+			if err := keys.Parse(token, &parsed, now); err != nil {
+				t.Fatal(err)
+			}
+
+			if parsed != claims {
+				t.Errorf("got %+v, want %+v", parsed, claims)
+			}
+		})
+	}
+}
+
+func TestSignUnknownKey(t *testing.T) {
+	keys := testKeys(t)
+	keys.Current = "gone"
+
+	if _, err := keys.Sign(testClaims{}); !errors.Is(err, ErrorUnknownKey) {
+		t.Errorf("got %v, want %v", err, ErrorUnknownKey)
+	}
+}
+
+// Replaces one segment of a signed token.
+func replace(token string, index int, segment string) string {
+	parts := strings.Split(token, ".")
+	parts[index] = segment
+	return strings.Join(parts, ".")
+}
+
+func encode(t *testing.T, value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return encoding.EncodeToString(data)
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	keys := testKeys(t)
+
+	sign := func(id string, claims RegisteredClaims) string {
+		keys.Current = id
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	valid := sign("hs", RegisteredClaims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()})
+	signature := strings.Split(valid, ".")[2]
+
+	// Headers that name a key with an algorithm other than its own.
+	wrongAlgorithm := replace(valid, 0, encode(t, header{Algorithm: EdDSA, Type: "JWT", KeyID: "hs"}))
+	confused := replace(valid, 0, encode(t, header{Algorithm: HS256, Type: "JWT", KeyID: "ed"}))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "tampered signature", token: replace(valid, 2, strings.Repeat("A", len(signature))), want: ErrorSignature},
+		{name: "tampered payload", token: replace(valid, 1, encode(t, RegisteredClaims{Subject: "2", ExpiresAt: now.Add(time.Minute).Unix()})), want: ErrorSignature},
+		{name: "other key's signature", token: replace(valid, 2, strings.Split(sign("ed", RegisteredClaims{Subject: "1"}), ".")[2]), want: ErrorSignature},
+		{name: "algorithm not of the key", token: wrongAlgorithm, want: ErrorSignature},
+		{name: "algorithm confusion", token: confused, want: ErrorSignature},
+		{name: "no algorithm", token: replace(valid, 0, encode(t, header{Algorithm: "none", KeyID: "hs"})), want: ErrorSignature},
+		{name: "unknown kid", token: replace(valid, 0, encode(t, header{Algorithm: HS256, KeyID: "gone"})), want: ErrorUnknownKey},
+		{name: "no kid", token: replace(valid, 0, encode(t, header{Algorithm: HS256})), want: ErrorUnknownKey},
+		{name: "expired", token: sign("hs", RegisteredClaims{ExpiresAt: now.Unix()}), want: ErrorExpired},
+		{name: "not yet valid", token: sign("ed", RegisteredClaims{NotBefore: now.Add(time.Second).Unix()}), want: ErrorExpired},
+		{name: "two segments", token: strings.Join(strings.Split(valid, ".")[:2], "."), want: ErrorMalformed},
+		{name: "four segments", token: valid + ".", want: ErrorMalformed},
+		{name: "empty", token: "", want: ErrorMalformed},
+		{name: "header not base64", token: replace(valid, 0, "!!"), want: ErrorMalformed},
+		{name: "header not json", token: replace(valid, 0, encoding.EncodeToString([]byte("{"))), want: ErrorMalformed},
+		{name: "signature not base64", token: replace(valid, 2, "!!"), want: ErrorMalformed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var claims testClaims
+			if err := keys.Parse(test.token, &claims, now); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+// Registered claims that are not set are not checked, and the bounds of the
+// validity window are inclusive of nbf and exclusive of exp.
+func TestParseValidity(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	keys := testKeys(t)
+
+	tests := []struct {
+		name   string
+		claims RegisteredClaims
+		want   error
+	}{
+		{name: "no expiry", claims: RegisteredClaims{}, want: nil},
+		{name: "expires after now", claims: RegisteredClaims{ExpiresAt: now.Unix() + 1}, want: nil},
+		{name: "expires at now", claims: RegisteredClaims{ExpiresAt: now.Unix()}, want: ErrorExpired},
+		{name: "valid from now", claims: RegisteredClaims{NotBefore: now.Unix()}, want: nil},
+		{name: "valid after now", claims: RegisteredClaims{NotBefore: now.Unix() + 1}, want: ErrorExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := keys.Sign(test.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var claims RegisteredClaims
+			if err := keys.Parse(token, &claims, now); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
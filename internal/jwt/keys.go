@@ -0,0 +1,60 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Minimum length of HS256 secrets, as long as the hash.
+const minimumSecretLength = 32
+
+// Parses a comma separated list of id:algorithm:secret keys, with standard
+// base64 secrets. EdDSA secrets are ed25519 seeds. The first key signs new
+// tokens, the others are only used to verify tokens they signed before.
+func ParseKeySet(spec string) (*KeySet, error) {
+	keys := &KeySet{Keys: map[string]Key{}}
+
+	for _, entry := range strings.Split(spec, ",") {
+		fields := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, fmt.Errorf("key %q is not formatted as id:algorithm:secret", entry)
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", fields[0], err)
+		}
+
+		key := Key{ID: fields[0], Algorithm: fields[1]}
+		switch key.Algorithm {
+		case HS256:
+			if len(secret) < minimumSecretLength {
+				return nil, fmt.Errorf("key %q: secret must be at least %d bytes", key.ID, minimumSecretLength)
+			}
+
+			key.Secret = secret
+		case EdDSA:
+			if len(secret) != ed25519.SeedSize {
+				return nil, fmt.Errorf("key %q: seed must be %d bytes", key.ID, ed25519.SeedSize)
+			}
+
+			key.Private = ed25519.NewKeyFromSeed(secret)
+		default:
+			return nil, fmt.Errorf("key %q: unsupported algorithm %q", key.ID, key.Algorithm)
+		}
+
+		if _, ok := keys.Keys[key.ID]; ok {
+			return nil, fmt.Errorf("key %q is duplicated", key.ID)
+		}
+
+		if keys.Current == "" {
+			keys.Current = key.ID
+		}
+
+		keys.Keys[key.ID] = key
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func secret(length int) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat("s", length)))
+}
+
+func TestParseKeySet(t *testing.T) {
+	keys, err := ParseKeySet("new:EdDSA:" + secret(32) + ", old:HS256:" + secret(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys.Current != "new" || len(keys.Keys) != 2 {
+		t.Fatalf("got current %q and %d keys", keys.Current, len(keys.Keys))
+	}
+
+	if key := keys.Keys["new"]; key.Algorithm != EdDSA || key.Private == nil {
+		t.Errorf("got new key %+v", key)
+	}
+
+	if key := keys.Keys["old"]; key.Algorithm != HS256 || len(key.Secret) != 32 {
+		t.Errorf("got old key %+v", key)
+	}
+}
+
+func TestParseKeySetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{name: "empty", spec: "", want: "is not formatted"},
+		{name: "missing secret", spec: "a:HS256", want: "is not formatted"},
+		{name: "missing id", spec: ":HS256:" + secret(32), want: "is not formatted"},
+		{name: "invalid base64", spec: "a:HS256:not base64!", want: "illegal base64"},
+		{name: "short secret", spec: "a:HS256:" + secret(31), want: "at least 32 bytes"},
+		{name: "wrong seed size", spec: "a:EdDSA:" + secret(31), want: "seed must be 32 bytes"},
+		{name: "unsupported algorithm", spec: "a:RS256:" + secret(32), want: "unsupported algorithm"},
+		{name: "duplicated id", spec: "a:HS256:" + secret(32) + ",a:EdDSA:" + secret(32), want: "is duplicated"},
+		{name: "trailing comma", spec: "a:HS256:" + secret(32) + ",", want: "is not formatted"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := ParseKeySet(test.spec)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, %v, want an error containing %q", keys, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrorQueueFull = errors.New("mail queue is full")
+
+// Queues messages and sends them in the background, retrying failed sends
+// with an exponential backoff. Messages still queued when the context given
+// to Start is done are dropped.
+type AsyncMailer struct {
+	Mailer  Mailer
+	Logger  *zap.Logger
+	Retries int
+	Backoff time.Duration
+
+	queue chan Message
+	wg    sync.WaitGroup
+}
+
+func NewAsyncMailer(mailer Mailer, logger *zap.Logger, size int) *AsyncMailer {
+	return &AsyncMailer{
+		Mailer:  mailer,
+		Logger:  logger,
+		Retries: 3,
+		Backoff: time.Second,
+		queue:   make(chan Message, size),
+	}
+}
+
+// Never blocks, fails with ErrorQueueFull instead.
+func (mailer *AsyncMailer) Send(ctx context.Context, message Message) error {
+	select {
+	case mailer.queue <- message:
+		return nil
+	default:
+		return ErrorQueueFull
+	}
+}
+
+func (mailer *AsyncMailer) Start(ctx context.Context) {
+	mailer.wg.Add(1)
+	go func() {
+		defer mailer.wg.Done()
+		mailer.loop(ctx)
+	}()
+}
+
+func (mailer *AsyncMailer) Wait() {
+	mailer.wg.Wait()
+}
+
+func (mailer *AsyncMailer) loop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			if count := len(mailer.queue); count > 0 {
+				mailer.Logger.Warn("queued mail dropped", zap.Int("count", count))
+			}
+			return
+		case message := <-mailer.queue:
+			mailer.deliver(ctx, message)
+		}
+	}
+}
+
+func (mailer *AsyncMailer) deliver(ctx context.Context, message Message) {
+	backoff := mailer.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := mailer.Mailer.Send(ctx, message)
+		if err == nil {
+			return
+		}
+
+		if attempt >= mailer.Retries {
+			mailer.Logger.Error("mail dropped", zap.String("subject", message.Subject), zap.Error(err))
+			return
+		}
+
+		mailer.Logger.Warn("mail failed, retrying", zap.String("subject", message.Subject), zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			mailer.Logger.Error("mail dropped", zap.String("subject", message.Subject), zap.Error(ctx.Err()))
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
@@ -0,0 +1,233 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var errorTransport = errors.New("transport failed")
+
+// Transport that fails its first sends, all of them when failures is negative,
+// and reports every attempt.
+type fakeMailer struct {
+	mutex    sync.Mutex
+	failures int
+	sent     []Message
+	attempts chan Message
+}
+
+func newFakeMailer(failures int) *fakeMailer {
+	return &fakeMailer{failures: failures, attempts: make(chan Message, 100)}
+}
+
+func (mailer *fakeMailer) Send(ctx context.Context, message Message) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	defer func() { mailer.attempts <- message }()
+
+	if mailer.failures != 0 {
+		mailer.failures--
+		return errorTransport
+	}
+
+	mailer.sent = append(mailer.sent, message)
+	return nil
+}
+
+func (mailer *fakeMailer) delivered() []Message {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	return append([]Message(nil), mailer.sent...)
+}
+
+// Waits for the transport to be called count times.
+func (mailer *fakeMailer) wait(t *testing.T, count int) {
+	t.Helper()
+
+	for range count {
+		select {
+		case <-mailer.attempts:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a send")
+		}
+	}
+}
+
+func newTestMailer(transport Mailer, size int) (*AsyncMailer, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	mailer := NewAsyncMailer(transport, zap.New(core), size)
+	mailer.Backoff = time.Millisecond
+	return mailer, logs
+}
+
+func TestAsyncMailerDelivers(t *testing.T) {
+	transport := newFakeMailer(0)
+	mailer, logs := newTestMailer(transport, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mailer.Start(ctx)
+
+	for i := range 3 {
+		if err := mailer.Send(ctx, Message{To: "ann@example.com", Subject: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transport.wait(t, 3)
+	cancel()
+	mailer.Wait()
+
+	sent := transport.delivered()
+	if len(sent) != 3 {
+		t.Fatalf("got %d messages, want 3", len(sent))
+	}
+
+	for i, message := range sent {
+		if message.Subject != fmt.Sprint(i) {
+			t.Errorf("got %q at %d, messages are sent in order", message.Subject, i)
+		}
+	}
+
+	if logs.Len() != 0 {
+		t.Errorf("got logs %v", logs.All())
+	}
+}
+
+func TestAsyncMailerRetries(t *testing.T) {
+	transport := newFakeMailer(2)
+	mailer, logs := newTestMailer(transport, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mailer.Start(ctx)
+
+	if err := mailer.Send(ctx, Message{Subject: "retried"}); err != nil {
+		t.Fatal(err)
+	}
+
+	transport.wait(t, 3)
+	cancel()
+	mailer.Wait()
+
+	if sent := transport.delivered(); len(sent) != 1 || sent[0].Subject != "retried" {
+		t.Errorf("got %v", sent)
+	}
+
+	if retries := logs.FilterMessage("mail failed, retrying").Len(); retries != 2 {
+		t.Errorf("got %d retry warnings, want 2", retries)
+	}
+}
+
+func TestAsyncMailerLogsDroppedMail(t *testing.T) {
+	transport := newFakeMailer(-1)
+	mailer, logs := newTestMailer(transport, 10)
+	mailer.Retries = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mailer.Start(ctx)
+
+	if err := mailer.Send(ctx, Message{Subject: "failing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	transport.wait(t, 3)
+	cancel()
+	mailer.Wait()
+
+	dropped := logs.FilterMessage("mail dropped").All()
+	if len(dropped) != 1 || dropped[0].Level != zapcore.ErrorLevel {
+		t.Fatalf("got %v", logs.All())
+	}
+
+	fields := dropped[0].ContextMap()
+	if fields["subject"] != "failing" || fields["error"] != errorTransport.Error() {
+		t.Errorf("got fields %v", fields)
+	}
+
+	if len(transport.attempts) != 0 {
+		t.Errorf("got %d sends after the retries ran out", len(transport.attempts))
+	}
+}
+
+// Shutting down does not wait out the backoff of a failing message.
+func TestAsyncMailerShutdownDuringBackoff(t *testing.T) {
+	transport := newFakeMailer(-1)
+	mailer, logs := newTestMailer(transport, 10)
+	mailer.Backoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mailer.Start(ctx)
+
+	if err := mailer.Send(ctx, Message{Subject: "failing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	transport.wait(t, 1)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		mailer.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown waited for the backoff")
+	}
+
+	dropped := logs.FilterMessage("mail dropped").All()
+	if len(dropped) != 1 || dropped[0].ContextMap()["error"] != context.Canceled.Error() {
+		t.Errorf("got %v", logs.All())
+	}
+}
+
+// Messages still queued on shutdown are dropped and counted in the log, none
+// are lost without a trace.
+func TestAsyncMailerShutdownDropsQueue(t *testing.T) {
+	transport := newFakeMailer(0)
+	mailer, logs := newTestMailer(transport, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := range 5 {
+		if err := mailer.Send(ctx, Message{Subject: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cancel()
+	mailer.Start(ctx)
+	mailer.Wait()
+
+	dropped := 0
+	for _, entry := range logs.FilterMessage("queued mail dropped").All() {
+		dropped += int(entry.ContextMap()["count"].(int64))
+	}
+
+	if sent := len(transport.delivered()); sent+dropped != 5 {
+		t.Errorf("got %d sent and %d dropped, want 5 in total", sent, dropped)
+	}
+}
+
+func TestAsyncMailerQueueFull(t *testing.T) {
+	mailer, _ := newTestMailer(newFakeMailer(0), 1)
+
+	if err := mailer.Send(context.Background(), Message{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mailer.Send(context.Background(), Message{}); !errors.Is(err, ErrorQueueFull) {
+		t.Errorf("got %v, want %v", err, ErrorQueueFull)
+	}
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Writes messages as .eml files to Directory, or to Writer when Directory is
+// empty. Meant for local development.
+type FileMailer struct {
+	Directory string
+	Writer    io.Writer
+	From      string
+
+	mutex sync.Mutex
+}
+
+func (mailer *FileMailer) Send(ctx context.Context, message Message) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	if mailer.Directory == "" {
+		_, err := fmt.Fprintf(mailer.Writer, "%s\r\n", message.Bytes(mailer.From))
+		return err
+	}
+
+	if err := os.MkdirAll(mailer.Directory, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("/", "_", "\\", "_").Replace(message.To))
+	return os.WriteFile(filepath.Join(mailer.Directory, name), message.Bytes(mailer.From), 0o600)
+}
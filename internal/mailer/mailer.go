@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"time"
+)
+
+type Mailer interface {
+	Send(context.Context, Message) error
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Encodes the message as a multipart/alternative email with a text and an
+// html part.
+func (message Message) Bytes(from string) []byte {
+	var buffer bytes.Buffer
+	var body bytes.Buffer
+
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: message.Text},
+		{contentType: "text/html; charset=utf-8", content: message.HTML},
+	} {
+		writer, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+
+		encoder := quotedprintable.NewWriter(writer)
+		encoder.Write([]byte(part.content))
+		encoder.Close()
+	}
+	parts.Close()
+
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buffer, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buffer, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	buffer.Write(body.Bytes())
+
+	return buffer.Bytes()
+}
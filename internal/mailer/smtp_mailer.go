@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+// Sends messages through an SMTP server, authenticating when a username is
+// set. STARTTLS is used when the server supports it.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if mailer.Username != "" {
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, mailer.Host)
+	}
+
+	address := net.JoinHostPort(mailer.Host, strconv.Itoa(mailer.Port))
+	return smtp.SendMail(address, auth, mailer.From, []string{message.To}, message.Bytes(mailer.From))
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	TemplateVerification    = "verification"
+	TemplatePasswordReset   = "password_reset"
+	TemplatePasswordChanged = "password_changed"
+	TemplateContentApproved = "content_approved"
+	TemplateContentRejected = "content_rejected"
+)
+
+// Each template has a name.txt file, which also defines name.subject, and a
+// name.html file.
+//
+//go:embed templates
+var files embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(files, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(files, "templates/*.html"))
+)
+
+type Data struct {
+	Username string
+	Token    string
+
+	// Moderation decisions name the post or comment, with the title of the
+	// post, and the reason of the moderator.
+	Kind   string
+	Title  string
+	Reason string
+}
+
+// Renders the named template into a message to the given address.
+func Compose(name string, to string, data Data) (Message, error) {
+	var subject, text, html bytes.Buffer
+
+	if err := textTemplates.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, err
+	}
+
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, err
+	}
+
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,40 @@
+package policy
+
+import "time"
+
+// How failed logins of an account or address are throttled. Failures beyond
+// Free lock it out for Backoff, doubling with each failure up to MaxBackoff,
+// and Lockout failures lock it out for LockoutDuration. The zero value never
+// locks anything out.
+type LoginThrottle struct {
+	Free       int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	Lockout         int
+	LockoutDuration time.Duration
+
+	// Failures older than this are forgotten, and never when zero.
+	Window time.Duration
+}
+
+// Returns until when the failures lock logins out, which is zero when they do
+// not, and whether that is a lockout rather than a backoff.
+func (throttle LoginThrottle) LockedUntil(failures int, now time.Time) (time.Time, bool) {
+	if throttle.Lockout > 0 && failures >= throttle.Lockout {
+		return now.Add(throttle.LockoutDuration), true
+	}
+
+	if throttle.Backoff <= 0 || failures <= throttle.Free {
+		return time.Time{}, false
+	}
+
+	// Shifting further would overflow long before reaching any sensible
+	// maximum.
+	backoff := throttle.Backoff << min(failures-throttle.Free-1, 30)
+	if throttle.MaxBackoff > 0 && (backoff > throttle.MaxBackoff || backoff <= 0) {
+		backoff = throttle.MaxBackoff
+	}
+
+	return now.Add(backoff), false
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockedUntil(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	throttle := LoginThrottle{
+		Free:            3,
+		Backoff:         time.Second,
+		MaxBackoff:      time.Minute,
+		Lockout:         20,
+		LockoutDuration: time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		throttle LoginThrottle
+		failures int
+		until    time.Duration
+		lockout  bool
+	}{
+		{name: "zero value", throttle: LoginThrottle{}, failures: 100, until: 0},
+		{name: "no failures", throttle: throttle, failures: 0, until: 0},
+		{name: "free", throttle: throttle, failures: 3, until: 0},
+		{name: "first backoff", throttle: throttle, failures: 4, until: time.Second},
+		{name: "doubled backoff", throttle: throttle, failures: 6, until: 4 * time.Second},
+		{name: "max backoff", throttle: throttle, failures: 12, until: time.Minute},
+		{name: "lockout", throttle: throttle, failures: 20, until: time.Hour, lockout: true},
+		{name: "beyond lockout", throttle: throttle, failures: 50, until: time.Hour, lockout: true},
+		{
+			name:     "overflow",
+			throttle: LoginThrottle{Backoff: time.Hour, MaxBackoff: 24 * time.Hour},
+			failures: 100,
+			until:    24 * time.Hour,
+		},
+		{
+			name:     "unbounded backoff",
+			throttle: LoginThrottle{Free: 1, Backoff: time.Second},
+			failures: 5,
+			until:    8 * time.Second,
+		},
+		{
+			name:     "lockout only",
+			throttle: LoginThrottle{Lockout: 5, LockoutDuration: time.Minute},
+			failures: 4,
+			until:    0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			until, lockout := test.throttle.LockedUntil(test.failures, now)
+
+			want := time.Time{}
+			if test.until > 0 {
+				want = now.Add(test.until)
+			}
+
+			if !until.Equal(want) || lockout != test.lockout {
+				t.Errorf("got %v, %v, want %v, %v", until, lockout, want, test.lockout)
+			}
+		})
+	}
+}
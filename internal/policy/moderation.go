@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"fmt"
+	"web_blog/internal/data/entity"
+)
+
+// When new posts and comments are reviewed by moderators, who approve them by
+// verifying them. The zero value reviews them after they are published.
+type Moderation string
+
+const (
+	// New content is visible right away and reviewed afterwards.
+	ModerationPost Moderation = "post"
+	// New content is hidden from everyone but its author and moderators
+	// until it is approved.
+	ModerationPre Moderation = "pre"
+)
+
+func ParseModeration(value string) (Moderation, error) {
+	switch moderation := Moderation(value); moderation {
+	case ModerationPost, ModerationPre:
+		return moderation, nil
+	default:
+		return "", fmt.Errorf("unknown moderation policy %q", value)
+	}
+}
+
+// Whether edited content goes back to the moderators, which it does under
+// pre-moderation so approved content can not be changed without review.
+func (moderation Moderation) ReviewsEdits() bool {
+	return moderation == ModerationPre
+}
+
+// Whether the user can see content of the author, which is hidden until it is
+// approved under pre-moderation.
+func (moderation Moderation) CanSee(user *entity.User, author int64, verified bool) bool {
+	if verified || moderation != ModerationPre {
+		return true
+	}
+
+	return user != nil && (user.ID == author || user.Role.Can(entity.PermissionContentModerate))
+}
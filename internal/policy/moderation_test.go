@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"testing"
+	"web_blog/internal/data/entity"
+)
+
+func TestParseModeration(t *testing.T) {
+	for _, value := range []string{"post", "pre"} {
+		if moderation, err := ParseModeration(value); err != nil || string(moderation) != value {
+			t.Errorf("got %q, %v for %q", moderation, err, value)
+		}
+	}
+
+	if _, err := ParseModeration("never"); err == nil {
+		t.Error("got no error for an unknown policy")
+	}
+}
+
+func TestReviewsEdits(t *testing.T) {
+	tests := map[Moderation]bool{ModerationPost: false, ModerationPre: true, "": false}
+
+	for moderation, want := range tests {
+		if got := moderation.ReviewsEdits(); got != want {
+			t.Errorf("got %v for %q, want %v", got, moderation, want)
+		}
+	}
+}
+
+func TestCanSee(t *testing.T) {
+	author := &entity.User{ID: 1}
+	other := &entity.User{ID: 2}
+	moderator := &entity.User{ID: 3, Role: entity.Role{Permissions: []string{entity.PermissionContentModerate}}}
+
+	tests := []struct {
+		name       string
+		moderation Moderation
+		user       *entity.User
+		verified   bool
+		want       bool
+	}{
+		{name: "post anonymous", moderation: ModerationPost, user: nil, verified: false, want: true},
+		{name: "post other user", moderation: ModerationPost, user: other, verified: false, want: true},
+		{name: "zero value", moderation: "", user: nil, verified: false, want: true},
+		{name: "pre verified", moderation: ModerationPre, user: nil, verified: true, want: true},
+		{name: "pre anonymous", moderation: ModerationPre, user: nil, verified: false, want: false},
+		{name: "pre other user", moderation: ModerationPre, user: other, verified: false, want: false},
+		{name: "pre author", moderation: ModerationPre, user: author, verified: false, want: true},
+		{name: "pre moderator", moderation: ModerationPre, user: moderator, verified: false, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.moderation.CanSee(test.user, author.ID, test.verified); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package policy
+
+import "web_blog/internal/data/entity"
+
+// Posts can be edited and deleted by their author, and by roles allowed to
+// edit and delete any post.
+var (
+	UpdatePost = Policy[entity.Post]{Owner: postOwner, Permission: entity.PermissionPostsUpdateAny}
+	DeletePost = Policy[entity.Post]{Owner: postOwner, Permission: entity.PermissionPostsDeleteAny}
+)
+
+// Comments can be edited and deleted by their author, and by roles allowed to
+// edit and delete any comment. Authors can only edit within the edit window,
+// which is checked by the service.
+var (
+	UpdateComment = Policy[entity.Comment]{Owner: commentOwner, Permission: entity.PermissionCommentsUpdateAny}
+	DeleteComment = Policy[entity.Comment]{Owner: commentOwner, Permission: entity.PermissionCommentsDeleteAny}
+)
+
+func postOwner(post *entity.Post) int64 {
+	return post.UserID
+}
+
+func commentOwner(comment *entity.Comment) int64 {
+	return comment.UserID
+}
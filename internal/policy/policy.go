@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"errors"
+	"web_blog/internal/data/entity"
+)
+
+var ErrorForbidden = errors.New("forbidden")
+
+// A policy allows a user to act on a resource when they own it, or when their
+// role has the permission named by Permission. Owner is nil for resources
+// that nobody owns, which leaves only the permission check.
+type Policy[T any] struct {
+	Owner      func(*T) int64
+	Permission string
+}
+
+// The user's role must be loaded with its permissions.
+func (policy Policy[T]) Allows(user *entity.User, resource *T) bool {
+	if user == nil {
+		return false
+	}
+
+	if policy.Owner != nil && resource != nil && policy.Owner(resource) == user.ID {
+		return true
+	}
+
+	return user.Role.Can(policy.Permission)
+}
+
+func (policy Policy[T]) Authorize(user *entity.User, resource *T) error {
+	if !policy.Allows(user, resource) {
+		return ErrorForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"testing"
+	"web_blog/internal/data/entity"
+)
+
+func TestAllows(t *testing.T) {
+	author := &entity.User{ID: 1}
+	other := &entity.User{ID: 2}
+	moderator := &entity.User{ID: 3, Role: entity.Role{Permissions: []string{entity.PermissionPostsUpdateAny}}}
+	post := &entity.Post{ID: 1, UserID: 1}
+	unowned := Policy[entity.Post]{Permission: entity.PermissionPostsUpdateAny}
+
+	tests := []struct {
+		name     string
+		policy   Policy[entity.Post]
+		user     *entity.User
+		resource *entity.Post
+		want     bool
+	}{
+		{name: "anonymous", policy: UpdatePost, user: nil, resource: post, want: false},
+		{name: "owner", policy: UpdatePost, user: author, resource: post, want: true},
+		{name: "other user", policy: UpdatePost, user: other, resource: post, want: false},
+		{name: "permission", policy: UpdatePost, user: moderator, resource: post, want: true},
+		{name: "other permission", policy: DeletePost, user: moderator, resource: post, want: false},
+		{name: "no resource", policy: UpdatePost, user: author, resource: nil, want: false},
+		{name: "unowned", policy: unowned, user: author, resource: post, want: false},
+		{name: "unowned permission", policy: unowned, user: moderator, resource: post, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.Allows(test.user, test.resource); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+
+			err := test.policy.Authorize(test.user, test.resource)
+			if (err == nil) != test.want || (err != nil && err != ErrorForbidden) {
+				t.Errorf("got error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Proxies whose forwarding headers are trusted to name the client. The zero
+// value trusts none, so the client is always the peer that connected.
+type TrustedProxies []netip.Prefix
+
+// Parses a comma separated list of addresses and CIDR ranges.
+func ParseTrustedProxies(value string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", field)
+			}
+
+			addr = addr.Unmap()
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", field)
+		}
+
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+func (proxies TrustedProxies) Trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The address of the client behind the peer. The forwarded addresses are
+// read from the right, the hop closest to the peer, and the first one not
+// added by a trusted proxy is the client, so clients can not choose their
+// address by sending the header themselves.
+func (proxies TrustedProxies) ClientIP(peer netip.Addr, forwarded []string) netip.Addr {
+	client := peer.Unmap()
+	if !proxies.Trusts(client) {
+		return client
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return client
+		}
+
+		client = addr.Unmap()
+		if !proxies.Trusts(client) {
+			return client
+		}
+	}
+
+	return client
+}
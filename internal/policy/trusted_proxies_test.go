@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 10.0.0.0/8, 192.0.2.1,,::1 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10.0.0.0/8", "192.0.2.1/32", "::1/128"}
+	if len(proxies) != len(want) {
+		t.Fatalf("got %v, want %v", proxies, want)
+	}
+
+	for i := range want {
+		if proxies[i].String() != want[i] {
+			t.Errorf("got %v, want %v", proxies, want)
+		}
+	}
+
+	if proxies, err := ParseTrustedProxies(""); err != nil || len(proxies) != 0 {
+		t.Errorf("got %v, %v for no proxies", proxies, err)
+	}
+
+	for _, value := range []string{"proxy", "10.0.0.0/33", "10.0.0.1:80"} {
+		if _, err := ParseTrustedProxies(value); err == nil {
+			t.Errorf("got no error for %q", value)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		proxies   TrustedProxies
+		peer      string
+		forwarded []string
+		want      string
+	}{
+		{name: "no proxies", proxies: nil, peer: "203.0.113.1", forwarded: []string{"198.51.100.1"}, want: "203.0.113.1"},
+		{name: "untrusted peer", proxies: proxies, peer: "203.0.113.1", forwarded: []string{"198.51.100.1"}, want: "203.0.113.1"},
+		{name: "trusted peer", proxies: proxies, peer: "10.0.0.1", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed hop", proxies: proxies, peer: "10.0.0.1", forwarded: []string{"1.1.1.1", "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "proxy chain", proxies: proxies, peer: "10.0.0.1", forwarded: []string{"198.51.100.1", "10.0.0.2"}, want: "198.51.100.1"},
+		{name: "no header", proxies: proxies, peer: "10.0.0.1", forwarded: nil, want: "10.0.0.1"},
+		{name: "only proxies", proxies: proxies, peer: "10.0.0.1", forwarded: []string{"10.0.0.2"}, want: "10.0.0.2"},
+		{name: "malformed hop", proxies: proxies, peer: "10.0.0.1", forwarded: []string{"198.51.100.1", "garbage"}, want: "10.0.0.1"},
+		{name: "mapped peer", proxies: proxies, peer: "::ffff:10.0.0.1", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.proxies.ClientIP(netip.MustParseAddr(test.peer), test.forwarded)
+			if got.String() != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
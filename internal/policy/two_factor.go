@@ -0,0 +1,20 @@
+package policy
+
+import "web_blog/internal/data/entity"
+
+// Which users have to enable two factor authentication before they can use
+// their account, by the level of their role. The zero value requires it of
+// nobody.
+type TwoFactor struct {
+	// Roles at or above this level require it, none when zero.
+	Level int
+}
+
+// Returns the policy admins set in the settings.
+func NewTwoFactor(settings *entity.Settings) TwoFactor {
+	return TwoFactor{Level: settings.TwoFactorLevel}
+}
+
+func (twoFactor TwoFactor) Required(role *entity.Role) bool {
+	return twoFactor.Level > 0 && role.Level >= twoFactor.Level
+}
@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"testing"
+	"web_blog/internal/data/entity"
+)
+
+func TestTwoFactorRequired(t *testing.T) {
+	tests := []struct {
+		name      string
+		twoFactor TwoFactor
+		level     int
+		want      bool
+	}{
+		{name: "zero value", twoFactor: TwoFactor{}, level: 3, want: false},
+		{name: "zero value level zero", twoFactor: TwoFactor{}, level: 0, want: false},
+		{name: "below", twoFactor: TwoFactor{Level: 2}, level: 1, want: false},
+		{name: "at", twoFactor: TwoFactor{Level: 2}, level: 2, want: true},
+		{name: "above", twoFactor: TwoFactor{Level: 2}, level: 3, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.twoFactor.Required(&entity.Role{Level: test.level}); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package policy
+
+import (
+	"fmt"
+	"web_blog/internal/data/entity"
+)
+
+// What users that have not verified their email are allowed to do. The zero
+// value allows everything.
+type Unverified string
+
+const (
+	// Unverified users can do everything verified users can.
+	UnverifiedAllow Unverified = "allow"
+	// Unverified users can log in, but not create posts or comments.
+	UnverifiedRestrict Unverified = "restrict"
+	// Unverified users can not log in.
+	UnverifiedBlock Unverified = "block"
+)
+
+func ParseUnverified(value string) (Unverified, error) {
+	switch unverified := Unverified(value); unverified {
+	case UnverifiedAllow, UnverifiedRestrict, UnverifiedBlock:
+		return unverified, nil
+	default:
+		return "", fmt.Errorf("unknown unverified policy %q", value)
+	}
+}
+
+func (unverified Unverified) CanLogin(user *entity.User) bool {
+	return user.Verified || unverified != UnverifiedBlock
+}
+
+func (unverified Unverified) CanAuthor(user *entity.User) bool {
+	return user.Verified || (unverified != UnverifiedRestrict && unverified != UnverifiedBlock)
+}
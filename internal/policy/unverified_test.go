@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"testing"
+	"web_blog/internal/data/entity"
+)
+
+func TestUnverified(t *testing.T) {
+	tests := []struct {
+		name       string
+		unverified Unverified
+		verified   bool
+		login      bool
+		author     bool
+	}{
+		{name: "zero value", unverified: "", verified: false, login: true, author: true},
+		{name: "allow", unverified: UnverifiedAllow, verified: false, login: true, author: true},
+		{name: "restrict", unverified: UnverifiedRestrict, verified: false, login: true, author: false},
+		{name: "block", unverified: UnverifiedBlock, verified: false, login: false, author: false},
+		{name: "restrict verified", unverified: UnverifiedRestrict, verified: true, login: true, author: true},
+		{name: "block verified", unverified: UnverifiedBlock, verified: true, login: true, author: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &entity.User{Verified: test.verified}
+
+			if got := test.unverified.CanLogin(user); got != test.login {
+				t.Errorf("got can login %v, want %v", got, test.login)
+			}
+
+			if got := test.unverified.CanAuthor(user); got != test.author {
+				t.Errorf("got can author %v, want %v", got, test.author)
+			}
+		})
+	}
+}
+
+func TestParseUnverified(t *testing.T) {
+	for _, value := range []string{"allow", "restrict", "block"} {
+		if unverified, err := ParseUnverified(value); err != nil || string(unverified) != value {
+			t.Errorf("got %q, %v for %q", unverified, err, value)
+		}
+	}
+
+	if _, err := ParseUnverified("deny"); err == nil {
+		t.Error("got no error for an unknown policy")
+	}
+}
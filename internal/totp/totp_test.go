@@ -0,0 +1,100 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA1 secret of the RFC 6238 test vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The SHA1 test vectors of RFC 6238, truncated from 8 to 6 digits.
+func TestCode(t *testing.T) {
+	tests := []struct {
+		time int64
+		want string
+	}{
+		{time: 59, want: "287082"},
+		{time: 1111111109, want: "081804"},
+		{time: 1111111111, want: "050471"},
+		{time: 1234567890, want: "005924"},
+		{time: 2000000000, want: "279037"},
+		{time: 20000000000, want: "353130"},
+	}
+
+	for _, test := range tests {
+		for _, secret := range []string{rfcSecret, strings.ToLower(rfcSecret)} {
+			code, err := Code(secret, Step(time.Unix(test.time, 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != test.want {
+				t.Errorf("got %s at %d, want %s", code, test.time, test.want)
+			}
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("got no error for an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	code := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return code
+	}
+
+	tests := []struct {
+		name string
+		code string
+		step int64
+		ok   bool
+	}{
+		{name: "current step", code: code(current), step: current, ok: true},
+		{name: "previous step", code: code(current - 1), step: current - 1, ok: true},
+		{name: "next step", code: code(current + 1), step: current + 1, ok: true},
+		{name: "two steps behind", code: code(current - 2), ok: false},
+		{name: "two steps ahead", code: code(current + 2), ok: false},
+		{name: "wrong code", code: "000000", ok: false},
+		{name: "empty", code: "", ok: false},
+		{name: "too long", code: code(current) + "0", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, test.code, now)
+			if ok != test.ok || step != test.step {
+				t.Errorf("got %d, %v, want %d, %v", step, ok, test.step, test.ok)
+			}
+		})
+	}
+
+	if _, ok := Validate("not base32!", code(current), now); ok {
+		t.Error("got a valid code for an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, err := encoding.DecodeString(secret); err != nil || len(key) != secretSize {
+		t.Errorf("got %q, %v", secret, err)
+	}
+
+	if other, _ := GenerateSecret(); other == secret {
+		t.Error("got the same secret twice")
+	}
+}